@@ -0,0 +1,63 @@
+package awsconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Account is an AWS account targeted by a multi-account operation. It is
+// reached either through a named profile or by assuming a role in it with
+// the credentials of the base provider.
+type Account struct {
+	// Name labels the account in reports, e.g. the profile or account name
+	Name    string `json:"name"`
+	ID      string `json:"id,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	RoleARN string `json:"role_arn,omitempty"`
+}
+
+// ProfileAccounts returns one account per named profile
+func ProfileAccounts(profiles []string) []Account {
+	accounts := make([]Account, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			accounts = append(accounts, Account{Name: profile, Profile: profile})
+		}
+	}
+	return accounts
+}
+
+// RoleARN returns the ARN of a role in an account, e.g. the role created
+// in every member account by AWS Organizations
+func RoleARN(partition, accountID, roleName string) string {
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, accountID, roleName)
+}
+
+// ForAccount returns a provider for account that shares the client cache,
+// load options and MFA token provider of p. A profile account replaces the
+// profile and role chain of p; a role account appends its role to the
+// chain, so the role is assumed with the credentials of p.
+func (p *Provider) ForAccount(account Account) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	provider := &Provider{
+		profile:       p.profile,
+		roles:         append([]RoleConfig(nil), p.roles...),
+		tokenProvider: p.tokenProvider,
+		loadOptions:   p.loadOptions,
+		cache:         p.cache,
+		accountID:     account.ID,
+	}
+	if account.Profile != "" {
+		provider.profile = account.Profile
+		provider.roles = nil
+	}
+	if account.RoleARN != "" {
+		provider.roles = append(provider.roles, RoleConfig{RoleARN: account.RoleARN, SessionName: "aws-resources"})
+		if provider.accountID == "" {
+			provider.accountID = AccountFromARN(account.RoleARN)
+		}
+	}
+	return provider
+}
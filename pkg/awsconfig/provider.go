@@ -0,0 +1,320 @@
+// Package awsconfig provides a shared, concurrency-safe source of AWS
+// configuration and SDK clients for the services in this module.
+package awsconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
+)
+
+// tracer returns the tracer of the global provider at call time, so
+// providers installed later, e.g. by telemetry.Setup, are used
+func tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer("github.com/Tech-Preta/aws-resources/pkg/awsconfig")
+}
+
+// RoleConfig describes one hop of an assume-role chain
+type RoleConfig struct {
+	RoleARN     string
+	ExternalID  string
+	SessionName string
+	MFASerial   string
+	Duration    time.Duration
+}
+
+// Option configures a Provider
+type Option func(*Provider)
+
+// WithProfile selects a named profile from the shared AWS config files
+func WithProfile(name string) Option {
+	return func(p *Provider) {
+		p.profile = name
+	}
+}
+
+// WithAssumeRole appends a role to the assume-role chain. Roles are assumed
+// in the order they were added, each one using the credentials of the previous.
+func WithAssumeRole(role RoleConfig) Option {
+	return func(p *Provider) {
+		p.roles = append(p.roles, role)
+	}
+}
+
+// WithMFATokenProvider sets the function used to obtain MFA codes for roles
+// that declare an MFASerial. Defaults to prompting on stdin.
+func WithMFATokenProvider(fn func() (string, error)) Option {
+	return func(p *Provider) {
+		p.tokenProvider = fn
+	}
+}
+
+// WithLoadOptions passes additional options to config.LoadDefaultConfig
+func WithLoadOptions(opts ...func(*config.LoadOptions) error) Option {
+	return func(p *Provider) {
+		p.loadOptions = append(p.loadOptions, opts...)
+	}
+}
+
+// WithAPITimeout limits every HTTP request to AWS to timeout, each retry
+// attempt getting its own
+func WithAPITimeout(timeout time.Duration) Option {
+	return WithLoadOptions(config.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(timeout)))
+}
+
+// WithClientCache shares a client cache between providers
+func WithClientCache(cache *ClientCache) Option {
+	return func(p *Provider) {
+		p.cache = cache
+	}
+}
+
+// WithAccountID sets the account ID of the credentials so AccountID does not
+// need to call STS
+func WithAccountID(accountID string) Option {
+	return func(p *Provider) {
+		p.accountID = accountID
+	}
+}
+
+// Provider loads AWS configuration once for a profile and assume-role chain
+// and hands out per-region copies and cached SDK clients
+type Provider struct {
+	profile       string
+	roles         []RoleConfig
+	tokenProvider func() (string, error)
+	loadOptions   []func(*config.LoadOptions) error
+	cache         *ClientCache
+
+	mu        sync.Mutex
+	base      *aws.Config
+	accountID string
+}
+
+// NewProvider creates a new provider. Configuration is loaded lazily on first use.
+func NewProvider(opts ...Option) *Provider {
+	p := &Provider{
+		tokenProvider: stscreds.StdinTokenProvider,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.cache == nil {
+		p.cache = NewClientCache()
+	}
+	return p
+}
+
+// Profile returns the name of the profile used by the provider
+func (p *Provider) Profile() string {
+	return p.profile
+}
+
+// Config returns the AWS configuration for a region. Credentials are shared
+// between regions so assume-role and MFA prompts only happen once.
+func (p *Provider) Config(ctx context.Context, region string) (aws.Config, error) {
+	ctx, span := tracer().Start(ctx, "awsconfig.Config", trace.WithAttributes(
+		attribute.String("aws.region", region),
+		attribute.String("aws.profile", p.profile),
+		attribute.Int("aws.assume_roles", len(p.roles)),
+	))
+	defer span.End()
+
+	base, err := p.baseConfig(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return aws.Config{}, err
+	}
+
+	cfg := base.Copy()
+	if region != "" {
+		cfg.Region = region
+	}
+	return cfg, nil
+}
+
+// baseConfig loads the shared configuration and resolves the role chain once
+func (p *Provider) baseConfig(ctx context.Context) (aws.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.base != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("aws.config.cached", true))
+		return *p.base, nil
+	}
+
+	opts := append([]func(*config.LoadOptions) error{}, p.loadOptions...)
+	if p.profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(p.profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// STS needs a region to assume roles even when the profile has none
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	for _, role := range p.roles {
+		if role.RoleARN == "" {
+			return aws.Config{}, fmt.Errorf("assume role: role ARN is required")
+		}
+
+		role := role
+		assumeRole := stscreds.NewAssumeRoleProvider(newSTSClient(cfg), role.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if role.ExternalID != "" {
+				o.ExternalID = aws.String(role.ExternalID)
+			}
+			if role.SessionName != "" {
+				o.RoleSessionName = role.SessionName
+			}
+			if role.Duration > 0 {
+				o.Duration = role.Duration
+			}
+			if role.MFASerial != "" {
+				o.SerialNumber = aws.String(role.MFASerial)
+				o.TokenProvider = p.tokenProvider
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(assumeRole)
+	}
+
+	p.base = &cfg
+	return cfg, nil
+}
+
+// AccountID returns the account ID of the resolved credentials, calling
+// STS GetCallerIdentity on first use
+func (p *Provider) AccountID(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	accountID := p.accountID
+	p.mu.Unlock()
+	if accountID != "" {
+		return accountID, nil
+	}
+
+	cfg, err := p.baseConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	identity, err := newSTSClient(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve caller identity: %w", err)
+	}
+
+	p.mu.Lock()
+	p.accountID = aws.ToString(identity.Account)
+	p.mu.Unlock()
+	return aws.ToString(identity.Account), nil
+}
+
+// newSTSClient creates an STS client whose calls, e.g. the AssumeRole calls
+// resolving credentials, are traced
+func newSTSClient(cfg aws.Config) *sts.Client {
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		o.TracerProvider = telemetry.SDKTracerProvider()
+	})
+}
+
+// KnownAccountID returns the account ID if it was configured or already
+// resolved, without calling AWS
+func (p *Provider) KnownAccountID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.accountID
+}
+
+// identity returns the key that identifies the provider's account in the
+// client cache without calling AWS
+func (p *Provider) identity() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accountID != "" {
+		return p.accountID
+	}
+	if len(p.roles) > 0 {
+		if account := AccountFromARN(p.roles[len(p.roles)-1].RoleARN); account != "" {
+			return account
+		}
+		return p.roles[len(p.roles)-1].RoleARN
+	}
+	if p.profile != "" {
+		return "profile:" + p.profile
+	}
+	return "default"
+}
+
+// AccountFromARN extracts the account ID from an ARN, returning an empty
+// string if the ARN is malformed
+func AccountFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+// clientKey identifies a cached client
+type clientKey struct {
+	service string
+	account string
+	region  string
+}
+
+// ClientCache stores SDK clients per service, account and region
+type ClientCache struct {
+	mu      sync.Mutex
+	clients map[clientKey]interface{}
+}
+
+// NewClientCache creates an empty client cache
+func NewClientCache() *ClientCache {
+	return &ClientCache{
+		clients: make(map[clientKey]interface{}),
+	}
+}
+
+// Client returns the cached client of a service for the provider's account
+// and the given region, creating it with newClient on first use
+func Client[T any](ctx context.Context, p *Provider, service, region string, newClient func(aws.Config) T) (T, error) {
+	var zero T
+
+	cfg, err := p.Config(ctx, region)
+	if err != nil {
+		return zero, err
+	}
+
+	key := clientKey{service: service, account: p.identity(), region: cfg.Region}
+
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	if client, ok := p.cache.clients[key]; ok {
+		if typed, ok := client.(T); ok {
+			return typed, nil
+		}
+	}
+
+	client := newClient(cfg)
+	p.cache.clients[key] = client
+	return client, nil
+}
@@ -0,0 +1,123 @@
+package awsconfig
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// staticProvider returns a provider that never reads the environment's credentials
+func staticProvider(opts ...Option) *Provider {
+	opts = append([]Option{WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+	)}, opts...)
+	return NewProvider(opts...)
+}
+
+func TestProviderConfigRegion(t *testing.T) {
+	p := staticProvider()
+
+	cfg, err := p.Config(context.Background(), "eu-west-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Region != "eu-west-1" {
+		t.Errorf("Expected region eu-west-1, got %s", cfg.Region)
+	}
+
+	cfg, err = p.Config(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Region != "us-east-1" {
+		t.Errorf("Expected default region us-east-1, got %s", cfg.Region)
+	}
+}
+
+func TestProviderAssumeRoleRequiresARN(t *testing.T) {
+	p := staticProvider(WithAssumeRole(RoleConfig{ExternalID: "abc"}))
+
+	if _, err := p.Config(context.Background(), "us-east-1"); err == nil {
+		t.Error("Expected error for role without ARN")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	p := staticProvider()
+	ctx := context.Background()
+
+	type client struct{ region string }
+	newClient := func(cfg aws.Config) *client { return &client{region: cfg.Region} }
+
+	var wg sync.WaitGroup
+	clients := make([]*client, 10)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := Client(ctx, p, "test", "us-west-2", newClient)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			clients[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	for _, c := range clients {
+		if c != clients[0] {
+			t.Fatal("Expected the same cached client for every call")
+		}
+	}
+
+	other, _ := Client(ctx, p, "test", "eu-central-1", newClient)
+	if other == clients[0] || other.region != "eu-central-1" {
+		t.Errorf("Expected a separate client for another region, got %+v", other)
+	}
+}
+
+func TestClientCacheSeparatesAccounts(t *testing.T) {
+	cache := NewClientCache()
+	ctx := context.Background()
+	newClient := func(cfg aws.Config) *struct{ id int } { return &struct{ id int }{} }
+
+	dev := staticProvider(WithClientCache(cache), WithProfile("dev"))
+	prod := staticProvider(WithClientCache(cache), WithAssumeRole(RoleConfig{RoleARN: "arn:aws:iam::123456789012:role/admin"}))
+
+	devClient, _ := Client(ctx, dev, "test", "us-east-1", newClient)
+	prodClient, _ := Client(ctx, prod, "test", "us-east-1", newClient)
+	if devClient == prodClient {
+		t.Error("Expected different clients for different accounts")
+	}
+}
+
+func TestAccountFromARN(t *testing.T) {
+	if got := AccountFromARN("arn:aws:iam::123456789012:role/admin"); got != "123456789012" {
+		t.Errorf("Expected 123456789012, got %s", got)
+	}
+	if got := AccountFromARN("not-an-arn"); got != "" {
+		t.Errorf("Expected empty account, got %s", got)
+	}
+}
+
+func TestProviderForAccount(t *testing.T) {
+	base := staticProvider(WithProfile("management"))
+
+	member := base.ForAccount(Account{Name: "dev", RoleARN: RoleARN("aws", "222222222222", "Admin")})
+	if member.Profile() != "management" || member.KnownAccountID() != "222222222222" {
+		t.Errorf("Expected the role to be assumed from the base profile, got %q, %q", member.Profile(), member.KnownAccountID())
+	}
+	if member.cache != base.cache {
+		t.Error("Expected the client cache to be shared")
+	}
+
+	profile := base.ForAccount(Account{Name: "prod", Profile: "prod"})
+	if profile.Profile() != "prod" || len(profile.roles) != 0 {
+		t.Errorf("Expected the prod profile without roles, got %q, %d role(s)", profile.Profile(), len(profile.roles))
+	}
+}
@@ -2,12 +2,23 @@ package cli
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/config"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/logging"
 	"github.com/Tech-Preta/aws-resources/pkg/services"
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
 )
 
 // Screen represents different screens in the app
@@ -20,8 +31,14 @@ const (
 	S3CreateBucket
 	EC2CreateInstances
 	ResultScreen
+	InventoryScreen
+	InventoryDetail
+	ContextScreen
 )
 
+// inventoryFilters are the services the inventory screen cycles through, "" meaning all
+var inventoryFilters = []string{"", services.S3ServiceName, services.EC2ServiceName}
+
 // Model represents the main application model
 type Model struct {
 	screen       Screen
@@ -32,7 +49,34 @@ type Model struct {
 	height       int
 	result       *services.ResourceResult
 	errorMsg     string
-	
+
+	// cfg is the configuration, with the context in use applied
+	cfg *config.Config
+	// provider is shared by every service so credentials and clients are reused
+	provider *awsconfig.Provider
+	// serviceOptions are applied to every service after the provider, e.g. fake clients in tests
+	serviceOptions []services.Option
+	// hooks are the hooks of the config file
+	hooks []services.Option
+	// logger is the logger of the config file
+	logger *slog.Logger
+	// progress receives the progress events of running operations, nil when they are not shown
+	progress chan services.ProgressEvent
+	// status is the last progress event of the running operation
+	status string
+
+	// inventory records created resources, nil when the store could not be opened
+	inventory       *inventory.Store
+	inventoryErr    error
+	records         []inventory.Record
+	record          inventory.Record
+	inventoryFilter int
+	showDeleted     bool
+	// drift is the last drift check of the record shown in the detail screen
+	drift *services.DriftReport
+	// session identifies this run in the records of the resources it creates
+	session string
+
 	// Form fields
 	bucketName    string
 	region        string
@@ -40,6 +84,12 @@ type Model struct {
 	instanceType  string
 	keyName       string
 	count         string
+	// tags starts with the default tags from the config file so they can be edited per resource
+	tags          string
+	// defaults are the resource defaults of the config file
+	defaults      config.DefaultsConfig
+	// clientToken makes repeated launches of an unchanged form idempotent
+	clientToken   string
 	inputField    int
 	inputActive   bool
 }
@@ -74,19 +124,64 @@ var (
 		Bold(true)
 )
 
+// applyTheme sets the colors of the config file on the styles
+func applyTheme(theme config.ThemeConfig) {
+	if theme.PrimaryColor != "" {
+		titleStyle = titleStyle.Foreground(lipgloss.Color(theme.PrimaryColor))
+	}
+	if theme.AccentColor != "" {
+		successStyle = successStyle.Foreground(lipgloss.Color(theme.AccentColor))
+	}
+	if theme.ErrorColor != "" {
+		errorStyle = errorStyle.Foreground(lipgloss.Color(theme.ErrorColor))
+	}
+}
+
 // initialModel creates the initial model
-func initialModel() Model {
-	return Model{
-		screen:    MainMenu,
-		choices:   []string{"S3 - Manage Buckets", "EC2 - Manage Instances", "Exit"},
-		selected:  make(map[int]struct{}),
-		region:    "us-east-1", // default region
-		count:     "1",         // default count
+func initialModel(cfg *config.Config) Model {
+	m := Model{
+		screen:   MainMenu,
+		selected: make(map[int]struct{}),
+		provider: awsconfig.NewProvider(),
 	}
+	m.choices = m.getChoices()
+	return m.withConfig(cfg)
+}
+
+// withConfig fills the forms with the defaults of cfg
+func (m Model) withConfig(cfg *config.Config) Model {
+	m.cfg = cfg
+	m.region = cfg.AWS.Region
+	m.instanceType = cfg.Defaults.EC2.InstanceType
+	m.keyName = cfg.Defaults.EC2.KeyName
+	m.count = strconv.Itoa(cfg.Defaults.EC2.Count)
+	m.tags = services.FormatTags(cfg.Defaults.Tags)
+	m.defaults = cfg.Defaults
+	m.clientToken = services.NewClientToken()
+	return m
+}
+
+// switchContext applies another context of the config file to the forms
+// and the AWS settings, for this session only
+func (m Model) switchContext(name string) Model {
+	cfg, err := config.Resolve(m.cfg.Path, name)
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+	m = m.withConfig(cfg)
+	m.provider = awsconfig.NewProvider(providerOptions(cfg)...)
+	m.errorMsg = ""
+	m.screen = MainMenu
+	m.cursor = 0
+	return m
 }
 
 // Init is the first function that will be called. It returns an optional initial command.
 func (m Model) Init() tea.Cmd {
+	if m.progress != nil {
+		return waitForProgress(m.progress)
+	}
 	return nil
 }
 
@@ -101,7 +196,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case resultMsg:
 		return m.handleResult(msg)
 
+	case driftMsg:
+		m.drift = &msg.report
+		return m, nil
+
+	case progressMsg:
+		m.status = fmt.Sprintf("%s: %s", msg.event.Stage, msg.event.Message)
+		return m, waitForProgress(m.progress)
+
 	case tea.KeyMsg:
+		// While a field is being edited, keys are text rather than shortcuts
+		if m.inputActive {
+			switch msg.String() {
+			case "ctrl+c", "esc", "tab", "enter":
+			default:
+				return m.handleInput(msg.String())
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
@@ -124,9 +236,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.screen = EC2Menu
 				m.cursor = 0
 				m.inputField = 0
-			case ResultScreen:
+			case ResultScreen, InventoryScreen, ContextScreen:
 				m.screen = MainMenu
 				m.cursor = 0
+				m.errorMsg = ""
+			case InventoryDetail:
+				m.screen = InventoryScreen
 			}
 			return m, nil
 
@@ -149,11 +264,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+		case "f", "d":
+			// Change the inventory filter or toggle deleted resources
+			if m.screen != InventoryScreen {
+				break
+			}
+			if msg.String() == "f" {
+				m.inventoryFilter = (m.inventoryFilter + 1) % len(inventoryFilters)
+			} else {
+				m.showDeleted = !m.showDeleted
+			}
+			m.cursor = 0
+			return m.loadInventory(), nil
+
+		case "x":
+			// Open the context switcher
+			if m.screen == MainMenu && m.cfg != nil && len(m.cfg.Contexts) > 0 {
+				m.screen = ContextScreen
+				m.cursor = 0
+			}
+
+		case "c":
+			// Check the displayed record for drift
+			if m.screen == InventoryDetail && !m.record.Deleted() {
+				return m, m.checkDrift(m.record)
+			}
+
 		case "tab":
-			if m.screen == S3CreateBucket {
-				m.inputField = (m.inputField + 1) % 2
-			} else if m.screen == EC2CreateInstances {
-				m.inputField = (m.inputField + 1) % 5
+			// The first Tab starts editing the focused field, the next ones move to the following field
+			if m.screen != S3CreateBucket && m.screen != EC2CreateInstances {
+				break
+			}
+			if !m.inputActive {
+				m.inputActive = true
+			} else if m.screen == S3CreateBucket {
+				m.inputField = (m.inputField + 1) % 3
+			} else {
+				m.inputField = (m.inputField + 1) % 6
 			}
 
 		default:
@@ -170,15 +317,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) getChoices() []string {
 	switch m.screen {
 	case MainMenu:
-		return []string{"S3 - Manage Buckets", "EC2 - Manage Instances", "Exit"}
+		return []string{"S3 - Manage Buckets", "EC2 - Manage Instances", "Inventory", "Exit"}
 	case S3Menu:
 		return []string{"Create Bucket", "Back to Main Menu"}
 	case EC2Menu:
 		return []string{"Create Instances", "Back to Main Menu"}
 	case S3CreateBucket:
-		return []string{"Create Bucket", "Back to S3 Menu"}
+		return []string{"Create Bucket", "Preview (Dry Run)", "Back to S3 Menu"}
 	case EC2CreateInstances:
-		return []string{"Launch Instances", "Back to EC2 Menu"}
+		return []string{"Launch Instances", "Preview (Dry Run)", "Back to EC2 Menu"}
+	case InventoryScreen:
+		choices := make([]string, 0, len(m.records)+1)
+		for _, record := range m.records {
+			choices = append(choices, recordSummary(record))
+		}
+		return append(choices, "Back to Main Menu")
+	case ContextScreen:
+		return append(m.cfg.ContextNames(), "Back to Main Menu")
 	default:
 		return []string{}
 	}
@@ -200,7 +355,11 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		case 1: // EC2
 			m.screen = EC2Menu
 			m.cursor = 0
-		case 2: // Exit
+		case 2: // Inventory
+			m.screen = InventoryScreen
+			m.cursor = 0
+			return m.loadInventory(), nil
+		case 3: // Exit
 			return m, tea.Quit
 		}
 
@@ -210,6 +369,10 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			m.screen = S3CreateBucket
 			m.cursor = 0
 			m.inputField = 0
+			m.region = m.cfg.AWS.Region
+			if m.defaults.S3.Region != "" {
+				m.region = m.defaults.S3.Region
+			}
 		case 1: // Back
 			m.screen = MainMenu
 			m.cursor = 0
@@ -221,6 +384,10 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			m.screen = EC2CreateInstances
 			m.cursor = 0
 			m.inputField = 0
+			m.region = m.cfg.AWS.Region
+			if m.defaults.EC2.Region != "" {
+				m.region = m.defaults.EC2.Region
+			}
 		case 1: // Back
 			m.screen = MainMenu
 			m.cursor = 0
@@ -229,8 +396,10 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	case S3CreateBucket:
 		switch m.cursor {
 		case 0: // Create Bucket
-			return m, m.createS3Bucket()
-		case 1: // Back
+			return m, m.createS3Bucket(false)
+		case 1: // Preview
+			return m, m.createS3Bucket(true)
+		case 2: // Back
 			m.screen = S3Menu
 			m.cursor = 0
 		}
@@ -238,12 +407,35 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	case EC2CreateInstances:
 		switch m.cursor {
 		case 0: // Launch Instances
-			return m, m.createEC2Instances()
-		case 1: // Back
+			return m, m.createEC2Instances(false)
+		case 1: // Preview
+			return m, m.createEC2Instances(true)
+		case 2: // Back
 			m.screen = EC2Menu
 			m.cursor = 0
 		}
 
+	case InventoryScreen:
+		if m.cursor < len(m.records) {
+			m.record = m.records[m.cursor]
+			m.drift = nil
+			m.screen = InventoryDetail
+		} else {
+			m.screen = MainMenu
+			m.cursor = 0
+		}
+
+	case InventoryDetail:
+		m.screen = InventoryScreen
+
+	case ContextScreen:
+		if names := m.cfg.ContextNames(); m.cursor < len(names) {
+			return m.switchContext(names[m.cursor]), nil
+		}
+		m.screen = MainMenu
+		m.cursor = 0
+		m.errorMsg = ""
+
 	default:
 		if m.screen == S3CreateBucket || m.screen == EC2CreateInstances {
 			m.inputActive = true
@@ -278,8 +470,11 @@ func (m Model) addChar(char string) Model {
 			m.bucketName += char
 		case 1:
 			m.region += char
+		case 2:
+			m.tags += char
 		}
 	case EC2CreateInstances:
+		m.clientToken = services.NewClientToken()
 		switch m.inputField {
 		case 0:
 			m.imageID += char
@@ -291,6 +486,8 @@ func (m Model) addChar(char string) Model {
 			m.count += char
 		case 4:
 			m.region += char
+		case 5:
+			m.tags += char
 		}
 	}
 	return m
@@ -309,8 +506,13 @@ func (m Model) removeChar() Model {
 			if len(m.region) > 0 {
 				m.region = m.region[:len(m.region)-1]
 			}
+		case 2:
+			if len(m.tags) > 0 {
+				m.tags = m.tags[:len(m.tags)-1]
+			}
 		}
 	case EC2CreateInstances:
+		m.clientToken = services.NewClientToken()
 		switch m.inputField {
 		case 0:
 			if len(m.imageID) > 0 {
@@ -332,13 +534,49 @@ func (m Model) removeChar() Model {
 			if len(m.region) > 0 {
 				m.region = m.region[:len(m.region)-1]
 			}
+		case 5:
+			if len(m.tags) > 0 {
+				m.tags = m.tags[:len(m.tags)-1]
+			}
 		}
 	}
 	return m
 }
 
-// createS3Bucket creates an S3 bucket
-func (m Model) createS3Bucket() tea.Cmd {
+// servicesOptions returns the options used to create every service
+func (m Model) servicesOptions() []services.Option {
+	opts := []services.Option{services.WithProvider(m.provider)}
+	if m.inventory != nil {
+		opts = append(opts, services.WithInventory(m.inventory))
+	}
+	if m.session != "" {
+		opts = append(opts, services.WithSession(m.session))
+	}
+	if m.progress != nil {
+		opts = append(opts, services.WithProgressChannel(m.progress))
+	}
+	if m.logger != nil {
+		opts = append(opts, services.WithLogger(m.logger))
+	}
+	opts = append(opts, m.hooks...)
+	return append(opts, m.serviceOptions...)
+}
+
+// loadInventory reads the records matching the current inventory filter
+func (m Model) loadInventory() Model {
+	m.records = nil
+	if m.inventory == nil {
+		return m
+	}
+	m.records, m.inventoryErr = m.inventory.List(inventory.Filter{
+		Service:        inventoryFilters[m.inventoryFilter],
+		IncludeDeleted: m.showDeleted,
+	})
+	return m
+}
+
+// createS3Bucket creates an S3 bucket, or only previews it when dryRun is set
+func (m Model) createS3Bucket(dryRun bool) tea.Cmd {
 	return func() tea.Msg {
 		if m.bucketName == "" {
 			return resultMsg{
@@ -350,7 +588,11 @@ func (m Model) createS3Bucket() tea.Cmd {
 			}
 		}
 
-		s3Service, err := services.NewS3Service(m.region)
+		// One trace covers loading the configuration, resolving credentials and the API calls
+		ctx, span := tracer().Start(context.Background(), "tui create bucket")
+		defer span.End()
+
+		s3Service, err := services.NewS3Service(m.region, append(m.servicesOptions(), services.WithLoadContext(ctx))...)
 		if err != nil {
 			return resultMsg{
 				result: &services.ResourceResult{
@@ -364,9 +606,17 @@ func (m Model) createS3Bucket() tea.Cmd {
 		params := map[string]interface{}{
 			"bucket_name": m.bucketName,
 			"region":      m.region,
+			"tags":        m.tags,
+			"dry_run":     dryRun,
+		}
+		if m.defaults.S3.Versioning {
+			params["versioning"] = true
+		}
+		if m.defaults.S3.Encryption {
+			params["encryption"] = true
 		}
 
-		result, err := s3Service.CreateResource(context.TODO(), params)
+		result, err := s3Service.CreateResource(ctx, params)
 		if err != nil {
 			return resultMsg{
 				result: &services.ResourceResult{
@@ -381,8 +631,8 @@ func (m Model) createS3Bucket() tea.Cmd {
 	}
 }
 
-// createEC2Instances creates EC2 instances
-func (m Model) createEC2Instances() tea.Cmd {
+// createEC2Instances creates EC2 instances, or only previews them when dryRun is set
+func (m Model) createEC2Instances(dryRun bool) tea.Cmd {
 	return func() tea.Msg {
 		if m.imageID == "" || m.instanceType == "" || m.keyName == "" {
 			return resultMsg{
@@ -394,7 +644,10 @@ func (m Model) createEC2Instances() tea.Cmd {
 			}
 		}
 
-		ec2Service, err := services.NewEC2Service(m.region)
+		ctx, span := tracer().Start(context.Background(), "tui launch instances")
+		defer span.End()
+
+		ec2Service, err := services.NewEC2Service(m.region, append(m.servicesOptions(), services.WithLoadContext(ctx))...)
 		if err != nil {
 			return resultMsg{
 				result: &services.ResourceResult{
@@ -411,9 +664,15 @@ func (m Model) createEC2Instances() tea.Cmd {
 			"key_name":      m.keyName,
 			"count":         m.count,
 			"region":        m.region,
+			"tags":          m.tags,
+			"dry_run":       dryRun,
+			"client_token":  m.clientToken,
+		}
+		if len(m.defaults.EC2.SecurityGroups) > 0 {
+			params["security_group_ids"] = m.defaults.EC2.SecurityGroups
 		}
 
-		result, err := ec2Service.CreateResource(context.TODO(), params)
+		result, err := ec2Service.CreateResource(ctx, params)
 		if err != nil {
 			return resultMsg{
 				result: &services.ResourceResult{
@@ -428,6 +687,43 @@ func (m Model) createEC2Instances() tea.Cmd {
 	}
 }
 
+// checkDrift compares a recorded resource with its live configuration
+func (m Model) checkDrift(record inventory.Record) tea.Cmd {
+	return func() tea.Msg {
+		checker, err := services.NewDriftChecker(record.Service, record.Region, m.servicesOptions()...)
+		if err != nil {
+			return driftMsg{report: services.DriftReport{
+				Service: record.Service,
+				Type:    record.Type,
+				ID:      record.ID,
+				Region:  record.Region,
+				Status:  services.DriftError,
+				Error:   err.Error(),
+				Err:     err,
+			}}
+		}
+		return driftMsg{report: checker.CheckDrift(context.TODO(), record)}
+	}
+}
+
+// driftMsg carries the result of a drift check
+type driftMsg struct {
+	report services.DriftReport
+}
+
+// progressMsg carries a progress event of a running operation
+type progressMsg struct {
+	event services.ProgressEvent
+}
+
+// waitForProgress waits for the next progress event. Update issues it again
+// after every event, so events are received for as long as the program runs.
+func waitForProgress(ch <-chan services.ProgressEvent) tea.Cmd {
+	return func() tea.Msg {
+		return progressMsg{event: <-ch}
+	}
+}
+
 // resultMsg represents a result message
 type resultMsg struct {
 	result *services.ResourceResult
@@ -436,6 +732,7 @@ type resultMsg struct {
 // Update handles result messages
 func (m Model) handleResult(msg resultMsg) (tea.Model, tea.Cmd) {
 	m.result = msg.result
+	m.status = ""
 	m.screen = ResultScreen
 	m.cursor = 0
 	return m, nil
@@ -456,12 +753,26 @@ func (m Model) View() string {
 		return m.renderEC2CreateInstances()
 	case ResultScreen:
 		return m.renderResult()
+	case InventoryScreen:
+		return m.renderInventory()
+	case InventoryDetail:
+		return m.renderInventoryDetail()
+	case ContextScreen:
+		return m.renderContexts()
 	}
 	return ""
 }
 
+// header renders the title of a screen with the context in use
+func (m Model) header(title string) string {
+	if m.cfg != nil && m.cfg.Context != "" {
+		title = fmt.Sprintf("%s │ context: %s (%s)", title, m.cfg.Context, m.cfg.AWS.Region)
+	}
+	return titleStyle.Render(title)
+}
+
 func (m Model) renderMainMenu() string {
-	s := titleStyle.Render("AWS Resources CLI") + "\n\n"
+	s := m.header("AWS Resources CLI") + "\n\n"
 	s += "Choose a service to manage:\n\n"
 
 	for i, choice := range m.getChoices() {
@@ -475,12 +786,41 @@ func (m Model) renderMainMenu() string {
 		s += fmt.Sprintf("%s %s\n", cursor, choice)
 	}
 
-	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use ↑/↓ to navigate, Enter to select, q to quit")
+	help := "Use ↑/↓ to navigate, Enter to select, q to quit"
+	if m.cfg != nil && len(m.cfg.Contexts) > 0 {
+		help = "Use ↑/↓ to navigate, Enter to select, x to switch context, q to quit"
+	}
+	s += "\n" + lipgloss.NewStyle().Faint(true).Render(help)
+	return s
+}
+
+func (m Model) renderContexts() string {
+	s := m.header("Switch Context") + "\n\n"
+	s += "Contexts of " + m.cfg.Path + ":\n\n"
+
+	for i, choice := range m.getChoices() {
+		if choice == m.cfg.Context {
+			choice += " (in use)"
+		}
+		cursor := " "
+		if m.cursor == i {
+			cursor = ">"
+			choice = selectedItemStyle.Render(choice)
+		} else {
+			choice = itemStyle.Render(choice)
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, choice)
+	}
+
+	if m.errorMsg != "" {
+		s += "\n" + errorStyle.Render(m.errorMsg) + "\n"
+	}
+	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use ↑/↓ to navigate, Enter to switch for this session, Esc to go back")
 	return s
 }
 
 func (m Model) renderS3Menu() string {
-	s := titleStyle.Render("S3 - Simple Storage Service") + "\n\n"
+	s := m.header("S3 - Simple Storage Service") + "\n\n"
 	s += "Choose an action:\n\n"
 
 	for i, choice := range m.getChoices() {
@@ -499,7 +839,7 @@ func (m Model) renderS3Menu() string {
 }
 
 func (m Model) renderEC2Menu() string {
-	s := titleStyle.Render("EC2 - Elastic Compute Cloud") + "\n\n"
+	s := m.header("EC2 - Elastic Compute Cloud") + "\n\n"
 	s += "Choose an action:\n\n"
 
 	for i, choice := range m.getChoices() {
@@ -518,7 +858,7 @@ func (m Model) renderEC2Menu() string {
 }
 
 func (m Model) renderS3CreateBucket() string {
-	s := titleStyle.Render("Create S3 Bucket") + "\n\n"
+	s := m.header("Create S3 Bucket") + "\n\n"
 
 	// Bucket Name field
 	bucketLabel := "Bucket Name:"
@@ -552,6 +892,22 @@ func (m Model) renderS3CreateBucket() string {
 	s += regionLabel + "\n"
 	s += inputStyle.Render(regionInput) + "\n\n"
 
+	// Tags field
+	tagsLabel := "Tags (key=value,...):"
+	if m.inputField == 2 {
+		tagsLabel = selectedItemStyle.Render("→ " + tagsLabel)
+	} else {
+		tagsLabel = itemStyle.Render(tagsLabel)
+	}
+
+	tagsInput := m.tags
+	if m.inputField == 2 && m.inputActive {
+		tagsInput += "_"
+	}
+
+	s += tagsLabel + "\n"
+	s += inputStyle.Render(tagsInput) + "\n\n"
+
 	// Action buttons
 	for i, choice := range m.getChoices() {
 		cursor := " "
@@ -564,12 +920,16 @@ func (m Model) renderS3CreateBucket() string {
 		s += fmt.Sprintf("%s %s\n", cursor, choice)
 	}
 
-	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use Tab to switch fields, Enter to edit/confirm, Esc to go back")
+	if m.status != "" {
+		s += "\n" + successStyle.Render(m.status) + "\n"
+	}
+
+	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use Tab to edit/switch fields, Enter to confirm/select, Esc to go back")
 	return s
 }
 
 func (m Model) renderEC2CreateInstances() string {
-	s := titleStyle.Render("Create EC2 Instances") + "\n\n"
+	s := m.header("Create EC2 Instances") + "\n\n"
 
 	fields := []struct {
 		label string
@@ -581,6 +941,7 @@ func (m Model) renderEC2CreateInstances() string {
 		{"Key Name:", m.keyName, 2},
 		{"Count:", m.count, 3},
 		{"Region:", m.region, 4},
+		{"Tags (key=value,...):", m.tags, 5},
 	}
 
 	for _, field := range fields {
@@ -612,7 +973,11 @@ func (m Model) renderEC2CreateInstances() string {
 		s += fmt.Sprintf("%s %s\n", cursor, choice)
 	}
 
-	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use Tab to switch fields, Enter to edit/confirm, Esc to go back")
+	if m.status != "" {
+		s += "\n" + successStyle.Render(m.status) + "\n"
+	}
+
+	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use Tab to edit/switch fields, Enter to confirm/select, Esc to go back")
 	return s
 }
 
@@ -624,7 +989,11 @@ func (m Model) renderResult() string {
 	var s strings.Builder
 	
 	if m.result.Success {
-		s.WriteString(successStyle.Render("✅ Success!") + "\n\n")
+		if services.IsDryRun(m.result) {
+			s.WriteString(successStyle.Render("🔍 Dry run passed - nothing was created") + "\n\n")
+		} else {
+			s.WriteString(successStyle.Render("✅ Success!") + "\n\n")
+		}
 		s.WriteString(m.result.Message + "\n\n")
 		
 		if m.result.Data != nil {
@@ -645,9 +1014,184 @@ func (m Model) renderResult() string {
 	return s.String()
 }
 
-// Run starts the Bubble Tea application
+// recordSummary renders an inventory record as a single line
+func recordSummary(record inventory.Record) string {
+	summary := fmt.Sprintf("%-4s %-9s %-24s %-15s %s", record.Service, record.Type, record.ID, record.Region,
+		record.CreatedAt.Local().Format("2006-01-02 15:04"))
+	if record.Deleted() {
+		summary += " (deleted)"
+	}
+	return summary
+}
+
+func (m Model) renderInventory() string {
+	s := m.header("Inventory - Resources created by this tool") + "\n\n"
+
+	if m.inventory == nil || m.inventoryErr != nil {
+		err := m.inventoryErr
+		if err == nil {
+			err = fmt.Errorf("no inventory store configured")
+		}
+		s += errorStyle.Render("Inventory unavailable: "+err.Error()) + "\n\n"
+	} else {
+		filter := inventoryFilters[m.inventoryFilter]
+		if filter == "" {
+			filter = "all services"
+		}
+		s += fmt.Sprintf("Showing %d resource(s) from %s", len(m.records), filter)
+		if m.showDeleted {
+			s += ", including deleted"
+		}
+		s += "\n" + lipgloss.NewStyle().Faint(true).Render(m.inventory.Path()) + "\n\n"
+	}
+
+	for i, choice := range m.getChoices() {
+		cursor := " "
+		if m.cursor == i {
+			cursor = ">"
+			choice = selectedItemStyle.Render(choice)
+		} else {
+			choice = itemStyle.Render(choice)
+		}
+		s += fmt.Sprintf("%s %s\n", cursor, choice)
+	}
+
+	s += "\n" + lipgloss.NewStyle().Faint(true).Render("Use ↑/↓ to navigate, Enter for details, f to filter by service, d to show deleted, Esc to go back")
+	return s
+}
+
+func (m Model) renderInventoryDetail() string {
+	r := m.record
+
+	var s strings.Builder
+	s.WriteString(m.header(fmt.Sprintf("%s %s %s", r.Service, r.Type, r.ID)) + "\n\n")
+	s.WriteString(fmt.Sprintf("  ARN:     %s\n", r.ARN))
+	s.WriteString(fmt.Sprintf("  Region:  %s\n", r.Region))
+	s.WriteString(fmt.Sprintf("  Account: %s\n", r.Account))
+	if r.Session != "" {
+		s.WriteString(fmt.Sprintf("  Session: %s\n", r.Session))
+	}
+	if r.Imported {
+		s.WriteString("  Origin:  imported, existed before this tool\n")
+	}
+	s.WriteString(fmt.Sprintf("  Created: %s\n", r.CreatedAt.Local().Format("2006-01-02 15:04:05")))
+	s.WriteString(fmt.Sprintf("  Updated: %s\n", r.UpdatedAt.Local().Format("2006-01-02 15:04:05")))
+	if r.Deleted() {
+		s.WriteString(fmt.Sprintf("  Deleted: %s\n", r.DeletedAt.Local().Format("2006-01-02 15:04:05")))
+	}
+
+	s.WriteString("\nParameters:\n")
+	keys := make([]string, 0, len(r.Params))
+	for key := range r.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.WriteString(fmt.Sprintf("  %s: %v\n", key, r.Params[key]))
+	}
+
+	s.WriteString("\nTags:\n")
+	keys = keys[:0]
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.WriteString(fmt.Sprintf("  %s = %s\n", key, r.Tags[key]))
+	}
+
+	if m.drift != nil {
+		s.WriteString("\nDrift:\n")
+		if m.drift.Status == services.DriftInSync {
+			s.WriteString(successStyle.Render("  In sync with the recorded configuration") + "\n")
+		} else {
+			s.WriteString(errorStyle.Render("  "+driftStatusText(*m.drift)) + "\n")
+			for _, diff := range m.drift.Differences {
+				s.WriteString(fmt.Sprintf("  %-20s desired %-12s actual %s\n", diff.Field, driftValue(diff.Desired), driftValue(diff.Actual)))
+			}
+		}
+	}
+
+	help := "Press c to check for drift, Esc to return to the inventory"
+	if r.Deleted() {
+		help = "Press Esc to return to the inventory"
+	}
+	s.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render(help))
+	return s.String()
+}
+
+// Run runs the command given on the command line, or starts the Bubble Tea
+// application when there is none
 func Run() error {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	_, err := p.Run()
+	flags := flag.NewFlagSet("aws-resources", flag.ContinueOnError)
+	configPath := flags.String("config", "", "Configuration file, instead of $"+config.EnvVar+" or the default locations")
+	contextName := flags.String("context", "", "Context of the configuration file to use, instead of $"+config.ContextEnvVar+" or current_context")
+	// Errors are returned and -h shows the usage of the commands instead
+	flags.SetOutput(io.Discard)
+	args := []string{"help"}
+	if err := flags.Parse(os.Args[1:]); err != flag.ErrHelp {
+		if err != nil {
+			return err
+		}
+		args = flags.Args()
+	}
+
+	env, err := newEnvironment(os.Stdin, os.Stdout, *configPath, *contextName)
+	if err != nil {
+		return err
+	}
+
+	interactive := len(args) == 0
+	options := telemetry.Options{
+		Exporter: env.cfg.Telemetry.Exporter,
+		Endpoint: env.cfg.Telemetry.Endpoint,
+		Insecure: env.cfg.Telemetry.Insecure,
+	}
+	if interactive && options.Exporter == telemetry.ExporterStdout {
+		// Spans written to stdout would corrupt the interface
+		options.Exporter = telemetry.ExporterNone
+	}
+	shutdown, err := telemetry.Setup(context.Background(), options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdown(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to export telemetry: %v\n", err)
+		}
+	}()
+
+	logger, closer, err := newLogger(env.cfg.Logging, interactive)
+	if err != nil {
+		if !interactive {
+			return err
+		}
+		// The interface still starts, the message is shown before it
+		fmt.Fprintf(os.Stderr, "logging disabled: %v\n", err)
+		logger = logging.Discard()
+	} else {
+		defer closer.Close()
+	}
+	env.logger = logger
+	if env.inventoryErr != nil {
+		logger.Warn("Inventory unavailable", "error", env.inventoryErr)
+	}
+
+	// Arguments select a non-interactive command
+	if !interactive {
+		return env.execute(context.Background(), args)
+	}
+
+	applyTheme(env.cfg.UI.Theme)
+	m := initialModel(env.cfg)
+	m.provider = env.provider
+	m.inventory, m.inventoryErr = env.inventory, env.inventoryErr
+	m.session = env.session
+	m.hooks = env.hooks
+	m.logger = env.logger
+	m.progress = make(chan services.ProgressEvent, 16)
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err = p.Run()
 	return err
 }
\ No newline at end of file
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Tech-Preta/aws-resources/pkg/config"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+// press sends a sequence of keys to the model and returns the last command
+func press(t *testing.T, m Model, keys ...string) (Model, tea.Cmd) {
+	t.Helper()
+
+	var cmd tea.Cmd
+	for _, key := range keys {
+		var msg tea.KeyMsg
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		}
+
+		var model tea.Model
+		model, cmd = m.Update(msg)
+		m = model.(Model)
+	}
+	return m, cmd
+}
+
+// typeText sends every character of text as a key press
+func typeText(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	for _, r := range text {
+		m, _ = press(t, m, string(r))
+	}
+	return m
+}
+
+// run executes a command and feeds its message back into the model
+func run(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("Expected a command")
+	}
+	model, _ := m.Update(cmd())
+	return model.(Model)
+}
+
+func TestCreateBucketFlow(t *testing.T) {
+	backend := fake.NewS3()
+	m := initialModel(config.Default())
+	m.serviceOptions = []services.Option{services.WithS3Client(backend)}
+
+	// Main menu -> S3 menu -> Create bucket form, then edit the bucket name
+	m, _ = press(t, m, "enter", "enter", "tab")
+	m = typeText(t, m, "quick-bucket")
+	m, cmd := press(t, m, "enter", "enter")
+
+	m = run(t, m, cmd)
+	if m.screen != ResultScreen || m.result == nil || !m.result.Success {
+		t.Fatalf("Expected successful result screen, got screen %d with %+v", m.screen, m.result)
+	}
+	if _, ok := backend.Bucket("quick-bucket"); !ok {
+		t.Error("Expected bucket to be created in the fake backend")
+	}
+}
+
+func TestCreateBucketShowsProgress(t *testing.T) {
+	m := initialModel(config.Default())
+	m.serviceOptions = []services.Option{services.WithS3Client(fake.NewS3())}
+	m.progress = make(chan services.ProgressEvent, 16)
+
+	m, _ = press(t, m, "enter", "enter", "tab")
+	m = typeText(t, m, "status-bucket")
+	m, cmd := press(t, m, "enter", "enter")
+	result := cmd()
+
+	// The form shows each event until the result arrives
+	wait := m.Init()
+	for i := 0; i < 2; i++ {
+		model, next := m.Update(wait())
+		m, wait = model.(Model), next
+	}
+	if m.screen != S3CreateBucket || !strings.Contains(m.View(), "calling_api: Creating bucket status-bucket") {
+		t.Errorf("Expected the form to show the API call, got status %q", m.status)
+	}
+
+	model, _ := m.Update(result)
+	m = model.(Model)
+	if m.screen != ResultScreen || m.status != "" {
+		t.Errorf("Expected the result screen without a status, got screen %d with status %q", m.screen, m.status)
+	}
+}
+
+func TestLaunchInstancesFlow(t *testing.T) {
+	backend := fake.NewEC2("us-east-1")
+	m := initialModel(config.Default())
+	m.serviceOptions = []services.Option{services.WithEC2Client(backend)}
+
+	// Main menu -> EC2 menu -> Launch form
+	m, _ = press(t, m, "down", "enter", "enter", "tab")
+	m = typeText(t, m, "ami-12345678")
+	m, _ = press(t, m, "tab")
+	m = typeText(t, m, "t2.micro")
+	m, _ = press(t, m, "tab")
+	m = typeText(t, m, "my-key")
+	m, _ = press(t, m, "tab", "backspace")
+	m = typeText(t, m, "2")
+	m, cmd := press(t, m, "enter", "enter")
+
+	m = run(t, m, cmd)
+	if !m.result.Success {
+		t.Fatalf("Expected successful launch, got %+v", m.result)
+	}
+	if got := len(backend.Instances()); got != 2 {
+		t.Errorf("Expected 2 instances, got %d", got)
+	}
+}
+
+func TestTypingQDoesNotQuitWhileEditing(t *testing.T) {
+	m := initialModel(config.Default())
+	m, _ = press(t, m, "enter", "enter", "tab")
+
+	m, cmd := press(t, m, "q")
+	if cmd != nil {
+		t.Error("Expected no command while typing")
+	}
+	if m.bucketName != "q" {
+		t.Errorf("Expected bucket name 'q', got %q", m.bucketName)
+	}
+}
+
+func TestPreviewBucket(t *testing.T) {
+	backend := fake.NewS3()
+	m := initialModel(config.Default())
+	m.serviceOptions = []services.Option{services.WithS3Client(backend)}
+
+	m, _ = press(t, m, "enter", "enter", "tab")
+	m = typeText(t, m, "preview-bucket")
+	m, cmd := press(t, m, "enter", "down", "enter")
+
+	m = run(t, m, cmd)
+	if !m.result.Success || !services.IsDryRun(m.result) {
+		t.Fatalf("Expected successful dry run, got %+v", m.result)
+	}
+	if _, ok := backend.Bucket("preview-bucket"); ok {
+		t.Error("Expected preview not to create the bucket")
+	}
+}
+
+func TestRelaunchIsIdempotent(t *testing.T) {
+	backend := fake.NewEC2("us-east-1")
+	m := initialModel(config.Default())
+	m.serviceOptions = []services.Option{services.WithEC2Client(backend)}
+	m.imageID, m.instanceType, m.keyName = "ami-12345678", "t2.micro", "my-key"
+
+	m, _ = press(t, m, "down", "enter", "enter")
+	m, cmd := press(t, m, "enter")
+	m = run(t, m, cmd)
+
+	// Go back to the unchanged form and launch again
+	m, _ = press(t, m, "esc", "down", "enter", "enter")
+	m, cmd = press(t, m, "enter")
+	m = run(t, m, cmd)
+	if !m.result.Success {
+		t.Fatalf("Expected successful relaunch, got %+v", m.result)
+	}
+	if got := len(backend.Instances()); got != 1 {
+		t.Errorf("Expected a single instance after relaunching the same form, got %d", got)
+	}
+
+	// Editing the form starts a new launch
+	m, _ = press(t, m, "esc", "down", "enter", "enter", "tab", "tab", "tab", "tab", "backspace")
+	m = typeText(t, m, "2")
+	m, cmd = press(t, m, "enter", "enter")
+	m = run(t, m, cmd)
+	if got := len(backend.Instances()); got != 3 {
+		t.Errorf("Expected 3 instances after an edited launch, got %d", got)
+	}
+}
+
+func TestDefaultTagsAreEditable(t *testing.T) {
+	backend := fake.NewS3()
+	cfg := config.Default()
+	cfg.Defaults.Tags = map[string]string{"owner": "platform"}
+	m := initialModel(cfg)
+	m.serviceOptions = []services.Option{services.WithS3Client(backend)}
+
+	m, _ = press(t, m, "enter", "enter", "tab")
+	m = typeText(t, m, "tagged-bucket")
+	// Skip the region and append to the prefilled tags
+	m, _ = press(t, m, "tab", "tab")
+	m = typeText(t, m, ",team=data")
+	m, cmd := press(t, m, "enter", "enter")
+
+	m = run(t, m, cmd)
+	if !m.result.Success {
+		t.Fatalf("Expected successful result, got %+v", m.result)
+	}
+	bucket, _ := backend.Bucket("tagged-bucket")
+	if bucket.Tags["owner"] != "platform" || bucket.Tags["team"] != "data" {
+		t.Errorf("Expected default and edited tags, got %v", bucket.Tags)
+	}
+}
+
+func TestInventoryScreen(t *testing.T) {
+	store, err := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	m := initialModel(config.Default())
+	m.inventory = store
+	m.serviceOptions = []services.Option{services.WithS3Client(fake.NewS3())}
+
+	m, _ = press(t, m, "enter", "enter", "tab")
+	m = typeText(t, m, "listed-bucket")
+	m, cmd := press(t, m, "enter", "enter")
+	m = run(t, m, cmd)
+
+	// Back to the main menu, then open the inventory
+	m, _ = press(t, m, "esc", "down", "down", "enter")
+	if m.screen != InventoryScreen || len(m.records) != 1 {
+		t.Fatalf("Expected inventory with one record, got screen %d with %d records", m.screen, len(m.records))
+	}
+
+	m, _ = press(t, m, "enter")
+	if m.screen != InventoryDetail || m.record.ID != "listed-bucket" {
+		t.Errorf("Expected details of listed-bucket, got %+v", m.record)
+	}
+
+	m, cmd = press(t, m, "c")
+	m = run(t, m, cmd)
+	if m.drift == nil || m.drift.Status != services.DriftInSync {
+		t.Errorf("Expected bucket in sync, got %+v", m.drift)
+	}
+
+	// Filtering on EC2 hides the bucket
+	m, _ = press(t, m, "esc", "f", "f")
+	if len(m.records) != 0 {
+		t.Errorf("Expected no EC2 records, got %d", len(m.records))
+	}
+}
+
+func TestInitialModelUsesConfigDefaults(t *testing.T) {
+	cfg := config.Default()
+	cfg.AWS.Region = "sa-east-1"
+	cfg.Defaults.EC2 = config.EC2Defaults{Region: "eu-west-1", InstanceType: "t3.small", KeyName: "ops", Count: 2}
+	cfg.Defaults.S3.Region = ""
+
+	m := initialModel(cfg)
+	if m.region != "sa-east-1" || m.instanceType != "t3.small" || m.keyName != "ops" || m.count != "2" {
+		t.Errorf("Expected the config defaults, got region %s, type %s, key %s, count %s", m.region, m.instanceType, m.keyName, m.count)
+	}
+	if len(m.choices) != 4 || m.choices[2] != "Inventory" {
+		t.Errorf("Expected the main menu choices, got %v", m.choices)
+	}
+
+	// Each form starts in the region of its service
+	m, _ = press(t, m, "down", "enter", "enter")
+	if m.region != "eu-west-1" {
+		t.Errorf("Expected the EC2 region, got %s", m.region)
+	}
+	m, _ = press(t, m, "esc", "esc", "enter", "enter")
+	if m.screen != S3CreateBucket || m.region != "sa-east-1" {
+		t.Errorf("Expected the S3 form to use the AWS region without an S3 default, got %s", m.region)
+	}
+}
+
+func TestContextSwitcher(t *testing.T) {
+	t.Setenv(config.ContextEnvVar, "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "contexts:\n  dev:\n    region: us-east-1\n  prod:\n    region: sa-east-1\n    ec2:\n      key_name: prod-key\ncurrent_context: dev\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := config.Resolve(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	m := initialModel(cfg)
+	if !strings.Contains(m.View(), "context: dev (us-east-1)") {
+		t.Errorf("Expected the context in the header, got:\n%s", m.View())
+	}
+
+	m, _ = press(t, m, "x")
+	if m.screen != ContextScreen {
+		t.Fatalf("Expected the context switcher, got screen %d", m.screen)
+	}
+	m, _ = press(t, m, "down", "enter")
+	if m.screen != MainMenu || !strings.Contains(m.View(), "context: prod (sa-east-1)") {
+		t.Errorf("Expected prod in the header, got:\n%s", m.View())
+	}
+	if m.region != "sa-east-1" || m.keyName != "prod-key" {
+		t.Errorf("Expected the defaults of prod, got region %s and key %s", m.region, m.keyName)
+	}
+
+	// Switching only lasts for the session
+	if cfg, _ := config.Resolve(path, ""); cfg.Context != "dev" {
+		t.Errorf("Expected the file to keep dev, got %s", cfg.Context)
+	}
+}
@@ -0,0 +1,1107 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/config"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/logging"
+	"github.com/Tech-Preta/aws-resources/pkg/manifest"
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// tracer starts the root span of every command, so each run is one trace.
+// It is taken from the global provider at call time, after telemetry.Setup.
+func tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer("github.com/Tech-Preta/aws-resources/pkg/cli")
+}
+
+// ErrDriftDetected is returned by the drift command when a resource differs
+// from its recorded configuration, so scripts can rely on the exit status
+var ErrDriftDetected = errors.New("drift detected")
+
+// environment holds what commands share with the TUI
+type environment struct {
+	cfg          *config.Config
+	provider     *awsconfig.Provider
+	inventory    *inventory.Store
+	inventoryErr error
+	// session identifies this run in the records of the resources it creates
+	session string
+	stdin   io.Reader
+	stdout  io.Writer
+	// hooks are the hooks of the config file
+	hooks []services.Option
+	// logger is the logger of the config file, set by Run
+	logger *slog.Logger
+	// serviceOptions are applied to every service after the provider, e.g. fake clients in tests
+	serviceOptions []services.Option
+}
+
+// newEnvironment loads the configuration, from configPath and with the
+// named context when they are set, and opens the inventory. An inventory
+// that cannot be opened is only reported by the commands that need it.
+func newEnvironment(stdin io.Reader, stdout io.Writer, configPath, context string) (*environment, error) {
+	cfg, err := config.Resolve(configPath, context)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := hookOptions(cfg.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &environment{
+		cfg:      cfg,
+		provider: awsconfig.NewProvider(providerOptions(cfg)...),
+		session:  inventory.NewSessionID(),
+		hooks:    hooks,
+		stdin:    stdin,
+		stdout:   stdout,
+	}
+	if path, err := inventory.DefaultPath(); err != nil {
+		env.inventoryErr = err
+	} else {
+		env.inventory, env.inventoryErr = inventory.Open(path)
+	}
+	return env, nil
+}
+
+// servicesOptions returns the options used to create every service
+func (e *environment) servicesOptions() []services.Option {
+	opts := []services.Option{services.WithProvider(e.provider)}
+	if e.inventory != nil {
+		opts = append(opts, services.WithInventory(e.inventory))
+	}
+	if e.session != "" {
+		opts = append(opts, services.WithSession(e.session))
+	}
+	if len(e.cfg.Defaults.Tags) > 0 {
+		opts = append(opts, services.WithDefaultTags(e.cfg.Defaults.Tags))
+	}
+	if e.logger != nil {
+		opts = append(opts, services.WithLogger(e.logger))
+	}
+	opts = append(opts, e.hooks...)
+	return append(opts, e.serviceOptions...)
+}
+
+// providerOptions returns the AWS settings of the config file as provider options
+func providerOptions(cfg *config.Config) []awsconfig.Option {
+	opts := []awsconfig.Option{awsconfig.WithAPITimeout(time.Duration(cfg.UI.Timeout.APICalls) * time.Second)}
+	if cfg.AWS.Profile != "" {
+		opts = append(opts, awsconfig.WithProfile(cfg.AWS.Profile))
+	}
+	if cfg.AWS.RoleARN != "" {
+		opts = append(opts, awsconfig.WithAssumeRole(awsconfig.RoleConfig{RoleARN: cfg.AWS.RoleARN, ExternalID: cfg.AWS.ExternalID}))
+	}
+	return opts
+}
+
+// serviceRegion returns the default region of a service in the config file
+func (e *environment) serviceRegion(service string) string {
+	region := ""
+	switch service {
+	case services.S3ServiceName:
+		region = e.cfg.Defaults.S3.Region
+	case services.EC2ServiceName:
+		region = e.cfg.Defaults.EC2.Region
+	}
+	if region == "" {
+		return e.cfg.AWS.Region
+	}
+	return region
+}
+
+// createDefaults returns the create parameters of a service set in the
+// config file, which parameters given on the command line override
+func (e *environment) createDefaults(service string) map[string]interface{} {
+	params := map[string]interface{}{}
+	switch service {
+	case services.S3ServiceName:
+		// Disabled settings are left alone rather than turned off
+		if e.cfg.Defaults.S3.Versioning {
+			params["versioning"] = true
+		}
+		if e.cfg.Defaults.S3.Encryption {
+			params["encryption"] = true
+		}
+	case services.EC2ServiceName:
+		defaults := e.cfg.Defaults.EC2
+		if defaults.InstanceType != "" {
+			params["instance_type"] = defaults.InstanceType
+		}
+		if defaults.KeyName != "" {
+			params["key_name"] = defaults.KeyName
+		}
+		if len(defaults.SecurityGroups) > 0 {
+			params["security_group_ids"] = defaults.SecurityGroups
+		}
+		if defaults.Count > 0 {
+			params["count"] = defaults.Count
+		}
+	}
+	return params
+}
+
+// newLogger creates the logger of the logging section of the config file.
+// The interactive interface owns the terminal, so it only logs to
+// file_path, or nowhere when there is none.
+func newLogger(cfg config.LoggingConfig, interactive bool) (*slog.Logger, io.Closer, error) {
+	options := logging.Options{
+		Level:      cfg.Level,
+		Output:     cfg.Output,
+		FilePath:   cfg.FilePath,
+		MaxSize:    int64(cfg.MaxSizeMB) << 20,
+		MaxBackups: cfg.MaxBackups,
+	}
+	if interactive && options.Output != logging.OutputFile && options.Output != logging.OutputNone {
+		options.Output = logging.OutputNone
+		if options.FilePath != "" {
+			options.Output = logging.OutputFile
+		}
+	}
+	return logging.New(options)
+}
+
+// hookOptions turns the hooks of the config file into service options
+func hookOptions(configured []config.HookConfig) ([]services.Option, error) {
+	opts := make([]services.Option, 0, len(configured))
+	for i, hook := range configured {
+		point := services.HookPoint(hook.Point)
+		if !services.ValidHookPoint(point) {
+			return nil, fmt.Errorf("hook %d: unknown point %q, expected one of %v", i+1, hook.Point, services.HookPoints)
+		}
+		if len(hook.Command) == 0 {
+			return nil, fmt.Errorf("hook %d: a command is required", i+1)
+		}
+
+		fn := services.CommandHook(hook.Command, time.Duration(hook.Timeout)*time.Second)
+		if hook.Service != "" {
+			fn = services.ServiceHook(hook.Service, fn)
+		}
+		opts = append(opts, services.WithHook(point, fn))
+	}
+	return opts, nil
+}
+
+// showProgress makes services print the progress of their create and
+// delete operations to stdout, one line per event
+func (e *environment) showProgress() {
+	var mu sync.Mutex
+	e.serviceOptions = append(e.serviceOptions, services.WithProgress(func(event services.ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(e.stdout, event.String())
+	}))
+}
+
+// progressFlag shows progress when the -progress flag of a command is set.
+// Progress lines would corrupt JSON output, so the flags exclude each other.
+func (e *environment) progressFlag(progress *bool, asJSON bool) error {
+	if progress == nil || !*progress {
+		return nil
+	}
+	if asJSON {
+		return errors.New("-progress cannot be combined with -json")
+	}
+	e.showProgress()
+	return nil
+}
+
+// requireInventory returns the inventory or the reason it is unavailable
+func (e *environment) requireInventory() (*inventory.Store, error) {
+	if e.inventory == nil {
+		if e.inventoryErr != nil {
+			return nil, fmt.Errorf("inventory unavailable: %w", e.inventoryErr)
+		}
+		return nil, errors.New("inventory unavailable")
+	}
+	return e.inventory, nil
+}
+
+// command is a non-interactive subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *environment, args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in the usage
+var commands = []command{
+	{name: "drift", summary: "Compare recorded resources with their live configuration", run: runDrift},
+	{name: "plan", summary: "Show the changes needed to reach a manifest", run: runPlan},
+	{name: "apply", summary: "Create, update and delete resources to reach a manifest", run: runApply},
+	{name: "destroy", summary: "Delete recorded resources selected by ID, tag, manifest or session", run: runDestroy},
+	{name: "import", summary: "Record existing buckets or instances selected by ID, name or tag", run: runImport},
+	{name: "list", summary: "List buckets or instances in one or more regions", run: runList},
+	{name: "create", summary: "Create buckets or instances in one or more regions", run: runCreate},
+	{name: "run", summary: "Run an operation in several accounts, by profile or Organizations role", run: runAccounts},
+	{name: "bulk", summary: "Run many operations from a file through a worker pool", run: runBulk},
+	{name: "context", summary: "List, show or switch the named contexts of the config file", run: runContext},
+}
+
+// execute runs the subcommand named by args[0]
+func (e *environment) execute(ctx context.Context, args []string) error {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		e.usage()
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			ctx, span := tracer().Start(ctx, "aws-resources "+name)
+			defer span.End()
+
+			logger := e.logger
+			if logger == nil {
+				logger = logging.Discard()
+			}
+			logger = logger.With("command", name, "session", e.session)
+			logger.Debug("Command started")
+			start := time.Now()
+
+			err := cmd.run(ctx, e, args[1:])
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				logger.Error("Command failed", "duration", time.Since(start), "error", err)
+			} else {
+				logger.Debug("Command finished", "duration", time.Since(start))
+			}
+			return err
+		}
+	}
+
+	e.usage()
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// usage prints the available subcommands
+func (e *environment) usage() {
+	fmt.Fprintln(e.stdout, "Usage: aws-resources [-config file] [-context name] [command] [flags]")
+	fmt.Fprintln(e.stdout, "\nWithout a command the interactive interface is started.\n\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(e.stdout, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// confirm asks a question on stdout and reports whether "yes" was answered
+func (e *environment) confirm(question string) bool {
+	fmt.Fprintf(e.stdout, "\n%s Only 'yes' is accepted: ", question)
+	answer, _ := bufio.NewReader(e.stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// newFlagSet creates the flag set of a subcommand writing to stdout
+func (e *environment) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	return flags
+}
+
+// runDrift implements the drift command
+func runDrift(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("drift")
+	service := flags.String("service", "", "Only check resources of this service (s3 or ec2)")
+	region := flags.String("region", "", "Only check resources in this region")
+	tags := flags.String("tag", "", "Only check resources with these tags, as key=value,...")
+	accountRole := flags.String("account-role", "", "Check resources recorded in other accounts by assuming this role in them, e.g. OrganizationAccountAccessRole")
+	asJSON := flags.Bool("json", false, "Print the reports as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := env.requireInventory()
+	if err != nil {
+		return err
+	}
+
+	filter := inventory.Filter{Service: *service, Region: *region}
+	if *tags != "" {
+		if filter.Tags, err = services.ParseTags(*tags); err != nil {
+			return err
+		}
+	}
+
+	opts := append(env.servicesOptions(), services.WithAccountRole(*accountRole))
+	reports, err := services.DetectDrift(ctx, store, filter, opts...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(env.stdout, formatDriftReports(reports))
+	}
+
+	for _, report := range reports {
+		if report.Status != services.DriftInSync && report.Status != services.DriftSkipped {
+			return ErrDriftDetected
+		}
+	}
+	return nil
+}
+
+// formatDriftReports renders drift reports as text
+func formatDriftReports(reports []services.DriftReport) string {
+	if len(reports) == 0 {
+		return "No recorded resources to check\n"
+	}
+
+	var s strings.Builder
+	for _, report := range reports {
+		s.WriteString(fmt.Sprintf("%s %s %s (%s): %s\n", report.Service, report.Type, report.ID, report.Region, driftStatusText(report)))
+		for _, diff := range report.Differences {
+			s.WriteString(fmt.Sprintf("  %s: desired %s, actual %s\n", diff.Field, driftValue(diff.Desired), driftValue(diff.Actual)))
+		}
+	}
+	return s.String()
+}
+
+// driftStatusText describes the status of a report
+func driftStatusText(report services.DriftReport) string {
+	switch report.Status {
+	case services.DriftInSync:
+		return "in sync"
+	case services.DriftDetected:
+		return fmt.Sprintf("drifted (%d difference(s))", len(report.Differences))
+	case services.DriftMissing:
+		return "missing, deleted outside this tool"
+	case services.DriftError:
+		return "check failed: " + report.Error
+	case services.DriftSkipped:
+		return "not checked, " + report.Error
+	}
+	return string(report.Status)
+}
+
+// driftValue renders one side of a difference
+func driftValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	return fmt.Sprint(value)
+}
+
+// loadManifest reads a manifest, filling in the region and default tags of the configuration
+func (e *environment) loadManifest(path string) (*manifest.Manifest, error) {
+	if path == "" {
+		return nil, errors.New("a manifest is required, pass it with -f")
+	}
+
+	m, err := manifest.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if m.Defaults.Region == "" {
+		m.Defaults.Region = e.cfg.AWS.Region
+	}
+	m.Defaults.Tags = services.MergeTags(e.cfg.Defaults.Tags, m.Defaults.Tags)
+	return m, nil
+}
+
+// planManifest loads a manifest and computes its plan
+func (e *environment) planManifest(ctx context.Context, path string) (*manifest.Planner, *manifest.Plan, error) {
+	m, err := e.loadManifest(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := e.requireInventory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	planner := manifest.NewPlanner(store, e.servicesOptions()...)
+	plan, err := planner.Plan(ctx, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return planner, plan, nil
+}
+
+// runPlan implements the plan command
+func runPlan(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("plan")
+	path := flags.String("f", "", "Manifest file")
+	asJSON := flags.Bool("json", false, "Print the plan as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	_, plan, err := env.planManifest(ctx, *path)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+	fmt.Fprint(env.stdout, formatPlan(plan))
+	return nil
+}
+
+// runApply implements the apply command
+func runApply(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("apply")
+	path := flags.String("f", "", "Manifest file")
+	autoApprove := flags.Bool("auto-approve", false, "Apply without asking for confirmation")
+	rollback := flags.Bool("rollback", false, "Undo the resources created by this apply when an action fails")
+	progress := flags.Bool("progress", false, "Print the progress of every create and delete as it happens")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := env.progressFlag(progress, false); err != nil {
+		return err
+	}
+
+	planner, plan, err := env.planManifest(ctx, *path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(env.stdout, formatPlan(plan))
+	if !plan.HasChanges() {
+		return nil
+	}
+
+	if !*autoApprove && !env.confirm("Apply these changes?") {
+		fmt.Fprintln(env.stdout, "Apply cancelled")
+		return nil
+	}
+
+	fmt.Fprintln(env.stdout)
+	var opts []manifest.ApplyOption
+	if *rollback {
+		opts = append(opts, manifest.WithRollback())
+	}
+	result := planner.Apply(ctx, plan, opts...)
+	for _, r := range result.Results {
+		status := "done"
+		switch {
+		case r.Skipped:
+			status = r.Result.Message
+		case !r.Result.Success:
+			status = "failed: " + r.Result.Message
+		}
+		fmt.Fprintf(env.stdout, "%s %s: %s\n", r.Action.Type, actionName(r.Action), status)
+	}
+
+	if result.Rollback != nil {
+		fmt.Fprint(env.stdout, formatRollback(result.Rollback))
+	}
+	if failed := len(result.Failed()); failed > 0 {
+		return fmt.Errorf("%d of %d action(s) failed", failed, len(result.Results))
+	}
+	fmt.Fprintf(env.stdout, "\nApply complete: %d action(s)\n", len(result.Results))
+	return nil
+}
+
+// formatPlan renders a plan as text
+func formatPlan(plan *manifest.Plan) string {
+	var s strings.Builder
+	symbols := map[manifest.ActionType]string{
+		manifest.ActionCreate: "+",
+		manifest.ActionUpdate: "~",
+		manifest.ActionDelete: "-",
+	}
+
+	for _, action := range plan.Actions {
+		s.WriteString(fmt.Sprintf("%s %s %s in %s\n", symbols[action.Type], action.Type, actionName(action), action.Region))
+		if count, ok := action.Params["count"].(int); ok && count > 1 {
+			s.WriteString(fmt.Sprintf("    count: %d\n", count))
+		}
+		if deps := plan.Dependencies[action.Service+"."+action.Name]; len(deps) > 0 {
+			s.WriteString(fmt.Sprintf("    after: %s\n", strings.Join(deps, ", ")))
+		}
+		for _, change := range action.Changes {
+			s.WriteString(fmt.Sprintf("    %s: %s -> %s\n", change.Field, driftValue(change.Actual), driftValue(change.Desired)))
+		}
+		for _, note := range action.Notes {
+			s.WriteString(fmt.Sprintf("    note: %s\n", note))
+		}
+	}
+	for _, warning := range plan.Warnings {
+		s.WriteString(fmt.Sprintf("warning: %s\n", warning))
+	}
+
+	if !plan.HasChanges() {
+		s.WriteString("No changes, resources match the manifest\n")
+		return s.String()
+	}
+	s.WriteString(fmt.Sprintf("\nPlan: %d to create, %d to update, %d to delete\n",
+		plan.Count(manifest.ActionCreate), plan.Count(manifest.ActionUpdate), plan.Count(manifest.ActionDelete)))
+	return s.String()
+}
+
+// formatRollback renders a rollback report as text
+func formatRollback(report *services.RollbackReport) string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("\nRolled back: %d undone, %d could not be undone\n", len(report.Undone), len(report.Failed)))
+	for _, step := range report.Undone {
+		s.WriteString(fmt.Sprintf("  undone: %s\n", step.Description))
+	}
+	for _, step := range report.Failed {
+		s.WriteString(fmt.Sprintf("  not undone: %s: %s\n", step.Description, step.Error))
+	}
+	return s.String()
+}
+
+// actionName identifies the resource of an action, e.g. s3.logs (my-logs-bucket)
+func actionName(action manifest.Action) string {
+	name := action.Service + "." + action.Name
+	if action.ID != "" {
+		name += " (" + action.ID + ")"
+	}
+	return name
+}
+
+// runDestroy implements the destroy command
+func runDestroy(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("destroy")
+	ids := flags.String("id", "", "Only destroy these resources, as id,...")
+	service := flags.String("service", "", "Only destroy resources of this service (s3 or ec2)")
+	region := flags.String("region", "", "Only destroy resources in this region")
+	tags := flags.String("tag", "", "Only destroy resources with these tags, as key=value,...")
+	manifestName := flags.String("manifest", "", "Only destroy resources created from the manifest with this name")
+	session := flags.String("session", "", "Only destroy resources created by this session, see the inventory")
+	all := flags.Bool("all", false, "Destroy every recorded resource when no other selector is given")
+	includeImported := flags.Bool("include-imported", false, "Also destroy imported resources, which the tool did not create")
+	accountRole := flags.String("account-role", "", "Destroy resources recorded in other accounts by assuming this role in them, e.g. OrganizationAccountAccessRole")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be destroyed")
+	autoApprove := flags.Bool("auto-approve", false, "Destroy without asking for confirmation")
+	wait := flags.Bool("wait", true, "Wait until instances are terminated")
+	waitTimeout := flags.Duration("wait-timeout", services.DefaultWaitTimeout, "Maximum time to wait for termination")
+	progress := flags.Bool("progress", false, "Print the progress of every delete as it happens")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := env.progressFlag(progress, false); err != nil {
+		return err
+	}
+
+	store, err := env.requireInventory()
+	if err != nil {
+		return err
+	}
+
+	filter := inventory.Filter{Service: *service, Region: *region, Session: *session}
+	if *ids != "" {
+		filter.IDs = strings.Split(*ids, ",")
+	}
+	if *tags != "" {
+		if filter.Tags, err = services.ParseTags(*tags); err != nil {
+			return err
+		}
+	}
+	if *manifestName != "" {
+		filter.Tags = services.MergeTags(filter.Tags, map[string]string{manifest.TagManifest: *manifestName})
+	}
+	if !*all && len(filter.IDs) == 0 && filter.Service == "" && filter.Region == "" && filter.Session == "" && len(filter.Tags) == 0 {
+		return errors.New("select what to destroy with -id, -service, -region, -tag, -manifest or -session, or pass -all")
+	}
+
+	opts := append(env.servicesOptions(), services.WithAccountRole(*accountRole))
+	options := services.DestroyOptions{DryRun: true, IncludeImported: *includeImported}
+	preview, err := services.Destroy(ctx, store, filter, options, opts...)
+	if err != nil {
+		return err
+	}
+	if len(preview) == 0 {
+		fmt.Fprintln(env.stdout, "No recorded resources match")
+		return nil
+	}
+
+	fmt.Fprint(env.stdout, formatDestroyResults(preview))
+	if *dryRun {
+		return nil
+	}
+	planned := 0
+	for _, result := range preview {
+		if result.Status == services.DestroyPlanned {
+			planned++
+		}
+	}
+	if planned == 0 {
+		return fmt.Errorf("none of the %d resource(s) can be destroyed with these credentials", len(preview))
+	}
+	if !*autoApprove && !env.confirm(fmt.Sprintf("Destroy %d resource(s)? This cannot be undone.", planned)) {
+		fmt.Fprintln(env.stdout, "Destroy cancelled")
+		return nil
+	}
+
+	options = services.DestroyOptions{Wait: *wait, WaitTimeout: *waitTimeout, IncludeImported: *includeImported}
+	results, err := services.Destroy(ctx, store, filter, options, opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout)
+	fmt.Fprint(env.stdout, formatDestroyResults(results))
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == services.DestroyFailed || result.Status == services.DestroySkipped {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resource(s) could not be destroyed", failed, len(results))
+	}
+	return nil
+}
+
+// formatDestroyResults renders destroy results as text
+func formatDestroyResults(results []services.DestroyResult) string {
+	var s strings.Builder
+	for _, result := range results {
+		s.WriteString(fmt.Sprintf("%s %s %s (%s): %s\n", result.Service, result.Type, result.ID, result.Region, result.Message))
+	}
+	return s.String()
+}
+
+// runImport implements the import command
+func runImport(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("import")
+	service := flags.String("service", "", "Service of the resources to import (s3 or ec2)")
+	region := flags.String("region", env.cfg.AWS.Region, "Region to discover resources in")
+	ids := flags.String("id", "", "Only import these bucket names or instance IDs, as id,...")
+	name := flags.String("name", "", "Only import buckets, or instances with a Name tag, matching this pattern, e.g. logs-*")
+	tags := flags.String("tag", "", "Only import resources with these tags, as key=value,...")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be imported")
+	asJSON := flags.Bool("json", false, "Print the results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errors.New("the service to import from is required, pass it with -service")
+	}
+
+	store, err := env.requireInventory()
+	if err != nil {
+		return err
+	}
+
+	query := services.ImportQuery{NamePattern: *name}
+	if *ids != "" {
+		query.IDs = strings.Split(*ids, ",")
+	}
+	if *tags != "" {
+		if query.Tags, err = services.ParseTags(*tags); err != nil {
+			return err
+		}
+	}
+
+	options := services.ImportOptions{DryRun: *dryRun}
+	results, err := services.Import(ctx, store, *service, *region, query, options, env.servicesOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	fmt.Fprint(env.stdout, formatImportResults(results))
+	return nil
+}
+
+// formatImportResults renders import results as text
+func formatImportResults(results []services.ImportResult) string {
+	if len(results) == 0 {
+		return "No matching resources found\n"
+	}
+
+	var s strings.Builder
+	for _, result := range results {
+		r := result.Record
+		s.WriteString(fmt.Sprintf("%s %s %s (%s): %s\n", r.Service, r.Type, r.ID, r.Region, result.Message))
+	}
+	return s.String()
+}
+
+// paramsFlag collects repeated -p name=value flags into operation parameters
+type paramsFlag map[string]interface{}
+
+func (p paramsFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(p))
+}
+
+func (p paramsFlag) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid parameter %q, expected name=value", value)
+	}
+	p[name] = v
+	return nil
+}
+
+// runList implements the list command
+func runList(ctx context.Context, env *environment, args []string) error {
+	return runFanOut(ctx, env, services.OperationList, args)
+}
+
+// runCreate implements the create command
+func runCreate(ctx context.Context, env *environment, args []string) error {
+	return runFanOut(ctx, env, services.OperationCreate, args)
+}
+
+// runFanOut runs an operation in every region of the -regions flag
+func runFanOut(ctx context.Context, env *environment, operation string, args []string) error {
+	flags := env.newFlagSet(operation)
+	service := flags.String("service", "", "Service of the resources (s3 or ec2)")
+	regions := flags.String("regions", "", "Regions to run in, as region,... or all for every enabled region; the service region of the config when empty")
+	params := paramsFlag{}
+	flags.Var(params, "p", "Operation parameter as name=value, may be repeated; {region} in values is replaced with each region")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	var progress *bool
+	if operation == services.OperationCreate {
+		progress = flags.Bool("progress", false, "Print the progress of every create as it happens")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errors.New("the service is required, pass it with -service")
+	}
+	if err := env.progressFlag(progress, *asJSON); err != nil {
+		return err
+	}
+	if *regions == "" {
+		*regions = env.serviceRegion(*service)
+	}
+	if operation == services.OperationCreate {
+		merged := env.createDefaults(*service)
+		for name, value := range params {
+			merged[name] = value
+		}
+		params = merged
+	}
+
+	resolved, err := services.ResolveRegions(ctx, env.cfg.AWS.Region, services.ParseRegions(*regions), env.servicesOptions()...)
+	if err != nil {
+		return err
+	}
+
+	report, err := services.FanOut(ctx, *service, operation, resolved, params, env.servicesOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(env.stdout, formatRegionReport(report))
+	}
+
+	if !report.Success() {
+		return fmt.Errorf("%s failed in %d of %d region(s)", operation, report.Failed, len(report.Results))
+	}
+	return nil
+}
+
+// formatRegionReport renders a multi-region report as text
+func formatRegionReport(report *services.MultiRegionReport) string {
+	var s strings.Builder
+	for _, result := range report.Results {
+		status := "ok"
+		if !result.Result.Success {
+			status = "failed"
+		}
+		s.WriteString(fmt.Sprintf("%-15s %-6s %s\n", result.Region, status, result.Result.Message))
+	}
+	s.WriteString(fmt.Sprintf("\n%d region(s) succeeded, %d failed\n", report.Succeeded, report.Failed))
+	return s.String()
+}
+
+// runAccounts implements the run command
+func runAccounts(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("run")
+	service := flags.String("service", "", "Service of the resources (s3 or ec2)")
+	operation := flags.String("operation", services.OperationList, "Operation to run: create, describe, list, update or delete")
+	region := flags.String("region", env.cfg.AWS.Region, "Region to run in")
+	profiles := flags.String("profiles", "", "Accounts to run in, as named profiles profile,...")
+	orgRole := flags.String("org-role", "", "Run in every active account of the organization by assuming this role, e.g. OrganizationAccountAccessRole")
+	accountIDs := flags.String("accounts", "", "With -org-role, only run in these account IDs, as id,...")
+	concurrency := flags.Int("concurrency", services.DefaultAccountConcurrency, "Maximum number of accounts operated on at once")
+	params := paramsFlag{}
+	flags.Var(params, "p", "Operation parameter as name=value, may be repeated")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	progress := flags.Bool("progress", false, "Print the progress of every create and delete as it happens")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errors.New("the service is required, pass it with -service")
+	}
+	if err := env.progressFlag(progress, *asJSON); err != nil {
+		return err
+	}
+	if (*profiles == "") == (*orgRole == "") {
+		return errors.New("select the accounts with either -profiles or -org-role")
+	}
+
+	accounts := awsconfig.ProfileAccounts(strings.Split(*profiles, ","))
+	if *orgRole != "" {
+		members, err := services.OrganizationAccounts(ctx, *region, *orgRole, env.servicesOptions()...)
+		if err != nil {
+			return err
+		}
+		accounts = members
+		if *accountIDs != "" {
+			accounts = nil
+			wanted := strings.Split(*accountIDs, ",")
+			for _, account := range members {
+				for _, id := range wanted {
+					if account.ID == strings.TrimSpace(id) {
+						accounts = append(accounts, account)
+					}
+				}
+			}
+		}
+	}
+	if len(accounts) == 0 {
+		return errors.New("no accounts selected")
+	}
+
+	options := services.MultiAccountOptions{Concurrency: *concurrency}
+	report, err := services.RunInAccounts(ctx, accounts, *service, *operation, *region, params, options, env.servicesOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(env.stdout, formatAccountReport(report))
+	}
+
+	if !report.Success() {
+		return fmt.Errorf("%s failed in %d of %d account(s)", *operation, report.Failed, len(report.Results))
+	}
+	return nil
+}
+
+// formatAccountReport renders a multi-account report as text
+func formatAccountReport(report *services.MultiAccountReport) string {
+	var s strings.Builder
+	for _, result := range report.Results {
+		status := "ok"
+		if !result.Result.Success {
+			status = "failed"
+		}
+		account := result.Account
+		if account == "" {
+			account = "unknown"
+		}
+		s.WriteString(fmt.Sprintf("%-12s %-20s %-6s %s\n", account, result.Name, status, result.Result.Message))
+	}
+	s.WriteString(fmt.Sprintf("\n%d account(s) succeeded, %d failed\n", report.Succeeded, report.Failed))
+	return s.String()
+}
+
+// runBulk implements the bulk command
+func runBulk(ctx context.Context, env *environment, args []string) error {
+	flags := env.newFlagSet("bulk")
+	file := flags.String("f", "", "YAML or JSON file with a list of requests, each with service, operation, region and params")
+	workers := flags.Int("workers", services.DefaultBulkWorkers, "Number of requests run at once")
+	rates := flags.String("rate", "", "Maximum requests started per second for each service, as service=rate,...")
+	asJSON := flags.Bool("json", false, "Print the results as JSON")
+	progress := flags.Bool("progress", false, "Print the progress of every create and delete as it happens")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("a request file is required, pass it with -f")
+	}
+	if err := env.progressFlag(progress, *asJSON); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read requests: %w", err)
+	}
+	var requests []services.BulkRequest
+	if err := yaml.Unmarshal(data, &requests); err != nil {
+		return fmt.Errorf("failed to parse requests %s: %w", *file, err)
+	}
+	for i := range requests {
+		if requests[i].Region == "" {
+			requests[i].Region = env.cfg.AWS.Region
+		}
+	}
+
+	options := services.BulkOptions{Workers: *workers, RateLimits: map[string]float64{}}
+	if *rates != "" {
+		limits, err := services.ParseTags(*rates)
+		if err != nil {
+			return fmt.Errorf("invalid -rate: %w", err)
+		}
+		for service, value := range limits {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate <= 0 {
+				return fmt.Errorf("invalid -rate for %s: %q is not a positive number", service, value)
+			}
+			options.RateLimits[service] = rate
+		}
+	}
+
+	results := services.RunBulk(ctx, requests, options, env.servicesOptions()...)
+
+	failed := 0
+	for _, result := range results {
+		if !result.Result.Success {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			status := "ok"
+			if !result.Result.Success {
+				status = "failed"
+			}
+			fmt.Fprintf(env.stdout, "%3d %s %s (%s) %-6s %s\n", result.Index+1, result.Request.Service, result.Request.Operation,
+				result.Request.Region, status, result.Result.Message)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d request(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+// runContext implements the context command: list, show [name] and use name
+func runContext(ctx context.Context, env *environment, args []string) error {
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "list":
+		return env.listContexts()
+	case "show":
+		name := env.cfg.Context
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			return errors.New("no context is in use, pass its name or select one with 'context use'")
+		}
+		return env.showContext(name)
+	case "use":
+		if len(args) != 1 {
+			return errors.New("usage: context use <name>")
+		}
+		return env.useContext(args[0])
+	}
+	return fmt.Errorf("unknown context action %q, expected list, show or use", action)
+}
+
+// listContexts prints the contexts of the config file, marking the one in use
+func (e *environment) listContexts() error {
+	names := e.cfg.ContextNames()
+	if len(names) == 0 {
+		fmt.Fprintln(e.stdout, "No contexts are defined in the config file.")
+		return nil
+	}
+
+	fmt.Fprintf(e.stdout, "  %-15s %-15s %s\n", "NAME", "PROFILE", "REGION")
+	for _, name := range names {
+		marker := " "
+		if name == e.cfg.Context {
+			marker = "*"
+		}
+		c := e.cfg.Contexts[name]
+		fmt.Fprintf(e.stdout, "%s %-15s %-15s %s\n", marker, name, orDash(c.Profile), orDash(c.Region))
+	}
+	return nil
+}
+
+// showContext prints the settings in effect with a context
+func (e *environment) showContext(name string) error {
+	cfg, err := config.Resolve(e.cfg.Path, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Context:  %s\n", cfg.Context)
+	fmt.Fprintf(e.stdout, "Config:   %s\n", cfg.Path)
+	fmt.Fprintf(e.stdout, "Profile:  %s\n", orDash(cfg.AWS.Profile))
+	if cfg.AWS.RoleARN != "" {
+		fmt.Fprintf(e.stdout, "Role:     %s\n", cfg.AWS.RoleARN)
+	}
+	fmt.Fprintf(e.stdout, "Region:   %s\n", cfg.AWS.Region)
+	fmt.Fprintf(e.stdout, "Tags:     %s\n", orDash(services.FormatTags(cfg.Defaults.Tags)))
+	s3 := cfg.Defaults.S3
+	fmt.Fprintf(e.stdout, "S3:       region %s, versioning %t, encryption %t\n", orDash(s3.Region), s3.Versioning, s3.Encryption)
+	ec2 := cfg.Defaults.EC2
+	fmt.Fprintf(e.stdout, "EC2:      region %s, instance type %s, key %s, count %d\n", orDash(ec2.Region), orDash(ec2.InstanceType), orDash(ec2.KeyName), ec2.Count)
+	return nil
+}
+
+// useContext makes a context the current_context of the config file
+func (e *environment) useContext(name string) error {
+	if e.cfg.Path == "" {
+		return errors.New("no config file was found, create one or pass it with -config")
+	}
+	// Resolving checks that the context exists and yields a valid configuration
+	if _, err := config.Resolve(e.cfg.Path, name); err != nil {
+		return err
+	}
+	if err := config.SetCurrentContext(e.cfg.Path, name); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Switched to context %q in %s\n", name, e.cfg.Path)
+	return nil
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
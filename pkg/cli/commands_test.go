@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/config"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// newTestEnvironment returns an environment backed by fakes and a temporary inventory
+func newTestEnvironment(t *testing.T, opts ...services.Option) (*environment, *bytes.Buffer) {
+	t.Helper()
+
+	store, err := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	return &environment{
+		cfg:            config.Default(),
+		provider:       awsconfig.NewProvider(),
+		inventory:      store,
+		stdout:         stdout,
+		serviceOptions: opts,
+	}, stdout
+}
+
+func TestDriftCommand(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	service, _ := services.NewS3Service("us-east-1", env.servicesOptions()...)
+	service.CreateResource(ctx, map[string]interface{}{"bucket_name": "drift-bucket", "tags": "owner=alice"})
+
+	if err := env.execute(ctx, []string{"drift"}); err != nil {
+		t.Fatalf("Expected no drift, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "drift-bucket (us-east-1): in sync") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+
+	backend.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String("drift-bucket"),
+		Tagging: &types.Tagging{TagSet: []types.Tag{{Key: aws.String("owner"), Value: aws.String("bob")}}},
+	})
+
+	stdout.Reset()
+	err := env.execute(ctx, []string{"drift", "-service", "s3"})
+	if !errors.Is(err, ErrDriftDetected) {
+		t.Fatalf("Expected ErrDriftDetected, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "tags.owner: desired alice, actual bob") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	env, stdout := newTestEnvironment(t)
+
+	if err := env.execute(context.Background(), []string{"bogus"}); err == nil {
+		t.Error("Expected error for unknown command")
+	}
+	if !strings.Contains(stdout.String(), "drift") {
+		t.Errorf("Expected usage listing commands, got:\n%s", stdout.String())
+	}
+}
+
+func TestPlanAndApplyCommands(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte("name: staging\ns3:\n  - name: logs\n    bucket_name: staging-logs\n"), 0o600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := env.execute(ctx, []string{"plan", "-f", path}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "+ create s3.logs (staging-logs) in us-east-1") {
+		t.Errorf("Unexpected plan:\n%s", stdout.String())
+	}
+
+	// Anything but yes cancels
+	env.stdin = strings.NewReader("no\n")
+	if err := env.execute(ctx, []string{"apply", "-f", path}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := backend.Bucket("staging-logs"); ok {
+		t.Fatal("Expected cancelled apply to create nothing")
+	}
+
+	env.stdin = strings.NewReader("yes\n")
+	if err := env.execute(ctx, []string{"apply", "-f", path}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := backend.Bucket("staging-logs"); !ok {
+		t.Fatal("Expected staging-logs to be created")
+	}
+
+	stdout.Reset()
+	if err := env.execute(ctx, []string{"apply", "-f", path, "-auto-approve"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "No changes") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestDestroyCommand(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	service, _ := services.NewS3Service("us-east-1", env.servicesOptions()...)
+	service.CreateResource(ctx, map[string]interface{}{"bucket_name": "old-bucket", "tags": "team=data"})
+
+	if err := env.execute(ctx, []string{"destroy"}); err == nil {
+		t.Error("Expected an error without a selector")
+	}
+
+	if err := env.execute(ctx, []string{"destroy", "-tag", "team=data", "-dry-run"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Would delete bucket old-bucket") {
+		t.Errorf("Unexpected preview:\n%s", stdout.String())
+	}
+
+	env.stdin = strings.NewReader("yes\n")
+	if err := env.execute(ctx, []string{"destroy", "-id", "old-bucket"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := backend.Bucket("old-bucket"); ok {
+		t.Error("Expected old-bucket to be destroyed")
+	}
+}
+
+func TestImportCommand(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	existing, _ := services.NewS3Service("us-east-1", services.WithS3Client(backend))
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "legacy-bucket"})
+
+	if err := env.execute(ctx, []string{"import", "-name", "legacy-*"}); err == nil {
+		t.Error("Expected an error without a service")
+	}
+
+	if err := env.execute(ctx, []string{"import", "-service", "s3", "-name", "legacy-*"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "s3 bucket legacy-bucket (us-east-1): Imported bucket legacy-bucket") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+	if record, err := env.inventory.Get("s3", "legacy-bucket"); err != nil || !record.Imported {
+		t.Errorf("Expected an imported record, got %+v, %v", record, err)
+	}
+}
+
+func TestListCommandAcrossRegions(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	err := env.execute(ctx, []string{"create", "-service", "s3", "-regions", "us-east-1,eu-west-1", "-p", "bucket_name=app-{region}"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := backend.Bucket("app-eu-west-1"); !ok {
+		t.Error("Expected a bucket to be created in eu-west-1")
+	}
+
+	stdout.Reset()
+	if err := env.execute(ctx, []string{"list", "-service", "s3", "-regions", "eu-west-1,us-east-1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "2 region(s) succeeded, 0 failed") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestCreateCommandProgress(t *testing.T) {
+	ctx := context.Background()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(fake.NewS3()))
+
+	err := env.execute(ctx, []string{"create", "-service", "s3", "-regions", "us-east-1", "-p", "bucket_name=progress-bucket", "-progress"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range []string{
+		"[s3 create us-east-1] validating: Validating parameters",
+		"[s3 create progress-bucket us-east-1] calling_api: Creating bucket progress-bucket in us-east-1",
+		"[s3 create progress-bucket us-east-1] done: ",
+	} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected %q in output:\n%s", line, stdout.String())
+		}
+	}
+
+	if err := env.execute(ctx, []string{"create", "-service", "s3", "-progress", "-json"}); err == nil {
+		t.Error("Expected an error for -progress with -json")
+	}
+}
+
+func TestConfiguredHooks(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	hooks, err := hookOptions([]config.HookConfig{{
+		Point:   "pre_create",
+		Service: "s3",
+		Command: []string{"sh", "-c", `grep -q data-classification || { echo "classification missing" >&2; exit 1; }`},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	env.hooks = hooks
+
+	if err := env.execute(ctx, []string{"create", "-service", "s3", "-p", "bucket_name=raw-data"}); err == nil {
+		t.Error("Expected the hook to reject the bucket")
+	}
+	if !strings.Contains(stdout.String(), "Rejected by the pre_create hook: classification missing") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+
+	err = env.execute(ctx, []string{"create", "-service", "s3", "-p", "bucket_name=raw-data", "-p", "tags=data-classification=internal"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := backend.Bucket("raw-data"); !ok {
+		t.Error("Expected the classified bucket to be created")
+	}
+
+	if _, err := hookOptions([]config.HookConfig{{Point: "post_delete", Command: []string{"true"}}}); err == nil {
+		t.Error("Expected an error for an unknown hook point")
+	}
+}
+
+func TestRunCommandAcrossAccounts(t *testing.T) {
+	ctx := context.Background()
+	orgs := fake.NewOrganizations()
+	orgs.AddAccount("222222222222", "dev", orgtypes.AccountStateActive)
+	orgs.AddAccount("333333333333", "prod", orgtypes.AccountStateActive)
+	env, stdout := newTestEnvironment(t, services.WithS3Client(fake.NewS3()), services.WithOrganizationsClient(orgs))
+
+	if err := env.execute(ctx, []string{"run", "-service", "s3"}); err == nil {
+		t.Error("Expected an error without accounts")
+	}
+
+	err := env.execute(ctx, []string{"run", "-service", "s3", "-org-role", "Admin", "-accounts", "333333333333"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "333333333333 prod") || strings.Contains(stdout.String(), "222222222222") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+}
+
+func TestBulkCommand(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	env, stdout := newTestEnvironment(t, services.WithS3Client(backend))
+
+	file := filepath.Join(t.TempDir(), "requests.yaml")
+	requests := `
+- service: s3
+  operation: create
+  params: {bucket_name: bulk-one, versioning: true}
+- service: s3
+  operation: create
+  region: eu-west-1
+  params: {bucket_name: bulk-two}
+`
+	if err := os.WriteFile(file, []byte(requests), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.execute(ctx, []string{"bulk", "-f", file, "-workers", "2", "-rate", "s3=100"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if bucket, ok := backend.Bucket("bulk-two"); !ok || bucket.Region != "eu-west-1" {
+		t.Errorf("Expected bulk-two in eu-west-1, got %+v", bucket)
+	}
+	if !strings.Contains(stdout.String(), "1 s3 create (us-east-1) ok") {
+		t.Errorf("Unexpected output:\n%s", stdout.String())
+	}
+
+	if err := env.execute(ctx, []string{"bulk", "-f", file, "-rate", "s3=fast"}); err == nil {
+		t.Error("Expected an error for an invalid rate")
+	}
+}
+
+func TestInteractiveLoggerUsesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aws-resources.log")
+	logger, closer, err := newLogger(config.LoggingConfig{Level: "info", Output: "stdout", FilePath: path}, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	logger.Info("Interface started")
+	closer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "Interface started") {
+		t.Errorf("Expected the interface to log to %s, got %q (%v)", path, data, err)
+	}
+
+	if _, _, err := newLogger(config.LoggingConfig{Output: "stdout"}, true); err != nil {
+		t.Errorf("Expected no error without a log file, got %v", err)
+	}
+}
+
+func TestCreateCommandUsesConfigDefaults(t *testing.T) {
+	backend := fake.NewEC2("eu-west-1")
+	env, stdout := newTestEnvironment(t, services.WithEC2Client(backend))
+	env.cfg.Defaults.Tags = map[string]string{"owner": "platform"}
+	env.cfg.Defaults.EC2 = config.EC2Defaults{Region: "eu-west-1", InstanceType: "t3.small", KeyName: "ops", Count: 2}
+
+	err := env.execute(context.Background(), []string{"create", "-service", "ec2", "-p", "image_id=ami-12345678", "-p", "count=1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "eu-west-1") {
+		t.Errorf("Expected the EC2 default region, got:\n%s", stdout.String())
+	}
+
+	instances := backend.Instances()
+	if len(instances) != 1 {
+		t.Fatalf("Expected the count parameter to override the default, got %d instances", len(instances))
+	}
+	if instances[0].InstanceType != "t3.small" || aws.ToString(instances[0].KeyName) != "ops" {
+		t.Errorf("Expected t3.small with key ops, got %s with %s", instances[0].InstanceType, aws.ToString(instances[0].KeyName))
+	}
+	tagged := false
+	for _, tag := range instances[0].Tags {
+		tagged = tagged || aws.ToString(tag.Key) == "owner" && aws.ToString(tag.Value) == "platform"
+	}
+	if !tagged {
+		t.Errorf("Expected the default tags, got %v", instances[0].Tags)
+	}
+}
+
+func TestContextCommand(t *testing.T) {
+	t.Setenv(config.ContextEnvVar, "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "aws:\n  region: us-east-1\ncontexts:\n  dev:\n    profile: dev\n  prod:\n    profile: shared\n    region: sa-east-1\ncurrent_context: dev\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := config.Resolve(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	env, stdout := newTestEnvironment(t)
+	env.cfg = cfg
+	ctx := context.Background()
+
+	if err := env.execute(ctx, []string{"context", "list"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "* dev") || !strings.Contains(stdout.String(), "  prod            shared          sa-east-1") {
+		t.Errorf("Expected dev marked in use and prod listed, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := env.execute(ctx, []string{"context", "show", "prod"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Profile:  shared") || !strings.Contains(stdout.String(), "Region:   sa-east-1") {
+		t.Errorf("Expected the settings of prod, got:\n%s", stdout.String())
+	}
+
+	if err := env.execute(ctx, []string{"context", "use", "prod"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg, _ := config.Resolve(path, ""); cfg.Context != "prod" {
+		t.Errorf("Expected prod to be the current context, got %q", cfg.Context)
+	}
+	if err := env.execute(ctx, []string{"context", "use", "staging"}); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+}
+
+func TestContextRegionAppliesToCreates(t *testing.T) {
+	t.Setenv(config.ContextEnvVar, "")
+	t.Setenv("AWS_RESOURCES_REGION", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "aws:\n  region: us-east-1\ndefaults:\n  s3:\n    region: us-east-1\n  ec2:\n    region: us-east-1\ncontexts:\n  prod:\n    region: sa-east-1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cfg, err := config.Resolve(path, "prod")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s3Backend, ec2Backend := fake.NewS3(), fake.NewEC2("sa-east-1")
+	env, stdout := newTestEnvironment(t, services.WithS3Client(s3Backend), services.WithEC2Client(ec2Backend))
+	env.cfg = cfg
+	ctx := context.Background()
+
+	if err := env.execute(ctx, []string{"create", "-service", "s3", "-p", "bucket_name=prod-logs"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if bucket, ok := s3Backend.Bucket("prod-logs"); !ok || bucket.Region != "sa-east-1" {
+		t.Errorf("Expected prod-logs in sa-east-1, got %+v", bucket)
+	}
+
+	stdout.Reset()
+	if err := env.execute(ctx, []string{"create", "-service", "ec2", "-p", "image_id=ami-12345678", "-p", "key_name=ops"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(stdout.String(), "sa-east-1") || strings.Contains(stdout.String(), "us-east-1") {
+		t.Errorf("Expected the instance in sa-east-1, got:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file read from the working directory
+// when no other file is found
+const DefaultPath = "configs/aws-resources.yaml"
+
+// Config mirrors configs/aws-resources.yaml
+type Config struct {
+	// Path is the file the configuration was read from, empty for the defaults
+	Path string `yaml:"-"`
+
+	AWS      AWSConfig      `yaml:"aws"`
+	UI       UIConfig       `yaml:"ui"`
+	Defaults DefaultsConfig `yaml:"defaults"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	// Telemetry selects where traces and metrics are exported
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+	// Hooks are external commands run around resource creation
+	Hooks []HookConfig `yaml:"hooks"`
+	// Contexts are named sets of account, region and default settings
+	Contexts map[string]ContextConfig `yaml:"contexts"`
+	// CurrentContext is the context applied when no other is selected
+	CurrentContext string `yaml:"current_context"`
+	// Context is the name of the applied context, empty when there is none
+	Context string `yaml:"-"`
+}
+
+// AWSConfig holds the AWS account settings
+type AWSConfig struct {
+	Region  string `yaml:"region"`
+	Profile string `yaml:"profile"`
+	// RoleARN is assumed with the credentials of the profile
+	RoleARN    string `yaml:"role_arn"`
+	ExternalID string `yaml:"external_id"`
+}
+
+// UIConfig holds the TUI settings
+type UIConfig struct {
+	Theme   ThemeConfig   `yaml:"theme"`
+	Timeout TimeoutConfig `yaml:"timeout"`
+}
+
+// ThemeConfig holds the TUI colors
+type ThemeConfig struct {
+	PrimaryColor string `yaml:"primary_color"`
+	AccentColor  string `yaml:"accent_color"`
+	ErrorColor   string `yaml:"error_color"`
+}
+
+// TimeoutConfig holds timeouts in seconds
+type TimeoutConfig struct {
+	APICalls  int `yaml:"api_calls"`
+	UserInput int `yaml:"user_input"`
+}
+
+// DefaultsConfig holds the default settings of created resources
+type DefaultsConfig struct {
+	// Tags are applied to every created resource
+	Tags map[string]string `yaml:"tags"`
+	S3   S3Defaults        `yaml:"s3"`
+	EC2  EC2Defaults       `yaml:"ec2"`
+}
+
+// S3Defaults holds the default settings of created buckets
+type S3Defaults struct {
+	// Region overrides aws.region for buckets when set
+	Region     string `yaml:"region"`
+	Versioning bool   `yaml:"versioning"`
+	Encryption bool   `yaml:"encryption"`
+}
+
+// EC2Defaults holds the default settings of launched instances
+type EC2Defaults struct {
+	// Region overrides aws.region for instances when set
+	Region         string   `yaml:"region"`
+	InstanceType   string   `yaml:"instance_type"`
+	KeyName        string   `yaml:"key_name"`
+	SecurityGroups []string `yaml:"security_groups"`
+	// Count is the number of instances launched at once
+	Count int `yaml:"count"`
+}
+
+// LoggingConfig holds the logging settings
+type LoggingConfig struct {
+	Level string `yaml:"level"`
+	// Output is stdout, stderr, file or none
+	Output   string `yaml:"output"`
+	FilePath string `yaml:"file_path"`
+	// MaxSizeMB is the size at which the log file is rotated
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxBackups is the number of rotated log files kept
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// TelemetryConfig holds the OpenTelemetry export settings
+type TelemetryConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP endpoint, e.g. localhost:4318
+	Endpoint string `yaml:"endpoint"`
+	// Insecure sends OTLP over plain HTTP, e.g. to a local collector
+	Insecure bool `yaml:"insecure"`
+}
+
+// HookConfig runs an external command at a point of every create operation
+type HookConfig struct {
+	// Point is pre_validate, pre_create, post_create or on_error
+	Point string `yaml:"point"`
+	// Service limits the hook to s3 or ec2, every service when empty
+	Service string `yaml:"service"`
+	// Command is the program and its arguments, run without a shell
+	Command []string `yaml:"command"`
+	// Timeout is in seconds, 30 when zero
+	Timeout int `yaml:"timeout"`
+}
+
+// Default returns the configuration used when no file is present
+func Default() *Config {
+	return &Config{
+		AWS: AWSConfig{Region: "us-east-1"},
+		UI: UIConfig{
+			Theme: ThemeConfig{
+				PrimaryColor: "#FAFAFA",
+				AccentColor:  "#04B575",
+				ErrorColor:   "#FF0000",
+			},
+			Timeout: TimeoutConfig{APICalls: 30, UserInput: 300},
+		},
+		Defaults: DefaultsConfig{
+			S3:  S3Defaults{},
+			EC2: EC2Defaults{InstanceType: "t2.micro", Count: 1},
+		},
+		Logging:   LoggingConfig{Level: "info", Output: "stderr"},
+		Telemetry: TelemetryConfig{Exporter: "none"},
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults.
+// A missing file is not an error and yields the defaults. Unknown keys are
+// rejected, so a misspelled setting is not silently ignored.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// Resolve discovers the configuration file, see Discover, loads it, applies
+// a context, see SelectContext, then the environment variable overrides,
+// see ApplyEnv, and validates the result
+func Resolve(path, context string) (*Config, error) {
+	found, err := Discover(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if found != "" {
+		if cfg, err = Load(found); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.SelectContext(context); err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		if found == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid config %s: %w", found, err)
+	}
+	return cfg, nil
+}
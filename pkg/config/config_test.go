@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadExampleConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join("..", "..", DefaultPath))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.AWS.Region != "us-east-1" {
+		t.Errorf("Expected region us-east-1, got %s", cfg.AWS.Region)
+	}
+	if cfg.Defaults.Tags["created-by"] != "aws-resources" {
+		t.Errorf("Expected created-by default tag, got %v", cfg.Defaults.Tags)
+	}
+	if cfg.Defaults.EC2.InstanceType != "t2.micro" {
+		t.Errorf("Expected instance type t2.micro, got %s", cfg.Defaults.EC2.InstanceType)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.UI.Timeout.APICalls != 30 {
+		t.Errorf("Expected default api_calls timeout, got %d", cfg.UI.Timeout.APICalls)
+	}
+}
+
+func writeConfig(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return path
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv(EnvVar, "")
+
+	if path, err := Discover(""); err != nil || path != "" {
+		t.Errorf("Expected no file, got %q (%v)", path, err)
+	}
+
+	writeConfig(t, DefaultPath, "aws:\n  region: eu-west-1\n")
+	if path, _ := Discover(""); path != DefaultPath {
+		t.Errorf("Expected %s, got %q", DefaultPath, path)
+	}
+
+	user := writeConfig(t, filepath.Join(dir, "xdg", "aws-resources", "config.yaml"), "")
+	if path, _ := Discover(""); path != user {
+		t.Errorf("Expected the XDG file to win over the working directory, got %q", path)
+	}
+
+	fromEnv := writeConfig(t, filepath.Join(dir, "env.yaml"), "")
+	t.Setenv(EnvVar, fromEnv)
+	if path, _ := Discover(""); path != fromEnv {
+		t.Errorf("Expected %s, got %q", fromEnv, path)
+	}
+
+	flag := writeConfig(t, filepath.Join(dir, "flag.yaml"), "")
+	if path, _ := Discover(flag); path != flag {
+		t.Errorf("Expected the flag to win, got %q", path)
+	}
+	if _, err := Discover(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file given by the flag")
+	}
+}
+
+func TestResolveAppliesEnvOverrides(t *testing.T) {
+	path := writeConfig(t, filepath.Join(t.TempDir(), "config.yaml"), "aws:\n  region: eu-west-1\n  profile: dev\ndefaults:\n  s3:\n    region: eu-west-1\n")
+	t.Setenv("AWS_RESOURCES_REGION", "sa-east-1")
+	t.Setenv("AWS_RESOURCES_API_TIMEOUT", "5")
+
+	cfg, err := Resolve(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.AWS.Region != "sa-east-1" || cfg.AWS.Profile != "dev" {
+		t.Errorf("Expected the region to be overridden and the profile kept, got %+v", cfg.AWS)
+	}
+	if cfg.Defaults.S3.Region != "" {
+		t.Errorf("Expected the S3 region of the file to give way to the environment, got %s", cfg.Defaults.S3.Region)
+	}
+	if cfg.UI.Timeout.APICalls != 5 || cfg.Path != path {
+		t.Errorf("Expected a 5s timeout read from %s, got %d from %s", path, cfg.UI.Timeout.APICalls, cfg.Path)
+	}
+
+	t.Setenv("AWS_RESOURCES_API_TIMEOUT", "soon")
+	if _, err := Resolve(path, ""); err == nil || !strings.Contains(err.Error(), "AWS_RESOURCES_API_TIMEOUT") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, filepath.Join(t.TempDir(), "config.yaml"), "aws:\n  regoin: eu-west-1\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "regoin") {
+		t.Errorf("Expected an error naming the unknown key, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Expected the defaults to be valid, got %v", err)
+	}
+
+	cfg := Default()
+	cfg.AWS.Region = "useast1"
+	cfg.UI.Theme.AccentColor = "green"
+	cfg.Defaults.EC2.Count = 0
+	cfg.Logging.Output = "file"
+	cfg.Telemetry.Exporter = "zipkin"
+
+	var validation *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validation) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	for _, key := range []string{"aws.region", "ui.theme.accent_color", "defaults.ec2.count", "logging.file_path", "telemetry.exporter"} {
+		found := false
+		for _, problem := range validation.Problems {
+			found = found || strings.HasPrefix(problem, key+": ")
+		}
+		if !found {
+			t.Errorf("Expected a problem with %s, got %v", key, validation.Problems)
+		}
+	}
+}
+
+const contextsConfig = `aws:
+  region: us-east-1
+defaults:
+  tags:
+    owner: platform
+contexts:
+  dev:
+    profile: dev
+  prod:
+    profile: shared
+    role_arn: arn:aws:iam::123456789012:role/Deployer
+    region: sa-east-1
+    tags:
+      environment: prod
+    ec2:
+      instance_type: t3.medium
+current_context: "dev"  # in use
+`
+
+func TestResolveSelectsContext(t *testing.T) {
+	path := writeConfig(t, filepath.Join(t.TempDir(), "config.yaml"), contextsConfig)
+	t.Setenv(ContextEnvVar, "")
+
+	cfg, err := Resolve(path, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Context != "dev" || cfg.AWS.Profile != "dev" || cfg.AWS.Region != "us-east-1" {
+		t.Errorf("Expected the current context dev, got %s with %+v", cfg.Context, cfg.AWS)
+	}
+
+	t.Setenv(ContextEnvVar, "prod")
+	cfg, _ = Resolve(path, "")
+	if cfg.Context != "prod" || cfg.AWS.RoleARN == "" || cfg.AWS.Region != "sa-east-1" {
+		t.Errorf("Expected the environment to select prod, got %s with %+v", cfg.Context, cfg.AWS)
+	}
+	if cfg.Defaults.Tags["owner"] != "platform" || cfg.Defaults.Tags["environment"] != "prod" {
+		t.Errorf("Expected the context tags merged over the defaults, got %v", cfg.Defaults.Tags)
+	}
+	if cfg.Defaults.EC2.InstanceType != "t3.medium" || cfg.Defaults.EC2.Count != 1 {
+		t.Errorf("Expected only the instance type to be overridden, got %+v", cfg.Defaults.EC2)
+	}
+
+	if _, err := Resolve(path, "staging"); err == nil || !strings.Contains(err.Error(), "dev, prod") {
+		t.Errorf("Expected an error listing the contexts, got %v", err)
+	}
+}
+
+func TestSetCurrentContext(t *testing.T) {
+	path := writeConfig(t, filepath.Join(t.TempDir(), "config.yaml"), contextsConfig)
+	if err := SetCurrentContext(path, "prod"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := strings.Replace(contextsConfig, `current_context: "dev"  # in use`, `current_context: "prod"  # in use`, 1)
+	if string(data) != want {
+		t.Errorf("Expected only current_context to change, got:\n%s", data)
+	}
+
+	// A file without the setting gets it appended
+	path = writeConfig(t, filepath.Join(t.TempDir(), "config.yaml"), "aws:\n  region: us-east-1")
+	if err := SetCurrentContext(path, "dev"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "aws:\n  region: us-east-1\ncurrent_context: \"dev\"\n" {
+		t.Errorf("Expected current_context to be appended, got:\n%s", data)
+	}
+}
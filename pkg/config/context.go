@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContextEnvVar names the environment variable selecting a context instead of current_context
+const ContextEnvVar = "AWS_RESOURCES_CONTEXT"
+
+// ContextConfig is a named set of account, region and default settings,
+// e.g. one per environment. The settings it leaves empty keep the values
+// of the rest of the file.
+type ContextConfig struct {
+	Profile    string `yaml:"profile"`
+	RoleARN    string `yaml:"role_arn"`
+	ExternalID string `yaml:"external_id"`
+	Region     string `yaml:"region"`
+	// Tags are merged over defaults.tags
+	Tags map[string]string `yaml:"tags"`
+	S3   S3Defaults        `yaml:"s3"`
+	EC2  EC2Defaults       `yaml:"ec2"`
+}
+
+// ContextNames returns the names of the contexts, sorted
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectContext applies the context name, or else the one named by
+// AWS_RESOURCES_CONTEXT, or else current_context. Without any, nothing is applied.
+func (c *Config) SelectContext(name string) error {
+	if name == "" {
+		name = os.Getenv(ContextEnvVar)
+	}
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+	return c.UseContext(name)
+}
+
+// UseContext applies the settings of the named context on top of the others
+func (c *Config) UseContext(name string) error {
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		if len(c.Contexts) == 0 {
+			return fmt.Errorf("unknown context %q, the config has no contexts", name)
+		}
+		return fmt.Errorf("unknown context %q, expected one of %s", name, strings.Join(c.ContextNames(), ", "))
+	}
+
+	if ctx.Profile != "" {
+		c.AWS.Profile = ctx.Profile
+	}
+	if ctx.RoleARN != "" {
+		c.AWS.RoleARN, c.AWS.ExternalID = ctx.RoleARN, ctx.ExternalID
+	}
+	if ctx.Region != "" {
+		// the context region wins over the per-service regions of the
+		// file, unless the context sets those too
+		c.AWS.Region = ctx.Region
+		c.Defaults.S3.Region, c.Defaults.EC2.Region = "", ""
+	}
+	if len(ctx.Tags) > 0 {
+		tags := make(map[string]string, len(c.Defaults.Tags)+len(ctx.Tags))
+		for k, v := range c.Defaults.Tags {
+			tags[k] = v
+		}
+		for k, v := range ctx.Tags {
+			tags[k] = v
+		}
+		c.Defaults.Tags = tags
+	}
+
+	s3 := &c.Defaults.S3
+	if ctx.S3.Region != "" {
+		s3.Region = ctx.S3.Region
+	}
+	s3.Versioning = s3.Versioning || ctx.S3.Versioning
+	s3.Encryption = s3.Encryption || ctx.S3.Encryption
+
+	ec2 := &c.Defaults.EC2
+	if ctx.EC2.Region != "" {
+		ec2.Region = ctx.EC2.Region
+	}
+	if ctx.EC2.InstanceType != "" {
+		ec2.InstanceType = ctx.EC2.InstanceType
+	}
+	if ctx.EC2.KeyName != "" {
+		ec2.KeyName = ctx.EC2.KeyName
+	}
+	if len(ctx.EC2.SecurityGroups) > 0 {
+		ec2.SecurityGroups = ctx.EC2.SecurityGroups
+	}
+	if ctx.EC2.Count > 0 {
+		ec2.Count = ctx.EC2.Count
+	}
+
+	c.Context = name
+	return nil
+}
+
+// currentContextLine matches the current_context setting, its value and its comment
+var currentContextLine = regexp.MustCompile(`(?m)^current_context:[ \t]*("[^"\n]*"|'[^'\n]*'|[^#\n]*?)([ \t]*(#.*)?)$`)
+
+// SetCurrentContext stores name as the current_context of the file at
+// path. Only that line changes, so the layout and comments of the file are kept.
+func SetCurrentContext(path, name string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	setting := "current_context: " + strconv.Quote(name)
+	if loc := currentContextLine.FindSubmatchIndex(data); loc != nil {
+		// Keep the comment after the value
+		data = append(append(append([]byte{}, data[:loc[0]]...), setting...), data[loc[4]:]...)
+	} else {
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, setting+"\n"...)
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces path through a temporary file, so an interrupted
+// write never leaves it truncated
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	return nil
+}
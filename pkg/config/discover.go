@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// EnvVar names the environment variable holding the path of the configuration file
+const EnvVar = "AWS_RESOURCES_CONFIG"
+
+// Discover returns the configuration file to read: path when it is set,
+// then the file named by AWS_RESOURCES_CONFIG, then the first existing of
+// $XDG_CONFIG_HOME/aws-resources/config.yaml and DefaultPath. Files named
+// by the flag or the environment variable must exist. It returns "" when
+// there is no file, so the defaults apply.
+func Discover(path string) (string, error) {
+	if path != "" {
+		return requireFile(path, "-config")
+	}
+	if path := os.Getenv(EnvVar); path != "" {
+		return requireFile(path, EnvVar)
+	}
+
+	candidates := []string{DefaultPath}
+	if user, err := UserPath(); err == nil {
+		candidates = append([]string{user}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// UserPath returns the configuration file under the XDG configuration directory
+func UserPath() (string, error) {
+	dir, err := userConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aws-resources", "config.yaml"), nil
+}
+
+// userConfigDir returns $XDG_CONFIG_HOME, defaulting to ~/.config as the
+// XDG base directory specification does on every platform
+func userConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+// requireFile checks that the file named by source exists
+func requireFile(path, source string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("config %s given by %s does not exist", path, source)
+		}
+		return "", fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// envOverride is an environment variable overriding one setting
+type envOverride struct {
+	name string
+	set  func(cfg *Config, value string) error
+}
+
+// envOverrides are applied by ApplyEnv, in this order
+var envOverrides = []envOverride{
+	{"AWS_RESOURCES_REGION", setRegion},
+	{"AWS_RESOURCES_PROFILE", setString(func(c *Config) *string { return &c.AWS.Profile })},
+	{"AWS_RESOURCES_API_TIMEOUT", setInt(func(c *Config) *int { return &c.UI.Timeout.APICalls })},
+	{"AWS_RESOURCES_S3_REGION", setString(func(c *Config) *string { return &c.Defaults.S3.Region })},
+	{"AWS_RESOURCES_EC2_REGION", setString(func(c *Config) *string { return &c.Defaults.EC2.Region })},
+	{"AWS_RESOURCES_EC2_INSTANCE_TYPE", setString(func(c *Config) *string { return &c.Defaults.EC2.InstanceType })},
+	{"AWS_RESOURCES_EC2_KEY_NAME", setString(func(c *Config) *string { return &c.Defaults.EC2.KeyName })},
+	{"AWS_RESOURCES_LOG_LEVEL", setString(func(c *Config) *string { return &c.Logging.Level })},
+	{"AWS_RESOURCES_LOG_OUTPUT", setString(func(c *Config) *string { return &c.Logging.Output })},
+	{"AWS_RESOURCES_LOG_FILE", setString(func(c *Config) *string { return &c.Logging.FilePath })},
+	{"AWS_RESOURCES_TELEMETRY_EXPORTER", setString(func(c *Config) *string { return &c.Telemetry.Exporter })},
+	{"AWS_RESOURCES_TELEMETRY_ENDPOINT", setString(func(c *Config) *string { return &c.Telemetry.Endpoint })},
+}
+
+// ApplyEnv overrides settings with the AWS_RESOURCES_* environment
+// variables found by lookup, e.g. os.LookupEnv. Empty variables are ignored.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, override := range envOverrides {
+		value, ok := lookup(override.name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := override.set(c, value); err != nil {
+			return fmt.Errorf("%s: %w", override.name, err)
+		}
+	}
+	return nil
+}
+
+// setRegion sets the AWS region and drops the per-service regions of the
+// file, which AWS_RESOURCES_S3_REGION and AWS_RESOURCES_EC2_REGION may set again
+func setRegion(c *Config, value string) error {
+	c.AWS.Region = value
+	c.Defaults.S3.Region, c.Defaults.EC2.Region = "", ""
+	return nil
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	regionPattern       = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+	colorPattern        = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|\d{1,3})$`)
+	instanceTypePattern = regexp.MustCompile(`^[a-z0-9-]+\.[a-z0-9]+$`)
+)
+
+// Allowed values of enumerated settings
+var (
+	logLevels          = []string{"debug", "info", "warn", "error"}
+	logOutputs         = []string{"stdout", "stderr", "file", "none"}
+	telemetryExporters = []string{"none", "stdout", "otlp"}
+)
+
+// ValidationError lists every invalid setting of a configuration
+type ValidationError struct {
+	// Problems are "key: reason" messages, keys as written in the file
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Validate checks every setting and reports all invalid ones at once
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.require("aws.region", c.AWS.Region)
+	v.match("aws.region", c.AWS.Region, regionPattern, "an AWS region like us-east-1")
+
+	v.match("ui.theme.primary_color", c.UI.Theme.PrimaryColor, colorPattern, "a color like #04B575 or an ANSI color number")
+	v.match("ui.theme.accent_color", c.UI.Theme.AccentColor, colorPattern, "a color like #04B575 or an ANSI color number")
+	v.match("ui.theme.error_color", c.UI.Theme.ErrorColor, colorPattern, "a color like #04B575 or an ANSI color number")
+	v.positive("ui.timeout.api_calls", c.UI.Timeout.APICalls)
+	v.positive("ui.timeout.user_input", c.UI.Timeout.UserInput)
+
+	v.validateTags("defaults.tags", c.Defaults.Tags)
+	v.match("defaults.s3.region", c.Defaults.S3.Region, regionPattern, "an AWS region like us-east-1")
+	v.match("defaults.ec2.region", c.Defaults.EC2.Region, regionPattern, "an AWS region like us-east-1")
+	v.match("defaults.ec2.instance_type", c.Defaults.EC2.InstanceType, instanceTypePattern, "an instance type like t2.micro")
+	v.positive("defaults.ec2.count", c.Defaults.EC2.Count)
+
+	if _, ok := c.Contexts[c.CurrentContext]; c.CurrentContext != "" && !ok {
+		v.problem("current_context", "%q is not one of the contexts", c.CurrentContext)
+	}
+	for _, name := range c.ContextNames() {
+		ctx, key := c.Contexts[name], "contexts."+name
+		v.match(key+".region", ctx.Region, regionPattern, "an AWS region like us-east-1")
+		v.validateTags(key+".tags", ctx.Tags)
+		v.match(key+".s3.region", ctx.S3.Region, regionPattern, "an AWS region like us-east-1")
+		v.match(key+".ec2.region", ctx.EC2.Region, regionPattern, "an AWS region like us-east-1")
+		v.match(key+".ec2.instance_type", ctx.EC2.InstanceType, instanceTypePattern, "an instance type like t2.micro")
+		v.notNegative(key+".ec2.count", ctx.EC2.Count)
+	}
+
+	v.oneOf("logging.level", strings.ToLower(c.Logging.Level), logLevels)
+	v.oneOf("logging.output", strings.ToLower(c.Logging.Output), logOutputs)
+	if strings.EqualFold(c.Logging.Output, "file") && c.Logging.FilePath == "" {
+		v.problem("logging.file_path", "is required by the file output")
+	}
+	v.notNegative("logging.max_size_mb", c.Logging.MaxSizeMB)
+	v.notNegative("logging.max_backups", c.Logging.MaxBackups)
+
+	v.oneOf("telemetry.exporter", c.Telemetry.Exporter, telemetryExporters)
+	if c.Telemetry.Exporter == "otlp" && c.Telemetry.Endpoint == "" {
+		v.problem("telemetry.endpoint", "is required by the otlp exporter")
+	}
+
+	// Hook points and commands are checked when the hooks are set up
+	for i, hook := range c.Hooks {
+		v.notNegative(fmt.Sprintf("hooks[%d].timeout", i), hook.Timeout)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects the problems found by Validate
+type validator struct {
+	problems []string
+}
+
+func (v *validator) problem(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) validateTags(key string, tags map[string]string) {
+	for tag := range tags {
+		if tag == "" || strings.HasPrefix(strings.ToLower(tag), "aws:") {
+			v.problem(key, "%q is not a valid tag key, keys must not be empty or start with aws:", tag)
+		}
+	}
+}
+
+func (v *validator) require(key, value string) {
+	if value == "" {
+		v.problem(key, "is required")
+	}
+}
+
+// match checks optional values against pattern
+func (v *validator) match(key, value string, pattern *regexp.Regexp, expected string) {
+	if value != "" && !pattern.MatchString(value) {
+		v.problem(key, "%q is not %s", value, expected)
+	}
+}
+
+// oneOf checks optional values against the allowed ones
+func (v *validator) oneOf(key, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.problem(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+func (v *validator) positive(key string, value int) {
+	if value <= 0 {
+		v.problem(key, "must be greater than zero, got %d", value)
+	}
+}
+
+func (v *validator) notNegative(key string, value int) {
+	if value < 0 {
+		v.problem(key, "must not be negative, got %d", value)
+	}
+}
@@ -0,0 +1,346 @@
+// Package inventory records the AWS resources created by this module in a
+// local JSON file so they can be listed and inspected later.
+package inventory
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("inventory record not found")
+
+// Record describes a resource created by this module. Session identifies
+// the run of the tool that created or imported it, and Imported is set on
+// resources that existed before and were imported.
+type Record struct {
+	Service   string                 `json:"service"`
+	Type      string                 `json:"type"`
+	ID        string                 `json:"id"`
+	ARN       string                 `json:"arn,omitempty"`
+	Region    string                 `json:"region"`
+	Account   string                 `json:"account,omitempty"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	Tags      map[string]string      `json:"tags,omitempty"`
+	Session   string                 `json:"session,omitempty"`
+	Imported  bool                   `json:"imported,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+	DeletedAt *time.Time             `json:"deleted_at,omitempty"`
+}
+
+// Key identifies a record in the store. The account and region are part
+// of it, so the same ID recorded in several accounts or regions, e.g. a
+// bucket name reused after a deletion, gives separate records.
+func (r Record) Key() string {
+	return r.Service + "/" + r.Account + "/" + r.Region + "/" + r.ID
+}
+
+// Deleted reports whether the resource was deleted
+func (r Record) Deleted() bool {
+	return r.DeletedAt != nil
+}
+
+// Filter selects records in List. Empty fields match every record.
+type Filter struct {
+	Service string
+	Region  string
+	Account string
+	Session string
+	// IDs only matches records of these resources
+	IDs []string
+	// Tags only matches records carrying every given tag with the same value
+	Tags           map[string]string
+	IncludeDeleted bool
+	// ExcludeImported leaves out resources imported rather than created by the tool
+	ExcludeImported bool
+}
+
+// Matches reports whether a record is selected by the filter
+func (f Filter) Matches(r Record) bool {
+	switch {
+	case f.Service != "" && f.Service != r.Service:
+		return false
+	case f.Region != "" && f.Region != r.Region:
+		return false
+	case f.Account != "" && f.Account != r.Account:
+		return false
+	case f.Session != "" && f.Session != r.Session:
+		return false
+	case !f.IncludeDeleted && r.Deleted():
+		return false
+	case f.ExcludeImported && r.Imported:
+		return false
+	}
+	if len(f.IDs) > 0 && !containsID(f.IDs, r.ID) {
+		return false
+	}
+	for key, value := range f.Tags {
+		if tag, ok := r.Tags[key]; !ok || tag != value {
+			return false
+		}
+	}
+	return true
+}
+
+// containsID reports whether ids holds id
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSessionID returns an identifier for a run of the tool, e.g.
+// 20240102T150405Z-1a2b3c, that sorts by start time
+func NewSessionID() string {
+	now := time.Now().UTC()
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the clock just in case
+		return fmt.Sprintf("%s-%06x", now.Format("20060102T150405Z"), now.Nanosecond()&0xffffff)
+	}
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// file is the on-disk format of the store
+type file struct {
+	Version   int      `json:"version"`
+	Resources []Record `json:"resources"`
+}
+
+const fileVersion = 1
+
+// Store is a JSON file holding inventory records. It is safe for concurrent
+// use within a process; every change rewrites the file atomically.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the inventory file under the user configuration directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config dir: %w", err)
+	}
+	return filepath.Join(dir, "aws-resources", "inventory.json"), nil
+}
+
+// Open opens the store at path, creating its directory if needed. The file
+// itself is created on the first write.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create inventory dir: %w", err)
+	}
+
+	s := &Store{path: path}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the location of the inventory file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Put inserts or replaces a record. CreatedAt, Session and Imported are
+// kept from an existing record with the same key, unless that record is
+// marked deleted and the resource was created again: a put never turns an
+// imported resource into one created by the tool. UpdatedAt is set to the
+// current time.
+func (s *Store) Put(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	record.UpdatedAt = now
+	if existing, ok := records[record.Key()]; ok && !existing.Deleted() {
+		record.CreatedAt = existing.CreatedAt
+		record.Imported = record.Imported || existing.Imported
+		if existing.Session != "" {
+			record.Session = existing.Session
+		}
+	} else if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+
+	records[record.Key()] = record
+	return s.save(records)
+}
+
+// Get returns the current record of a resource, see current
+func (s *Store) Get(service, id string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return Record{}, err
+	}
+
+	_, record, ok := current(records, service, id)
+	if !ok {
+		return Record{}, fmt.Errorf("%s %s: %w", service, id, ErrNotFound)
+	}
+	return record, nil
+}
+
+// Update applies fn to the current record of a resource and saves it
+func (s *Store) Update(service, id string, fn func(*Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	key, record, ok := current(records, service, id)
+	if !ok {
+		return fmt.Errorf("%s %s: %w", service, id, ErrNotFound)
+	}
+
+	fn(&record)
+	record.UpdatedAt = time.Now().UTC()
+	records[key] = record
+	return s.save(records)
+}
+
+// MarkDeleted records that a resource was deleted. The record is kept so
+// the history of what was provisioned is not lost.
+func (s *Store) MarkDeleted(service, id string) error {
+	return s.Update(service, id, func(r *Record) {
+		now := time.Now().UTC()
+		r.DeletedAt = &now
+	})
+}
+
+// List returns the records selected by filter, oldest first
+func (s *Store) List(filter Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Record
+	for _, record := range records {
+		if filter.Matches(record) {
+			list = append(list, record)
+		}
+	}
+	sortRecords(list)
+	return list, nil
+}
+
+// current finds the record of a resource by service and ID in any account
+// or region. A record not marked deleted wins, then the most recently
+// updated one.
+func current(records map[string]Record, service, id string) (string, Record, bool) {
+	var key string
+	var found Record
+	for k, record := range records {
+		if record.Service != service || record.ID != id {
+			continue
+		}
+		if key != "" {
+			if record.Deleted() != found.Deleted() {
+				if record.Deleted() {
+					continue
+				}
+			} else if !record.UpdatedAt.After(found.UpdatedAt) {
+				continue
+			}
+		}
+		key, found = k, record
+	}
+	return key, found, key != ""
+}
+
+// sortRecords orders records by creation time, then by key
+func sortRecords(list []Record) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].Key() < list[j].Key()
+	})
+}
+
+// load reads every record keyed by Record.Key. A missing file is an empty store.
+func (s *Store) load() (map[string]Record, error) {
+	records := make(map[string]Record)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", s.path, err)
+	}
+	if f.Version > fileVersion {
+		return nil, fmt.Errorf("inventory %s has version %d, newer than the supported version %d", s.path, f.Version, fileVersion)
+	}
+
+	for _, record := range f.Resources {
+		records[record.Key()] = record
+	}
+	return records, nil
+}
+
+// save writes records to a temporary file and renames it over the store
+func (s *Store) save(records map[string]Record) error {
+	f := file{Version: fileVersion, Resources: make([]Record, 0, len(records))}
+	for _, record := range records {
+		f.Resources = append(f.Resources, record)
+	}
+	sortRecords(f.Resources)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inventory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".inventory-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	return nil
+}
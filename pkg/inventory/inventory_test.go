@@ -0,0 +1,136 @@
+package inventory
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := Open(filepath.Join(t.TempDir(), "aws-resources", "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return store
+}
+
+func TestStorePutAndGet(t *testing.T) {
+	store := openTestStore(t)
+
+	record := Record{Service: "s3", Type: "bucket", ID: "my-bucket", Region: "us-east-1", Tags: map[string]string{"owner": "alice"}}
+	if err := store.Put(record); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	got, err := store.Get("s3", "my-bucket")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Tags["owner"] != "alice" || got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected record: %+v", got)
+	}
+
+	// Putting the same resource again keeps its creation time
+	record.Tags = map[string]string{"owner": "bob"}
+	store.Put(record)
+	updated, _ := store.Get("s3", "my-bucket")
+	if !updated.CreatedAt.Equal(got.CreatedAt) || updated.Tags["owner"] != "bob" {
+		t.Errorf("Expected replaced record with original creation time, got %+v", updated)
+	}
+
+	// Putting an imported resource again keeps it imported, in its session
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "legacy", Region: "us-east-1", Session: "s1", Imported: true})
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "legacy", Region: "us-east-1", Session: "s2"})
+	if legacy, _ := store.Get("s3", "legacy"); !legacy.Imported || legacy.Session != "s1" {
+		t.Errorf("Expected the record to stay imported in session s1, got %+v", legacy)
+	}
+
+	// A resource created again after its deletion is a new resource
+	store.MarkDeleted("s3", "my-bucket")
+	time.Sleep(time.Millisecond)
+	store.Put(record)
+	if recreated, _ := store.Get("s3", "my-bucket"); !recreated.CreatedAt.After(got.CreatedAt) {
+		t.Errorf("Expected a new creation time after the deletion, got %+v", recreated)
+	}
+
+	if _, err := store.Get("s3", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreKeepsIDsOfSeveralAccounts(t *testing.T) {
+	store := openTestStore(t)
+
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "shared-logs", Region: "us-east-1", Account: "222222222222"})
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "shared-logs", Region: "us-east-1", Account: "444444444444"})
+	if records, _ := store.List(Filter{}); len(records) != 2 {
+		t.Fatalf("Expected a record per account, got %+v", records)
+	}
+
+	// The live record is the current one of the resource
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "reused", Region: "eu-west-1", Account: "222222222222"})
+	store.MarkDeleted("s3", "reused")
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "reused", Region: "sa-east-1", Account: "222222222222"})
+	if got, _ := store.Get("s3", "reused"); got.Region != "sa-east-1" || got.Deleted() {
+		t.Errorf("Expected the live record in sa-east-1, got %+v", got)
+	}
+	if records, _ := store.List(Filter{IDs: []string{"reused"}, IncludeDeleted: true}); len(records) != 2 {
+		t.Errorf("Expected the deleted record to be kept, got %+v", records)
+	}
+}
+
+func TestStoreListFilters(t *testing.T) {
+	store := openTestStore(t)
+
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "logs", Region: "us-east-1", Tags: map[string]string{"team": "data"}})
+	store.Put(Record{Service: "ec2", Type: "instance", ID: "i-1", Region: "eu-west-1", Tags: map[string]string{"team": "web"}, Session: "s1"})
+	store.Put(Record{Service: "ec2", Type: "instance", ID: "i-2", Region: "eu-west-1"})
+	if err := store.MarkDeleted("ec2", "i-2"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all live", Filter{}, 2},
+		{"including deleted", Filter{IncludeDeleted: true}, 3},
+		{"by service", Filter{Service: "ec2"}, 1},
+		{"by region", Filter{Region: "us-east-1"}, 1},
+		{"by tag", Filter{Tags: map[string]string{"team": "web"}}, 1},
+		{"by session", Filter{Session: "s1"}, 1},
+		{"by id", Filter{IDs: []string{"logs", "i-2"}}, 1},
+		{"no match", Filter{Tags: map[string]string{"team": "ops"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(records) != tt.want {
+				t.Errorf("Expected %d records, got %d", tt.want, len(records))
+			}
+		})
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	store, _ := Open(path)
+	store.Put(Record{Service: "s3", Type: "bucket", ID: "my-bucket", Params: map[string]interface{}{"region": "us-east-1"}})
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	record, err := reopened.Get("s3", "my-bucket")
+	if err != nil || record.Params["region"] != "us-east-1" {
+		t.Errorf("Expected persisted record, got %+v (%v)", record, err)
+	}
+}
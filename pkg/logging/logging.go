@@ -0,0 +1,103 @@
+// Package logging builds the structured logger of this module from the
+// logging section of the config file.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Outputs
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+	OutputNone   = "none"
+)
+
+// Rotation defaults
+const (
+	DefaultMaxSize    = 10 << 20
+	DefaultMaxBackups = 3
+)
+
+// Options configures New
+type Options struct {
+	// Level is debug, info, warn or error, info when empty
+	Level string
+	// Output is stdout, stderr, file or none, stdout when empty
+	Output string
+	// FilePath is the log file of the file output
+	FilePath string
+	// MaxSize is the size in bytes at which the log file is rotated, DefaultMaxSize when zero
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept, DefaultMaxBackups when zero
+	MaxBackups int
+	// Stdout and Stderr replace the process streams when set, e.g. in tests
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New creates a logger writing to the configured output. Files get one
+// JSON object per line, terminals get text. Attributes holding
+// credentials or user data are redacted. The returned closer closes the
+// log file, if any.
+func New(options Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(options.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	handlerOptions := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	switch strings.ToLower(options.Output) {
+	case "", OutputStdout:
+		return slog.New(slog.NewTextHandler(orDefault(options.Stdout, os.Stdout), handlerOptions)), nopCloser{}, nil
+	case OutputStderr:
+		return slog.New(slog.NewTextHandler(orDefault(options.Stderr, os.Stderr), handlerOptions)), nopCloser{}, nil
+	case OutputFile:
+		if options.FilePath == "" {
+			return nil, nil, fmt.Errorf("the file output requires a file_path")
+		}
+		file, err := openRotatingFile(options.FilePath, options.MaxSize, options.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		return slog.New(slog.NewJSONHandler(file, handlerOptions)), file, nil
+	case OutputNone:
+		return Discard(), nopCloser{}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown log output %q, expected %s, %s, %s or %s", options.Output, OutputStdout, OutputStderr, OutputFile, OutputNone)
+}
+
+// ParseLevel parses debug, info, warn or error; empty means info
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// Discard returns a logger that logs nothing
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+func orDefault(w, fallback io.Writer) io.Writer {
+	if w == nil {
+		return fallback
+	}
+	return w
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
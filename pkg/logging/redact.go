@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in logs
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are parts of attribute and parameter names whose values are never logged
+var sensitiveKeys = []string{"secret", "password", "token", "credential", "authorization", "access_key", "private_key", "user_data"}
+
+// accessKeyPattern matches AWS access key IDs, long-term (AKIA) and temporary (ASIA)
+var accessKeyPattern = regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`)
+
+// Sensitive reports whether the value of a key must not be logged
+func Sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeys {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString replaces AWS access key IDs in s
+func RedactString(s string) string {
+	return accessKeyPattern.ReplaceAllString(s, Redacted)
+}
+
+// RedactParams returns a copy of operation parameters safe to log: values
+// of sensitive keys, e.g. user_data, are replaced, nested maps are
+// redacted too and access key IDs are removed from strings
+func RedactParams(params map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		switch v := value.(type) {
+		case string:
+			value = RedactString(v)
+		case map[string]interface{}:
+			value = RedactParams(v)
+		}
+		if Sensitive(key) {
+			value = Redacted
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// redactAttr is the ReplaceAttr function of every handler
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if Sensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if params, ok := a.Value.Any().(map[string]interface{}); ok {
+			return slog.Any(a.Key, RedactParams(params))
+		}
+	}
+	return a
+}
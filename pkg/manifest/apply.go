@@ -0,0 +1,261 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+)
+
+// ActionResult is the outcome of applying one action
+type ActionResult struct {
+	Action Action                   `json:"action"`
+	Result *services.ResourceResult `json:"result"`
+	// Skipped is set when the action did not run because an entry it depends on failed
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ApplyResult lists the outcome of every action of a plan, in plan order
+type ApplyResult struct {
+	Results []ActionResult `json:"results"`
+	// Rollback reports what was undone after a failure when applying with WithRollback
+	Rollback *services.RollbackReport `json:"rollback,omitempty"`
+}
+
+// ApplyOption configures Apply
+type ApplyOption func(*applyOptions)
+
+type applyOptions struct {
+	rollback bool
+}
+
+// WithRollback makes Apply stop starting new entries once an action fails
+// and undo what it created: launched instances are terminated and created
+// buckets deleted, newest first. Updates and deletes are not undone and
+// are listed as failed steps of the rollback report.
+func WithRollback() ApplyOption {
+	return func(o *applyOptions) {
+		o.rollback = true
+	}
+}
+
+// Failed returns the results of the actions that failed or were skipped
+func (r *ApplyResult) Failed() []ActionResult {
+	var failed []ActionResult
+	for _, result := range r.Results {
+		if !result.Result.Success {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Apply runs the actions of a plan. Each manifest entry starts once the
+// entries it depends on are done, so independent entries run in parallel,
+// and the entries depending on a failed entry are skipped. References are
+// replaced with the outputs of the applied entries. Resources of entries
+// removed from the manifest are deleted last.
+func (p *Planner) Apply(ctx context.Context, plan *Plan, opts ...ApplyOption) *ApplyResult {
+	var options applyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var tx *services.Transaction
+	if options.rollback {
+		tx = services.NewTransaction()
+		ctx = services.ContextWithTransaction(ctx, tx)
+	}
+
+	results := make([]ActionResult, len(plan.Actions))
+	g := plan.graph
+	if g == nil {
+		g = &graph{}
+	}
+
+	byNode := make(map[string][]int)
+	var removed []int
+	for i, action := range plan.Actions {
+		if node := nodeKey(action.Service, action.Name); contains(g.order, node) {
+			byNode[node] = append(byNode[node], i)
+		} else {
+			removed = append(removed, i)
+		}
+	}
+
+	known := newOutputs()
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	// aborted is set on the first failure when rolling back
+	aborted := false
+	done := make(map[string]chan struct{}, len(g.order))
+	for _, node := range g.order {
+		done[node] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range g.order {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			defer close(done[node])
+
+			var failedDeps []string
+			for _, dep := range g.deps[node] {
+				<-done[dep]
+				mu.Lock()
+				if failed[dep] {
+					failedDeps = append(failedDeps, dep)
+				}
+				mu.Unlock()
+			}
+
+			mu.Lock()
+			skip := aborted
+			mu.Unlock()
+
+			if len(failedDeps) > 0 || skip {
+				err := errors.New("skipped because another action failed and the apply is rolled back")
+				if len(failedDeps) > 0 {
+					sort.Strings(failedDeps)
+					err = fmt.Errorf("skipped because %s failed", strings.Join(failedDeps, ", "))
+				}
+				for _, i := range byNode[node] {
+					results[i] = ActionResult{Action: plan.Actions[i], Result: failure(err), Skipped: true}
+				}
+				mu.Lock()
+				failed[node] = true
+				mu.Unlock()
+				return
+			}
+
+			ok := true
+			for _, i := range byNode[node] {
+				results[i] = ActionResult{Action: plan.Actions[i], Result: p.applyAction(ctx, plan.Actions[i], known)}
+				ok = ok && results[i].Result.Success
+			}
+
+			if ok {
+				// Dependents cannot resolve their references without the outputs
+				values, err := p.nodeOutputs(ctx, plan.manifest, node)
+				if err == nil {
+					known.set(node, values)
+				}
+				ok = err == nil
+			}
+			if !ok {
+				mu.Lock()
+				failed[node] = true
+				aborted = options.rollback
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	for _, i := range removed {
+		if aborted {
+			err := errors.New("skipped because another action failed and the apply is rolled back")
+			results[i] = ActionResult{Action: plan.Actions[i], Result: failure(err), Skipped: true}
+			continue
+		}
+		results[i] = ActionResult{Action: plan.Actions[i], Result: p.applyAction(ctx, plan.Actions[i], known)}
+		if !results[i].Result.Success && options.rollback {
+			aborted = true
+		}
+	}
+
+	result := &ApplyResult{Results: results}
+	if aborted {
+		// Undo even when ctx was cancelled, which is often why the apply failed
+		result.Rollback = tx.Rollback(context.WithoutCancel(ctx))
+		for _, r := range results {
+			if r.Result.Success && r.Action.Type != ActionCreate {
+				result.Rollback.Failed = append(result.Rollback.Failed, notRolledBack(r.Action))
+			}
+		}
+	}
+	return result
+}
+
+// notRolledBack describes an applied update or delete that a rollback cannot undo
+func notRolledBack(action Action) services.RollbackStep {
+	step := services.RollbackStep{
+		Compensation: services.Compensation{
+			Service:     action.Service,
+			Type:        services.ResourceTypeBucket,
+			ID:          action.ID,
+			Region:      action.Region,
+			Description: fmt.Sprintf("%s %s", action.Type, nodeKey(action.Service, action.Name)),
+		},
+		Error: "updates are not rolled back",
+	}
+	if action.Service == services.EC2ServiceName {
+		step.Type = services.ResourceTypeInstance
+	}
+	if action.Type == ActionDelete {
+		step.Error = "deleted resources cannot be restored"
+	}
+	return step
+}
+
+// applyAction replaces the references in the parameters of an action and runs it
+func (p *Planner) applyAction(ctx context.Context, action Action, known *outputs) *services.ResourceResult {
+	params, err := known.expandParams(action.Params)
+	if err != nil {
+		return failure(err)
+	}
+	action.Params = params
+	return p.apply(ctx, action)
+}
+
+// nodeOutputs returns the outputs of an applied entry
+func (p *Planner) nodeOutputs(ctx context.Context, m *Manifest, node string) (map[string]string, error) {
+	if bucket, ok := m.bucket(node); ok {
+		return bucketOutputs(m, bucket), nil
+	}
+
+	group, _ := m.group(node)
+	instances, err := p.liveInstances(ctx, m.Name, group.Name)
+	if err != nil {
+		return nil, err
+	}
+	return groupOutputs(m.region(group.Region), instances), nil
+}
+
+// apply runs a single action through its service
+func (p *Planner) apply(ctx context.Context, action Action) *services.ResourceResult {
+	svc, err := p.service(action.Service, action.Region)
+	if err != nil {
+		return failure(err)
+	}
+
+	var result *services.ResourceResult
+	switch action.Type {
+	case ActionCreate:
+		result, err = svc.CreateResource(ctx, action.Params)
+	case ActionUpdate:
+		result, err = svc.UpdateResource(ctx, action.Params)
+	case ActionDelete:
+		result, err = svc.DeleteResource(ctx, action.Params)
+	default:
+		err = fmt.Errorf("unknown action type %q", action.Type)
+	}
+	if err != nil {
+		return failure(err)
+	}
+	return result
+}
+
+// failure wraps an error that is not an AWS failure into a failed result
+func failure(err error) *services.ResourceResult {
+	return &services.ResourceResult{
+		Success: false,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
@@ -0,0 +1,283 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+)
+
+// referencePattern matches references to the outputs of other entries, e.g. ${s3.logs.bucket_name}
+var referencePattern = regexp.MustCompile(`\$\{([a-z0-9]+)\.([A-Za-z0-9_-]+)\.([a-z_]+)\}`)
+
+// outputNames lists the outputs each service makes available to references
+var outputNames = map[string][]string{
+	services.S3ServiceName:  {"bucket_name", "region"},
+	services.EC2ServiceName: {"instance_ids", "private_ips", "region"},
+}
+
+// nodeKey identifies a manifest entry in the dependency graph, e.g. s3.logs
+func nodeKey(service, name string) string {
+	return service + "." + name
+}
+
+// reference is a parsed ${service.name.output} reference
+type reference struct {
+	raw    string
+	node   string
+	output string
+}
+
+// references returns the references found in values
+func references(values ...string) []reference {
+	var refs []reference
+	for _, value := range values {
+		for _, match := range referencePattern.FindAllStringSubmatch(value, -1) {
+			refs = append(refs, reference{raw: match[0], node: nodeKey(match[1], match[2]), output: match[3]})
+		}
+	}
+	return refs
+}
+
+// values returns the settings of a bucket that may contain references
+func (b Bucket) values() []string {
+	return tagValues(b.Tags)
+}
+
+// values returns the settings of an instance group that may contain references
+func (g InstanceGroup) values() []string {
+	values := []string{g.ImageID, g.InstanceType, g.KeyName, g.UserData, g.SubnetID}
+	values = append(values, g.SecurityGroupIDs...)
+	return append(values, tagValues(g.Tags)...)
+}
+
+// tagValues returns the values of tags, sorted by key
+func tagValues(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = tags[key]
+	}
+	return values
+}
+
+// graph is the dependency graph of the entries of a manifest
+type graph struct {
+	// order lists every entry after the entries it depends on
+	order []string
+	deps  map[string][]string
+}
+
+// graph builds the dependency graph from depends_on and references,
+// rejecting unknown entries, unknown outputs and cycles
+func (m *Manifest) graph() (*graph, error) {
+	var nodes []string
+	edges := make(map[string][]string)
+	declared := make(map[string]bool)
+	for _, bucket := range m.S3 {
+		declared[nodeKey(services.S3ServiceName, bucket.Name)] = true
+	}
+	for _, group := range m.EC2 {
+		declared[nodeKey(services.EC2ServiceName, group.Name)] = true
+	}
+
+	var errs []error
+	add := func(node string, dependsOn []string, values []string) {
+		nodes = append(nodes, node)
+		seen := make(map[string]bool)
+		depend := func(dep string) {
+			if !seen[dep] {
+				seen[dep] = true
+				edges[node] = append(edges[node], dep)
+			}
+		}
+
+		for _, dep := range dependsOn {
+			switch {
+			case !declared[dep]:
+				errs = append(errs, fmt.Errorf("%s: depends_on %q is not an entry of the manifest", node, dep))
+			case dep == node:
+				errs = append(errs, fmt.Errorf("%s: cannot depend on itself", node))
+			default:
+				depend(dep)
+			}
+		}
+
+		for _, ref := range references(values...) {
+			service := strings.SplitN(ref.node, ".", 2)[0]
+			switch {
+			case !declared[ref.node]:
+				errs = append(errs, fmt.Errorf("%s: %s refers to an unknown entry", node, ref.raw))
+			case !contains(outputNames[service], ref.output):
+				errs = append(errs, fmt.Errorf("%s: %s refers to an unknown output, %s entries provide %s",
+					node, ref.raw, service, strings.Join(outputNames[service], ", ")))
+			case ref.node == node:
+				errs = append(errs, fmt.Errorf("%s: %s refers to the entry itself", node, ref.raw))
+			default:
+				depend(ref.node)
+			}
+		}
+	}
+
+	for _, bucket := range m.S3 {
+		add(nodeKey(services.S3ServiceName, bucket.Name), bucket.DependsOn, bucket.values())
+	}
+	for _, group := range m.EC2 {
+		add(nodeKey(services.EC2ServiceName, group.Name), group.DependsOn, group.values())
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	order, err := sortNodes(nodes, edges)
+	if err != nil {
+		return nil, err
+	}
+	return &graph{order: order, deps: edges}, nil
+}
+
+// sortNodes orders nodes so every node comes after its dependencies,
+// keeping the manifest order otherwise
+func sortNodes(nodes []string, deps map[string][]string) ([]string, error) {
+	done := make(map[string]bool)
+	order := make([]string, 0, len(nodes))
+
+	for len(order) < len(nodes) {
+		progress := false
+		for _, node := range nodes {
+			if done[node] || !allDone(deps[node], done) {
+				continue
+			}
+			done[node] = true
+			order = append(order, node)
+			progress = true
+		}
+
+		if !progress {
+			var cycle []string
+			for _, node := range nodes {
+				if !done[node] {
+					cycle = append(cycle, node)
+				}
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+// allDone reports whether every node in nodes is done
+func allDone(nodes []string, done map[string]bool) bool {
+	for _, node := range nodes {
+		if !done[node] {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// outputs holds the outputs of applied entries. It is safe for concurrent use.
+type outputs struct {
+	mu     sync.Mutex
+	values map[string]map[string]string
+}
+
+func newOutputs() *outputs {
+	return &outputs{values: make(map[string]map[string]string)}
+}
+
+// set stores the outputs of a node
+func (o *outputs) set(node string, values map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[node] = values
+}
+
+// expand replaces the references in s whose outputs are known
+func (o *outputs) expand(s string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return referencePattern.ReplaceAllStringFunc(s, func(raw string) string {
+		ref := references(raw)[0]
+		if values, ok := o.values[ref.node]; ok {
+			if value, ok := values[ref.output]; ok {
+				return value
+			}
+		}
+		return raw
+	})
+}
+
+// expandTags returns tags with the known references in their values replaced
+func (o *outputs) expandTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(tags))
+	for key, value := range tags {
+		expanded[key] = o.expand(value)
+	}
+	return expanded
+}
+
+// expandParams returns a copy of action parameters with every reference
+// replaced, failing when an output is still unknown
+func (o *outputs) expandParams(params map[string]interface{}) (map[string]interface{}, error) {
+	expanded := make(map[string]interface{}, len(params))
+	var unresolved []string
+	check := func(value string) string {
+		value = o.expand(value)
+		for _, ref := range references(value) {
+			unresolved = append(unresolved, ref.raw)
+		}
+		return value
+	}
+
+	for name, value := range params {
+		switch v := value.(type) {
+		case string:
+			expanded[name] = check(v)
+		case []string:
+			list := make([]string, len(v))
+			for i, item := range v {
+				list[i] = check(item)
+			}
+			expanded[name] = list
+		case map[string]string:
+			tags := make(map[string]string, len(v))
+			for key, item := range v {
+				tags[key] = check(item)
+			}
+			expanded[name] = tags
+		default:
+			expanded[name] = value
+		}
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return nil, fmt.Errorf("unresolved references %s", strings.Join(unresolved, ", "))
+	}
+	return expanded, nil
+}
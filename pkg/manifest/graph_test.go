@@ -0,0 +1,218 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestGraphOrder(t *testing.T) {
+	m := mustParse(t, `
+name: staging
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    user_data: "aws s3 cp s3://${s3.config.bucket_name}/app.conf /etc/app.conf"
+    depends_on: [ec2.db]
+  - name: db
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+s3:
+  - name: config
+    bucket_name: staging-config
+`)
+
+	g, err := m.graph()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(g.order, " "); got != "s3.config ec2.db ec2.web" {
+		t.Errorf("Expected s3.config ec2.db ec2.web, got %s", got)
+	}
+	if got := strings.Join(g.deps["ec2.web"], " "); got != "ec2.db s3.config" {
+		t.Errorf("Expected ec2.web to depend on ec2.db and s3.config, got %s", got)
+	}
+}
+
+func TestGraphRejectsInvalidDependencies(t *testing.T) {
+	group := func(name, extra string) string {
+		return "  - name: " + name + "\n    image_id: ami-1\n    instance_type: t3.micro\n    key_name: k\n" + extra
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"unknown entry", "name: a\nec2:\n" + group("web", "    depends_on: [s3.missing]\n"), `depends_on "s3.missing" is not an entry`},
+		{"unknown reference", "name: a\nec2:\n" + group("web", "    user_data: ${s3.missing.bucket_name}\n"), "${s3.missing.bucket_name} refers to an unknown entry"},
+		{"unknown output", "name: a\nec2:\n" + group("web", "    user_data: ${ec2.db.arn}\n") + group("db", ""), "refers to an unknown output"},
+		{"self reference", "name: a\nec2:\n" + group("web", "    user_data: ${ec2.web.private_ips}\n"), "refers to the entry itself"},
+		{"cycle", "name: a\nec2:\n" + group("web", "    depends_on: [ec2.db]\n") + group("db", "    depends_on: [ec2.web]\n"), "dependency cycle between ec2.db, ec2.web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.manifest))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestApplyResolvesReferences(t *testing.T) {
+	planner, _, ec2Backend := newTestPlanner(t)
+	m := mustParse(t, `
+name: staging
+s3:
+  - name: config
+    bucket_name: staging-config
+ec2:
+  - name: db
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    tags:
+      config-bucket: ${s3.config.bucket_name}
+      db-hosts: ${ec2.db.private_ips}
+`)
+
+	plan := planAndApply(t, planner, m)
+	if deps := plan.Dependencies["ec2.web"]; len(deps) != 2 {
+		t.Errorf("Expected ec2.web to depend on two entries, got %v", deps)
+	}
+
+	var db, web string
+	for _, instance := range ec2Backend.Instances() {
+		tags := map[string]string{}
+		for _, tag := range instance.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		switch tags[TagName] {
+		case "db":
+			db = aws.ToString(instance.PrivateIpAddress)
+		case "web":
+			web = tags["db-hosts"]
+			if tags["config-bucket"] != "staging-config" {
+				t.Errorf("Expected config-bucket tag staging-config, got %q", tags["config-bucket"])
+			}
+		}
+	}
+	if db == "" || web != db {
+		t.Errorf("Expected db-hosts tag %q, got %q", db, web)
+	}
+
+	// Once every output is known the plan is empty
+	plan, err := planner.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("Expected no changes, got %+v", plan.Actions)
+	}
+}
+
+func TestApplySkipsDependentsOfFailures(t *testing.T) {
+	planner, s3Backend, ec2Backend := newTestPlanner(t)
+	s3Backend.InjectError("CreateBucket", fake.APIError("S3", "CreateBucket", "AccessDenied", "Access Denied", 403))
+
+	m := mustParse(t, `
+name: staging
+s3:
+  - name: config
+    bucket_name: staging-config
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    depends_on: [s3.config]
+  - name: worker
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+`)
+
+	plan, err := planner.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result := planner.Apply(context.Background(), plan)
+
+	failed := result.Failed()
+	if len(failed) != 2 {
+		t.Fatalf("Expected the bucket and web to fail, got %+v", failed)
+	}
+	for _, r := range result.Results {
+		switch r.Action.Name {
+		case "web":
+			if !r.Skipped || !strings.Contains(r.Result.Message, "s3.config failed") {
+				t.Errorf("Expected web to be skipped, got %+v", r.Result)
+			}
+		case "worker":
+			if !r.Result.Success {
+				t.Errorf("Expected independent worker to be launched, got %+v", r.Result)
+			}
+		}
+	}
+	if n := len(ec2Backend.Instances()); n != 1 {
+		t.Errorf("Expected only the worker instance, got %d instances", n)
+	}
+	if !errors.Is(failed[0].Result.Err, services.ErrAuth) {
+		t.Errorf("Expected the bucket to fail with ErrAuth, got %v", failed[0].Result.Err)
+	}
+}
+
+func TestApplyWithRollback(t *testing.T) {
+	planner, s3Backend, ec2Backend := newTestPlanner(t)
+	ec2Backend.InjectError("RunInstances", fake.APIError("EC2", "RunInstances", "InsufficientInstanceCapacity", "No capacity", 500))
+
+	m := mustParse(t, `
+name: staging
+s3:
+  - name: config
+    bucket_name: staging-config
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    depends_on: [s3.config]
+`)
+
+	plan, err := planner.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result := planner.Apply(context.Background(), plan, WithRollback())
+
+	if result.Rollback == nil {
+		t.Fatal("Expected a rollback report")
+	}
+	if len(result.Rollback.Undone) != 1 || result.Rollback.Undone[0].ID != "staging-config" {
+		t.Errorf("Expected the bucket to be undone, got %+v", result.Rollback)
+	}
+	if _, ok := s3Backend.Bucket("staging-config"); ok {
+		t.Error("Expected staging-config to be deleted")
+	}
+
+	// Without failures nothing is rolled back
+	plan, _ = planner.Plan(context.Background(), m)
+	if result := planner.Apply(context.Background(), plan, WithRollback()); result.Rollback != nil || len(result.Failed()) != 0 {
+		t.Errorf("Expected a clean apply, got %+v", result)
+	}
+}
@@ -0,0 +1,208 @@
+// Package manifest describes environments of S3 buckets and EC2 instance
+// groups in YAML, and plans and applies the changes needed to reach them.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tags added to every resource created from a manifest. They tie live
+// resources back to the manifest entry that declared them.
+const (
+	TagManifest = "aws-resources:manifest"
+	TagName     = "aws-resources:name"
+)
+
+// DefaultRegion is used when neither a resource nor the manifest defaults set a region
+const DefaultRegion = "us-east-1"
+
+// Manifest declares the desired resources of an environment
+type Manifest struct {
+	// Name identifies the environment; resources of other manifests are never touched
+	Name     string          `yaml:"name"`
+	Defaults Defaults        `yaml:"defaults"`
+	S3       []Bucket        `yaml:"s3"`
+	EC2      []InstanceGroup `yaml:"ec2"`
+}
+
+// Defaults apply to every resource of the manifest
+type Defaults struct {
+	Region string            `yaml:"region"`
+	Tags   map[string]string `yaml:"tags"`
+}
+
+// Bucket declares an S3 bucket
+type Bucket struct {
+	// Name is the logical name of the bucket within the manifest
+	Name       string            `yaml:"name"`
+	BucketName string            `yaml:"bucket_name"`
+	Region     string            `yaml:"region"`
+	Versioning *bool             `yaml:"versioning"`
+	Encryption *bool             `yaml:"encryption"`
+	Tags       map[string]string `yaml:"tags"`
+	// DependsOn lists entries, e.g. ec2.web, that must be applied first
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// InstanceGroup declares a number of identical EC2 instances
+type InstanceGroup struct {
+	// Name is the logical name of the group within the manifest
+	Name         string            `yaml:"name"`
+	ImageID      string            `yaml:"image_id"`
+	InstanceType string            `yaml:"instance_type"`
+	KeyName      string            `yaml:"key_name"`
+	Count        *int              `yaml:"count"`
+	Region       string            `yaml:"region"`
+	Tags         map[string]string `yaml:"tags"`
+	// UserData is run at boot; only new instances use a changed value
+	UserData         string   `yaml:"user_data"`
+	SubnetID         string   `yaml:"subnet_id"`
+	SecurityGroupIDs []string `yaml:"security_group_ids"`
+	// DependsOn lists entries, e.g. s3.logs, that must be applied first
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// Size returns the number of instances of the group, defaulting to one
+func (g InstanceGroup) Size() int {
+	if g.Count == nil {
+		return 1
+	}
+	return *g.Count
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Load reads and validates the manifest at path
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a manifest. Unknown fields are rejected so
+// typos do not silently drop settings.
+func Parse(data []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var m Manifest
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks the manifest for missing or invalid settings
+func (m *Manifest) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !namePattern.MatchString(m.Name) {
+		fail("name: must be set and contain only letters, numbers, hyphens and underscores")
+	}
+	if m.Defaults.Region != "" && !services.ValidRegion(m.Defaults.Region) {
+		fail("defaults.region: %q is not a valid AWS region", m.Defaults.Region)
+	}
+	if err := services.ValidateTags(m.Defaults.Tags); err != nil {
+		fail("defaults.tags: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for i, bucket := range m.S3 {
+		where := fmt.Sprintf("s3[%d]", i)
+		if bucket.Name != "" {
+			where = "s3." + bucket.Name
+		}
+
+		switch {
+		case !namePattern.MatchString(bucket.Name):
+			fail("%s: name must be set and contain only letters, numbers, hyphens and underscores", where)
+		case names["s3."+bucket.Name]:
+			fail("%s: duplicate name", where)
+		}
+		names["s3."+bucket.Name] = true
+
+		if err := services.ValidateBucketName(bucket.BucketName); err != nil {
+			fail("%s: bucket_name %v", where, err)
+		}
+		if bucket.Region != "" && !services.ValidRegion(bucket.Region) {
+			fail("%s: region %q is not a valid AWS region", where, bucket.Region)
+		}
+		if err := services.ValidateTags(bucket.Tags); err != nil {
+			fail("%s: tags: %v", where, err)
+		}
+	}
+
+	for i, group := range m.EC2 {
+		where := fmt.Sprintf("ec2[%d]", i)
+		if group.Name != "" {
+			where = "ec2." + group.Name
+		}
+
+		switch {
+		case !namePattern.MatchString(group.Name):
+			fail("%s: name must be set and contain only letters, numbers, hyphens and underscores", where)
+		case names["ec2."+group.Name]:
+			fail("%s: duplicate name", where)
+		}
+		names["ec2."+group.Name] = true
+
+		if group.ImageID == "" || group.InstanceType == "" || group.KeyName == "" {
+			fail("%s: image_id, instance_type and key_name are required", where)
+		}
+		if group.Size() < 0 {
+			fail("%s: count must not be negative", where)
+		}
+		if group.Region != "" && !services.ValidRegion(group.Region) {
+			fail("%s: region %q is not a valid AWS region", where, group.Region)
+		}
+		if err := services.ValidateTags(group.Tags); err != nil {
+			fail("%s: tags: %v", where, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		if _, err := m.graph(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid manifest: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// region resolves the region of a resource
+func (m *Manifest) region(region string) string {
+	switch {
+	case region != "":
+		return region
+	case m.Defaults.Region != "":
+		return m.Defaults.Region
+	}
+	return DefaultRegion
+}
+
+// tags returns the tags of a resource, including the manifest ownership tags
+func (m *Manifest) tags(name string, tags map[string]string) map[string]string {
+	return services.MergeTags(m.Defaults.Tags, tags, map[string]string{
+		TagManifest: m.Name,
+		TagName:     name,
+	})
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`
+name: staging
+defaults:
+  region: eu-west-1
+  tags:
+    environment: staging
+s3:
+  - name: logs
+    bucket_name: staging-logs
+    versioning: true
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    count: 2
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(m.S3) != 1 || m.S3[0].BucketName != "staging-logs" || m.S3[0].Versioning == nil || !*m.S3[0].Versioning {
+		t.Errorf("Unexpected buckets: %+v", m.S3)
+	}
+	if len(m.EC2) != 1 || m.EC2[0].Size() != 2 {
+		t.Errorf("Unexpected instance groups: %+v", m.EC2)
+	}
+	if region := m.region(""); region != "eu-west-1" {
+		t.Errorf("Expected default region eu-west-1, got %s", region)
+	}
+
+	tags := m.tags("logs", map[string]string{"role": "logs"})
+	if tags["environment"] != "staging" || tags["role"] != "logs" || tags[TagManifest] != "staging" || tags[TagName] != "logs" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
+
+func TestParseRejectsInvalidManifests(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"unknown field", "name: a\ns3:\n  - name: logs\n    bucket: a-bucket\n", "field bucket not found"},
+		{"missing name", "s3: []\n", "name: must be set"},
+		{"duplicate name", "name: a\ns3:\n  - name: logs\n    bucket_name: bucket-one\n  - name: logs\n    bucket_name: bucket-two\n", "s3.logs: duplicate name"},
+		{"invalid bucket name", "name: a\ns3:\n  - name: logs\n    bucket_name: Bad_Bucket\n", "s3.logs: bucket_name"},
+		{"missing image", "name: a\nec2:\n  - name: web\n    instance_type: t3.micro\n    key_name: k\n", "image_id, instance_type and key_name are required"},
+		{"negative count", "name: a\nec2:\n  - name: web\n    image_id: ami-1\n    instance_type: t3.micro\n    key_name: k\n    count: -1\n", "count must not be negative"},
+		{"invalid region", "name: a\ndefaults:\n  region: nowhere\n", "not a valid AWS region"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.manifest))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	m, err := Load("../../examples/manifest/staging.yaml")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if m.Name != "staging" || len(m.S3) != 2 || len(m.EC2) != 1 {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+}
@@ -0,0 +1,548 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+)
+
+// ActionType is the kind of change an action makes
+type ActionType string
+
+const (
+	ActionCreate ActionType = "create"
+	ActionUpdate ActionType = "update"
+	ActionDelete ActionType = "delete"
+)
+
+// Action is a single service operation needed to reach the manifest
+type Action struct {
+	Type    ActionType `json:"type"`
+	Service string     `json:"service"`
+	// Name is the logical name of the manifest entry the action belongs to
+	Name string `json:"name"`
+	// ID is the bucket name or instance ID, empty for instances not launched yet
+	ID     string                 `json:"id,omitempty"`
+	Region string                 `json:"region"`
+	Params map[string]interface{} `json:"params"`
+	// Changes lists the settings an update changes
+	Changes []services.Difference `json:"changes,omitempty"`
+	// Notes explain differences the action cannot resolve
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Plan lists the actions that make live resources match a manifest
+type Plan struct {
+	Manifest string   `json:"manifest"`
+	Actions  []Action `json:"actions"`
+	// Warnings report differences no action can resolve
+	Warnings []string `json:"warnings,omitempty"`
+	// Dependencies lists the entries each entry waits for when applied
+	Dependencies map[string][]string `json:"dependencies,omitempty"`
+
+	manifest *Manifest
+	graph    *graph
+}
+
+// add appends an action, or turns its notes into warnings when it changes nothing
+func (p *Plan) add(action Action) {
+	if action.Type != ActionUpdate || len(action.Changes) > 0 {
+		p.Actions = append(p.Actions, action)
+		return
+	}
+	for _, note := range action.Notes {
+		p.Warnings = append(p.Warnings, fmt.Sprintf("%s.%s %s: %s", action.Service, action.Name, action.ID, note))
+	}
+}
+
+// actionOrder is the order in which action types are applied
+var actionOrder = map[ActionType]int{ActionCreate: 0, ActionUpdate: 1, ActionDelete: 2}
+
+// Count returns the number of actions of a type
+func (p *Plan) Count(actionType ActionType) int {
+	n := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			n++
+		}
+	}
+	return n
+}
+
+// HasChanges reports whether applying the plan would change anything
+func (p *Plan) HasChanges() bool {
+	return len(p.Actions) > 0
+}
+
+// Planner compares manifests with the live resources recorded in the
+// inventory and applies the resulting plans through the services
+type Planner struct {
+	store    *inventory.Store
+	opts     []services.Option
+	mu       sync.Mutex
+	services map[string]services.AWSService
+}
+
+// NewPlanner creates a planner. Resources created by the planner are
+// recorded in store, which is also used to find what a manifest created.
+func NewPlanner(store *inventory.Store, opts ...services.Option) *Planner {
+	return &Planner{
+		store:    store,
+		opts:     append(opts, services.WithInventory(store)),
+		services: make(map[string]services.AWSService),
+	}
+}
+
+// service returns the service for a region, creating it on first use
+func (p *Planner) service(name, region string) (services.AWSService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := name + "/" + region
+	if svc, ok := p.services[key]; ok {
+		return svc, nil
+	}
+
+	svc, err := services.NewService(name, region, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.services[key] = svc
+	return svc, nil
+}
+
+// Plan computes the actions needed to make live resources match m.
+// Entries are planned in dependency order, with references to outputs
+// already known replaced. Creates come first and deletes last.
+func (p *Planner) Plan(ctx context.Context, m *Manifest) (*Plan, error) {
+	g, err := m.graph()
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Manifest: m.Name, Dependencies: make(map[string][]string), manifest: m, graph: g}
+
+	known := newOutputs()
+	for _, node := range g.order {
+		if deps := g.deps[node]; len(deps) > 0 {
+			plan.Dependencies[node] = deps
+		}
+
+		if bucket, ok := m.bucket(node); ok {
+			bucket.Tags = known.expandTags(bucket.Tags)
+			if err := p.planBucket(ctx, plan, m, bucket); err != nil {
+				return nil, err
+			}
+			known.set(node, bucketOutputs(m, bucket))
+			continue
+		}
+
+		group, _ := m.group(node)
+		group.ImageID = known.expand(group.ImageID)
+		group.InstanceType = known.expand(group.InstanceType)
+		group.KeyName = known.expand(group.KeyName)
+		group.UserData = known.expand(group.UserData)
+		group.SubnetID = known.expand(group.SubnetID)
+		securityGroups := make([]string, len(group.SecurityGroupIDs))
+		for i, id := range group.SecurityGroupIDs {
+			securityGroups[i] = known.expand(id)
+		}
+		group.SecurityGroupIDs = securityGroups
+		group.Tags = known.expandTags(group.Tags)
+
+		// Outputs of groups that launch or terminate instances are only known after apply
+		outputs, err := p.planGroup(ctx, plan, m, group)
+		if err != nil {
+			return nil, err
+		}
+		if outputs != nil {
+			known.set(node, outputs)
+		}
+	}
+
+	if err := p.planRemoved(ctx, plan, m); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(plan.Actions, func(i, j int) bool {
+		return actionOrder[plan.Actions[i].Type] < actionOrder[plan.Actions[j].Type]
+	})
+	return plan, nil
+}
+
+// planBucket compares a declared bucket with the live bucket. An existing
+// bucket the inventory does not record for the manifest fails the plan.
+func (p *Planner) planBucket(ctx context.Context, plan *Plan, m *Manifest, bucket Bucket) error {
+	region := m.region(bucket.Region)
+	tags := m.tags(bucket.Name, bucket.Tags)
+
+	svc, err := p.service(services.S3ServiceName, region)
+	if err != nil {
+		return err
+	}
+
+	live, err := svc.DescribeResource(ctx, map[string]interface{}{"bucket_name": bucket.BucketName})
+	if err != nil {
+		return err
+	}
+
+	action := Action{
+		Service: services.S3ServiceName,
+		Name:    bucket.Name,
+		ID:      bucket.BucketName,
+		Region:  region,
+		Params:  map[string]interface{}{"bucket_name": bucket.BucketName},
+	}
+
+	if !live.Success {
+		if !errors.Is(live.Err, services.ErrNotFound) {
+			return fmt.Errorf("s3.%s: %s", bucket.Name, live.Message)
+		}
+
+		action.Type = ActionCreate
+		action.Params["region"] = region
+		action.Params["tags"] = tags
+		if bucket.Versioning != nil {
+			action.Params["versioning"] = *bucket.Versioning
+		}
+		if bucket.Encryption != nil {
+			action.Params["encryption"] = *bucket.Encryption
+		}
+		plan.add(action)
+		return nil
+	}
+
+	// A bucket the manifest did not create is only adopted once imported
+	managed, err := p.managedBucket(m, bucket.BucketName)
+	if err != nil {
+		return err
+	}
+	if !managed {
+		return fmt.Errorf("s3.%s: bucket %s already exists but is not managed by manifest %s, import it first to adopt it", bucket.Name, bucket.BucketName, m.Name)
+	}
+
+	// DescribeResource looks the bucket up in its own region, which may
+	// differ from the declared one
+	action.Type = ActionUpdate
+	if liveRegion, _ := live.Data["region"].(string); liveRegion != "" && liveRegion != region {
+		action.Region = liveRegion
+		action.Notes = append(action.Notes, fmt.Sprintf("bucket exists in %s, the region of a bucket cannot be changed", liveRegion))
+	}
+
+	if bucket.Versioning != nil {
+		enabled := live.Data["versioning"] == "Enabled"
+		if enabled != *bucket.Versioning {
+			action.Params["versioning"] = *bucket.Versioning
+			action.Changes = append(action.Changes, services.Difference{Field: "versioning", Desired: *bucket.Versioning, Actual: enabled})
+		}
+	}
+
+	if bucket.Encryption != nil {
+		_, encrypted := live.Data["encryption"]
+		if encrypted != *bucket.Encryption {
+			action.Params["encryption"] = *bucket.Encryption
+			action.Changes = append(action.Changes, services.Difference{Field: "encryption", Desired: *bucket.Encryption, Actual: encrypted})
+		}
+	}
+
+	liveTags, _ := live.Data["tags"].(map[string]string)
+	if changed := changedTags(&action, tags, liveTags); len(changed) > 0 {
+		action.Params["tags"] = changed
+	}
+
+	plan.add(action)
+	return nil
+}
+
+// managedBucket reports whether the inventory records a bucket as created
+// by manifest m, or as imported and not claimed by another manifest
+func (p *Planner) managedBucket(m *Manifest, bucketName string) (bool, error) {
+	records, err := p.store.List(inventory.Filter{Service: services.S3ServiceName, IDs: []string{bucketName}})
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records {
+		owner := record.Tags[TagManifest]
+		if owner == m.Name || (record.Imported && owner == "") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// liveInstance is an instance of a group that still exists
+type liveInstance struct {
+	record inventory.Record
+	data   map[string]interface{}
+}
+
+// planGroup compares a declared instance group with its live instances.
+// Missing instances are launched and surplus instances, newest first, are
+// terminated. The outputs of the group are returned when no instance is
+// launched or terminated.
+func (p *Planner) planGroup(ctx context.Context, plan *Plan, m *Manifest, group InstanceGroup) (map[string]string, error) {
+	region := m.region(group.Region)
+	tags := m.tags(group.Name, group.Tags)
+
+	instances, err := p.liveInstances(ctx, m.Name, group.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []liveInstance
+	for _, instance := range instances {
+		if instance.record.Region == region && len(kept) < group.Size() {
+			kept = append(kept, instance)
+			continue
+		}
+		plan.add(deleteInstance(group.Name, instance.record))
+	}
+
+	for _, instance := range kept {
+		action := Action{
+			Type:    ActionUpdate,
+			Service: services.EC2ServiceName,
+			Name:    group.Name,
+			ID:      instance.record.ID,
+			Region:  region,
+			Params:  map[string]interface{}{"instance_id": instance.record.ID},
+		}
+
+		if liveType, _ := instance.data["instance_type"].(string); liveType != group.InstanceType {
+			action.Params["instance_type"] = group.InstanceType
+			action.Changes = append(action.Changes, services.Difference{Field: "instance_type", Desired: group.InstanceType, Actual: liveType})
+			if state, _ := instance.data["state"].(string); state != "stopped" {
+				action.Notes = append(action.Notes, "the instance must be stopped before its type can be changed")
+			}
+		}
+		if liveImage, _ := instance.data["image_id"].(string); liveImage != group.ImageID {
+			action.Notes = append(action.Notes, fmt.Sprintf("image_id differs (%s), the instance must be replaced to use %s", liveImage, group.ImageID))
+		}
+
+		liveTags, _ := instance.data["tags"].(map[string]string)
+		if changed := changedTags(&action, tags, liveTags); len(changed) > 0 {
+			action.Params["tags"] = changed
+		}
+
+		plan.add(action)
+	}
+
+	missing := group.Size() - len(kept)
+	if missing > 0 {
+		action := Action{
+			Type:    ActionCreate,
+			Service: services.EC2ServiceName,
+			Name:    group.Name,
+			Region:  region,
+			Params: map[string]interface{}{
+				"image_id":      group.ImageID,
+				"instance_type": group.InstanceType,
+				"key_name":      group.KeyName,
+				"count":         missing,
+				"region":        region,
+				"tags":          tags,
+			},
+		}
+		if group.UserData != "" {
+			action.Params["user_data"] = group.UserData
+		}
+		if group.SubnetID != "" {
+			action.Params["subnet_id"] = group.SubnetID
+		}
+		if len(group.SecurityGroupIDs) > 0 {
+			action.Params["security_group_ids"] = group.SecurityGroupIDs
+		}
+		plan.add(action)
+	}
+
+	if missing != 0 || len(kept) != len(instances) {
+		return nil, nil
+	}
+	return groupOutputs(region, kept), nil
+}
+
+// liveInstances returns the recorded instances of a group that still exist, oldest first
+func (p *Planner) liveInstances(ctx context.Context, manifestName, group string) ([]liveInstance, error) {
+	records, err := p.store.List(inventory.Filter{
+		Service: services.EC2ServiceName,
+		Tags:    map[string]string{TagManifest: manifestName, TagName: group},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []liveInstance
+	for _, record := range records {
+		svc, err := p.service(services.EC2ServiceName, record.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		live, err := svc.DescribeResource(ctx, map[string]interface{}{"instance_id": record.ID})
+		if err != nil {
+			return nil, err
+		}
+		if !live.Success {
+			if errors.Is(live.Err, services.ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("ec2.%s: %s", group, live.Message)
+		}
+
+		switch live.Data["state"] {
+		case "shutting-down", "terminated":
+			continue
+		}
+		instances = append(instances, liveInstance{record: record, data: live.Data})
+	}
+	return instances, nil
+}
+
+// planRemoved deletes the resources of entries that are no longer in the manifest
+func (p *Planner) planRemoved(ctx context.Context, plan *Plan, m *Manifest) error {
+	records, err := p.store.List(inventory.Filter{Tags: map[string]string{TagManifest: m.Name}})
+	if err != nil {
+		return err
+	}
+
+	declared := make(map[string]bool)
+	for _, bucket := range m.S3 {
+		declared["s3/"+bucket.BucketName] = true
+	}
+	for _, group := range m.EC2 {
+		declared["ec2/"+group.Name] = true
+	}
+
+	for _, record := range records {
+		name := record.Tags[TagName]
+
+		switch record.Service {
+		case services.S3ServiceName:
+			if declared["s3/"+record.ID] {
+				continue
+			}
+			svc, err := p.service(services.S3ServiceName, record.Region)
+			if err != nil {
+				return err
+			}
+			live, err := svc.DescribeResource(ctx, map[string]interface{}{"bucket_name": record.ID})
+			if err != nil {
+				return err
+			}
+			if !live.Success {
+				continue
+			}
+			plan.add(Action{
+				Type:    ActionDelete,
+				Service: services.S3ServiceName,
+				Name:    name,
+				ID:      record.ID,
+				Region:  record.Region,
+				Params:  map[string]interface{}{"bucket_name": record.ID},
+			})
+
+		case services.EC2ServiceName:
+			if declared["ec2/"+name] {
+				continue
+			}
+			// Handle the whole group on its first record
+			declared["ec2/"+name] = true
+			instances, err := p.liveInstances(ctx, m.Name, name)
+			if err != nil {
+				return err
+			}
+			for _, instance := range instances {
+				plan.add(deleteInstance(name, instance.record))
+			}
+		}
+	}
+	return nil
+}
+
+// deleteInstance returns the action terminating a recorded instance
+func deleteInstance(group string, record inventory.Record) Action {
+	return Action{
+		Type:    ActionDelete,
+		Service: services.EC2ServiceName,
+		Name:    group,
+		ID:      record.ID,
+		Region:  record.Region,
+		Params:  map[string]interface{}{"instance_id": []string{record.ID}},
+	}
+}
+
+// changedTags adds a change to action for every desired tag missing or
+// different on the live resource, and returns those tags. Tags added by
+// hand are left alone.
+func changedTags(action *Action, desired, live map[string]string) map[string]string {
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := make(map[string]string)
+	for _, key := range keys {
+		value := desired[key]
+		liveValue, ok := live[key]
+		if ok && liveValue == value {
+			continue
+		}
+
+		changed[key] = value
+		diff := services.Difference{Field: "tags." + key, Desired: value}
+		if ok {
+			diff.Actual = liveValue
+		}
+		action.Changes = append(action.Changes, diff)
+	}
+	return changed
+}
+
+// bucket returns the bucket entry of a node
+func (m *Manifest) bucket(node string) (Bucket, bool) {
+	for _, bucket := range m.S3 {
+		if nodeKey(services.S3ServiceName, bucket.Name) == node {
+			return bucket, true
+		}
+	}
+	return Bucket{}, false
+}
+
+// group returns the instance group entry of a node
+func (m *Manifest) group(node string) (InstanceGroup, bool) {
+	for _, group := range m.EC2 {
+		if nodeKey(services.EC2ServiceName, group.Name) == node {
+			return group, true
+		}
+	}
+	return InstanceGroup{}, false
+}
+
+// bucketOutputs returns the outputs of a bucket entry
+func bucketOutputs(m *Manifest, bucket Bucket) map[string]string {
+	return map[string]string{
+		"bucket_name": bucket.BucketName,
+		"region":      m.region(bucket.Region),
+	}
+}
+
+// groupOutputs returns the outputs of an instance group, listing instances oldest first
+func groupOutputs(region string, instances []liveInstance) map[string]string {
+	ids := make([]string, len(instances))
+	ips := make([]string, len(instances))
+	for i, instance := range instances {
+		ids[i] = instance.record.ID
+		ips[i], _ = instance.data["private_ip"].(string)
+	}
+	return map[string]string{
+		"instance_ids": strings.Join(ids, ","),
+		"private_ips":  strings.Join(ips, ","),
+		"region":       region,
+	}
+}
@@ -0,0 +1,261 @@
+package manifest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// newTestPlanner returns a planner backed by fakes and a temporary inventory
+func newTestPlanner(t *testing.T) (*Planner, *fake.S3, *fake.EC2) {
+	t.Helper()
+
+	store, err := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s3Backend := fake.NewS3()
+	ec2Backend := fake.NewEC2("us-east-1")
+	return NewPlanner(store, services.WithS3Client(s3Backend), services.WithEC2Client(ec2Backend)), s3Backend, ec2Backend
+}
+
+func mustParse(t *testing.T, data string) *Manifest {
+	t.Helper()
+
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return m
+}
+
+// planAndApply plans m, applies the plan and fails the test on any error
+func planAndApply(t *testing.T, planner *Planner, m *Manifest) *Plan {
+	t.Helper()
+
+	plan, err := planner.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result := planner.Apply(context.Background(), plan)
+	for _, failed := range result.Failed() {
+		t.Fatalf("Expected %s %s.%s to succeed, got %s", failed.Action.Type, failed.Action.Service, failed.Action.Name, failed.Result.Message)
+	}
+	return plan
+}
+
+const testManifest = `
+name: staging
+s3:
+  - name: logs
+    bucket_name: staging-logs
+    versioning: true
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    count: 2
+`
+
+func TestPlanAndApply(t *testing.T) {
+	ctx := context.Background()
+	planner, s3Backend, ec2Backend := newTestPlanner(t)
+	m := mustParse(t, testManifest)
+
+	plan := planAndApply(t, planner, m)
+	if plan.Count(ActionCreate) != 2 || len(plan.Actions) != 2 {
+		t.Fatalf("Expected two creates, got %+v", plan.Actions)
+	}
+
+	bucket, ok := s3Backend.Bucket("staging-logs")
+	if !ok || bucket.Tags[TagManifest] != "staging" || bucket.Tags[TagName] != "logs" {
+		t.Errorf("Expected bucket tagged with its manifest, got %+v", bucket)
+	}
+	if instances := ec2Backend.Instances(); len(instances) != 2 {
+		t.Errorf("Expected 2 instances, got %d", len(instances))
+	}
+
+	// Applying again changes nothing
+	plan, err := planner.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("Expected no changes, got %+v", plan.Actions)
+	}
+
+	// Changes made by hand are reverted, without removing tags added by hand
+	s3Backend.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket:                  aws.String("staging-logs"),
+		VersioningConfiguration: &types.VersioningConfiguration{Status: types.BucketVersioningStatusSuspended},
+	})
+	plan = planAndApply(t, planner, m)
+	if plan.Count(ActionUpdate) != 1 || plan.Actions[0].Changes[0].Field != "versioning" {
+		t.Errorf("Expected versioning update, got %+v", plan.Actions)
+	}
+}
+
+func TestPlanScalesInstanceGroups(t *testing.T) {
+	planner, _, ec2Backend := newTestPlanner(t)
+	planAndApply(t, planner, mustParse(t, testManifest))
+
+	plan := planAndApply(t, planner, mustParse(t, `
+name: staging
+s3:
+  - name: logs
+    bucket_name: staging-logs
+    versioning: true
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    count: 3
+`))
+	if plan.Count(ActionCreate) != 1 || plan.Actions[0].Params["count"] != 1 {
+		t.Errorf("Expected one instance to be launched, got %+v", plan.Actions)
+	}
+
+	plan = planAndApply(t, planner, mustParse(t, `
+name: staging
+s3:
+  - name: logs
+    bucket_name: staging-logs
+    versioning: true
+ec2:
+  - name: web
+    image_id: ami-12345678
+    instance_type: t3.micro
+    key_name: staging-key
+    count: 1
+`))
+	if plan.Count(ActionDelete) != 2 {
+		t.Errorf("Expected two instances to be terminated, got %+v", plan.Actions)
+	}
+
+	running := 0
+	for _, instance := range ec2Backend.Instances() {
+		if instance.State.Name != "terminated" && instance.State.Name != "shutting-down" {
+			running++
+		}
+	}
+	if running != 1 {
+		t.Errorf("Expected 1 instance left, got %d", running)
+	}
+}
+
+func TestPlanDeletesRemovedEntries(t *testing.T) {
+	planner, s3Backend, _ := newTestPlanner(t)
+	planAndApply(t, planner, mustParse(t, testManifest))
+
+	// Resources of other manifests are never touched
+	other := mustParse(t, "name: other\ns3:\n  - name: data\n    bucket_name: other-data\n")
+	planAndApply(t, planner, other)
+
+	plan := planAndApply(t, planner, mustParse(t, "name: staging\n"))
+	if plan.Count(ActionDelete) != 3 || len(plan.Actions) != 3 {
+		t.Errorf("Expected the bucket and both instances to be deleted, got %+v", plan.Actions)
+	}
+	if _, ok := s3Backend.Bucket("staging-logs"); ok {
+		t.Error("Expected staging-logs to be deleted")
+	}
+	if _, ok := s3Backend.Bucket("other-data"); !ok {
+		t.Error("Expected other-data to be kept")
+	}
+}
+
+// euBucketTransport answers like S3 for a bucket in eu-west-1: requests
+// sent to another region are redirected
+type euBucketTransport struct{}
+
+func (euBucketTransport) Do(req *http.Request) (*http.Response, error) {
+	status, body := http.StatusOK, ""
+	switch {
+	case req.URL.Query().Has("location"):
+		body = `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">eu-west-1</LocationConstraint>`
+	case !strings.Contains(req.URL.Host, "eu-west-1"):
+		status = http.StatusMovedPermanently
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"X-Amz-Bucket-Region": []string{"eu-west-1"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestPlanBucketInAnotherRegion(t *testing.T) {
+	// A CA bundle cannot be applied to a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	store, _ := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(euBucketTransport{}),
+	))
+	planner := NewPlanner(store, services.WithProvider(provider))
+	m := mustParse(t, "name: staging\ns3:\n  - name: logs\n    bucket_name: staging-logs\n")
+
+	// Created by the manifest before its region changed
+	store.Put(inventory.Record{
+		Service: services.S3ServiceName,
+		Type:    services.ResourceTypeBucket,
+		ID:      "staging-logs",
+		Region:  "eu-west-1",
+		Tags:    map[string]string{TagManifest: "staging", TagName: "logs"},
+	})
+
+	plan, err := planner.Plan(context.Background(), m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != ActionUpdate || plan.Actions[0].Region != "eu-west-1" {
+		t.Fatalf("Expected an update of the bucket in eu-west-1, got %+v", plan.Actions)
+	}
+	if notes := plan.Actions[0].Notes; len(notes) != 1 || !strings.Contains(notes[0], "bucket exists in eu-west-1") {
+		t.Errorf("Expected a note about the region, got %v", notes)
+	}
+}
+
+func TestPlanRejectsUnmanagedBuckets(t *testing.T) {
+	ctx := context.Background()
+	planner, s3Backend, _ := newTestPlanner(t)
+	m := mustParse(t, "name: staging\ns3:\n  - name: logs\n    bucket_name: staging-logs\n")
+
+	// Created outside the manifest
+	s3Backend.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String("staging-logs")})
+
+	if _, err := planner.Plan(ctx, m); err == nil || !strings.Contains(err.Error(), "not managed by manifest staging") {
+		t.Fatalf("Expected the unmanaged bucket to fail the plan, got %v", err)
+	}
+	if bucket, _ := s3Backend.Bucket("staging-logs"); bucket.Tags[TagManifest] != "" {
+		t.Errorf("Expected the bucket to be left untagged, got %+v", bucket.Tags)
+	}
+
+	if _, err := services.Import(ctx, planner.store, services.S3ServiceName, "us-east-1", services.ImportQuery{IDs: []string{"staging-logs"}}, services.ImportOptions{}, services.WithS3Client(s3Backend)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	plan, err := planner.Plan(ctx, m)
+	if err != nil {
+		t.Fatalf("Expected the imported bucket to be adopted, got %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Type != ActionUpdate {
+		t.Errorf("Expected an update tagging the bucket, got %+v", plan.Actions)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+// DefaultAccountConcurrency is the number of accounts operated on at once
+// when no limit is given
+const DefaultAccountConcurrency = 4
+
+// OrganizationAccounts lists the active accounts of the organization of the
+// provider's credentials, each reached by assuming roleName in it, e.g.
+// OrganizationAccountAccessRole. region selects the partition of the role ARNs.
+func OrganizationAccounts(ctx context.Context, region, roleName string, opts ...Option) ([]awsconfig.Account, error) {
+	if roleName == "" {
+		return nil, fmt.Errorf("a role name to assume in the member accounts is required")
+	}
+
+	options := newServiceOptions(opts)
+	client := options.orgsClient
+	if client == nil {
+		var err error
+		client, err = awsconfig.Client(ctx, options.provider, "organizations", region, func(cfg aws.Config) OrganizationsAPI {
+			return organizations.NewFromConfig(cfg, func(o *organizations.Options) {
+				o.TracerProvider = telemetry.SDKTracerProvider()
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var accounts []awsconfig.Account
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization accounts: %w", ClassifyError(err))
+		}
+
+		for _, account := range page.Accounts {
+			if account.State != orgtypes.AccountStateActive {
+				continue
+			}
+			id := aws.ToString(account.Id)
+			accounts = append(accounts, awsconfig.Account{
+				Name:    aws.ToString(account.Name),
+				ID:      id,
+				RoleARN: awsconfig.RoleARN(partition(region), id, roleName),
+			})
+		}
+	}
+	return accounts, nil
+}
+
+// AccountResult is the outcome of an operation in one account
+type AccountResult struct {
+	// Account is the ID of the account, empty when it could not be resolved
+	Account string          `json:"account"`
+	Name    string          `json:"name"`
+	Result  *ResourceResult `json:"result"`
+}
+
+// MultiAccountReport aggregates the outcome of an operation run in several accounts
+type MultiAccountReport struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	Region    string `json:"region"`
+	// Results holds one result per account, in the order the accounts were given
+	Results   []AccountResult `json:"results"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+}
+
+// Success reports whether the operation succeeded in every account
+func (r *MultiAccountReport) Success() bool {
+	return r.Failed == 0
+}
+
+// Failures returns the results of the accounts where the operation failed
+func (r *MultiAccountReport) Failures() []AccountResult {
+	var failures []AccountResult
+	for _, result := range r.Results {
+		if !result.Result.Success {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// MultiAccountOptions configures RunInAccounts
+type MultiAccountOptions struct {
+	// Concurrency limits the accounts operated on at once, DefaultAccountConcurrency when zero
+	Concurrency int
+}
+
+// RunInAccounts runs an operation of service in region in every account.
+// Each account gets its own service, created with opts and a provider
+// derived from the provider of opts with awsconfig.Provider.ForAccount.
+// A failure in one account does not stop the others.
+func RunInAccounts(ctx context.Context, accounts []awsconfig.Account, service, operation, region string, params map[string]interface{}, options MultiAccountOptions, opts ...Option) (*MultiAccountReport, error) {
+	if _, ok := LookupSchema(service, operation); !ok {
+		return nil, fmt.Errorf("unknown %s operation %q", service, operation)
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAccountConcurrency
+	}
+
+	base := newServiceOptions(opts)
+	injected := base.s3Client != nil || base.ec2Client != nil
+	report := &MultiAccountReport{Service: service, Operation: operation, Region: region, Results: make([]AccountResult, len(accounts))}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		wg.Add(1)
+		go func(i int, account awsconfig.Account) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			provider := base.provider.ForAccount(account)
+			result := AccountResult{Account: account.ID, Name: account.Name}
+			if result.Account == "" {
+				result.Account = accountID(ctx, provider, injected)
+			}
+
+			svc, err := NewService(service, region, append(opts[:len(opts):len(opts)], WithProvider(provider))...)
+			if err != nil {
+				result.Result = configFailure(region, err)
+			} else {
+				result.Result = runOperation(ctx, svc, operation, params)
+			}
+			report.Results[i] = result
+		}(i, account)
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Result.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// runOperation runs an operation of a service, turning an error into a failed result
+func runOperation(ctx context.Context, svc AWSService, operation string, params map[string]interface{}) *ResourceResult {
+	var result *ResourceResult
+	var err error
+	switch operation {
+	case OperationCreate:
+		result, err = svc.CreateResource(ctx, params)
+	case OperationDescribe:
+		result, err = svc.DescribeResource(ctx, params)
+	case OperationList:
+		result, err = svc.ListResources(ctx, params)
+	case OperationUpdate:
+		result, err = svc.UpdateResource(ctx, params)
+	case OperationDelete:
+		result, err = svc.DeleteResource(ctx, params)
+	default:
+		err = fmt.Errorf("unknown operation %q", operation)
+	}
+	if err != nil {
+		return &ResourceResult{Success: false, Message: err.Error(), Err: err}
+	}
+	return result
+}
+
+// accountScope gives the options of the services that reach the account of
+// each inventory record
+type accountScope struct {
+	opts   []Option
+	base   serviceOptions
+	caller string
+}
+
+// newAccountScope resolves the account of the provider of opts
+func newAccountScope(ctx context.Context, opts []Option) *accountScope {
+	base := newServiceOptions(opts)
+	injected := base.s3Client != nil || base.ec2Client != nil
+	return &accountScope{opts: opts, base: base, caller: accountID(ctx, base.provider, injected)}
+}
+
+// key identifies the services that reach record
+func (a *accountScope) key(record inventory.Record) string {
+	if a.local(record) {
+		return record.Region
+	}
+	return record.Account + "/" + record.Region
+}
+
+// local reports whether record is in the provider's account
+func (a *accountScope) local(record inventory.Record) bool {
+	return record.Account == "" || record.Account == a.caller
+}
+
+// optionsFor returns the options of the services that reach the account of
+// record. A record of another account is reached by assuming the role set
+// with WithAccountRole; without it, an error says why it cannot be reached.
+func (a *accountScope) optionsFor(record inventory.Record) ([]Option, error) {
+	if a.local(record) {
+		return a.opts, nil
+	}
+	if a.base.accountRole == "" {
+		caller := a.caller
+		if caller == "" {
+			caller = "unknown"
+		}
+		return nil, fmt.Errorf("recorded in account %s but the credentials are of account %s, pass the role to assume in it", record.Account, caller)
+	}
+
+	account := awsconfig.Account{
+		ID:      record.Account,
+		RoleARN: awsconfig.RoleARN(partition(record.Region), record.Account, a.base.accountRole),
+	}
+	return append(a.opts[:len(a.opts):len(a.opts)], WithProvider(a.base.provider.ForAccount(account))), nil
+}
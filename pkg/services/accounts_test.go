@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+func TestOrganizationAccounts(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewOrganizations()
+	backend.AddAccount("222222222222", "dev", orgtypes.AccountStateActive)
+	backend.AddAccount("333333333333", "closed", orgtypes.AccountStateSuspended)
+
+	accounts, err := OrganizationAccounts(ctx, "us-east-1", "OrganizationAccountAccessRole", WithOrganizationsClient(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].Name != "dev" {
+		t.Fatalf("Expected only the active account, got %+v", accounts)
+	}
+	if expected := "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole"; accounts[0].RoleARN != expected {
+		t.Errorf("Expected role %s, got %s", expected, accounts[0].RoleARN)
+	}
+
+	backend.InjectError("ListAccounts", fake.APIError("Organizations", "ListAccounts", "AccessDeniedException", "not the management account", http.StatusBadRequest))
+	if _, err := OrganizationAccounts(ctx, "us-east-1", "OrganizationAccountAccessRole", WithOrganizationsClient(backend)); err == nil {
+		t.Error("Expected an error when the accounts cannot be listed")
+	}
+}
+
+func TestRunInAccounts(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+	accounts := []awsconfig.Account{
+		{Name: "dev", RoleARN: awsconfig.RoleARN("aws", "222222222222", "Admin")},
+		{Name: "prod", ID: "444444444444", RoleARN: awsconfig.RoleARN("aws", "444444444444", "Admin")},
+	}
+
+	report, err := RunInAccounts(ctx, accounts, S3ServiceName, OperationCreate, "us-east-1",
+		map[string]interface{}{"bucket_name": "shared-logs"}, MultiAccountOptions{Concurrency: 1},
+		WithS3Client(backend), WithInventory(store))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !report.Success() || report.Succeeded != 2 {
+		t.Fatalf("Expected success in both accounts, got %+v", report)
+	}
+	if report.Results[0].Account != "222222222222" || report.Results[1].Account != "444444444444" {
+		t.Errorf("Expected results tagged with the account IDs in order, got %+v", report.Results)
+	}
+
+	for _, account := range []string{"222222222222", "444444444444"} {
+		if records, _ := store.List(inventory.Filter{Account: account}); len(records) != 1 {
+			t.Errorf("Expected a record carrying account %s, got %+v", account, records)
+		}
+	}
+
+	if _, err := RunInAccounts(ctx, accounts, S3ServiceName, "restart", "us-east-1", nil, MultiAccountOptions{}, WithS3Client(backend)); err == nil {
+		t.Error("Expected an error for an unknown operation")
+	}
+}
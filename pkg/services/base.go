@@ -2,20 +2,81 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"regexp"
+	"time"
 )
 
 // AWSService represents the interface that all AWS services must implement
 type AWSService interface {
 	CreateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)
+	DescribeResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)
+	ListResources(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)
+	UpdateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)
+	DeleteResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)
 }
 
-// ResourceResult represents the result of a resource creation operation
+// ResourceResult represents the result of a resource operation
 type ResourceResult struct {
 	Success bool                   `json:"success"`
 	Message string                 `json:"message"`
 	Data    map[string]interface{} `json:"data,omitempty"`
 	Error   string                 `json:"error,omitempty"`
+
+	// RequestID is the AWS request ID of the failed call, if any
+	RequestID string `json:"request_id,omitempty"`
+
+	// Err is the typed error behind a failed result, for use with errors.Is and errors.As
+	Err error `json:"-"`
+}
+
+// NewService creates the service registered under name, e.g. S3ServiceName
+func NewService(name, region string, opts ...Option) (AWSService, error) {
+	switch name {
+	case S3ServiceName:
+		return NewS3Service(region, opts...)
+	case EC2ServiceName:
+		return NewEC2Service(region, opts...)
+	}
+	return nil, fmt.Errorf("unknown service %q", name)
+}
+
+// regionPattern matches AWS region names such as us-east-1 or us-gov-west-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`)
+
+// ValidRegion reports whether region looks like an AWS region name
+func ValidRegion(region string) bool {
+	return regionPattern.MatchString(region)
+}
+
+// dryRunData returns the base data of a dry-run result
+func dryRunData(action string) map[string]interface{} {
+	return map[string]interface{}{
+		"dry_run": true,
+		"action":  action,
+	}
+}
+
+// IsDryRun reports whether a result was produced by a dry run
+func IsDryRun(result *ResourceResult) bool {
+	if result == nil {
+		return false
+	}
+	dryRun, _ := result.Data["dry_run"].(bool)
+	return dryRun
+}
+
+// NewClientToken returns a random idempotency token suitable for EC2 ClientToken
+func NewClientToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the clock just in case
+		return fmt.Sprintf("aws-resources-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
 }
 
 // BaseService provides common functionality for all AWS services
@@ -30,19 +91,41 @@ func NewBaseService(region string) *BaseService {
 	}
 }
 
-// ValidateRequiredParams validates that all required parameters are provided
-func (b *BaseService) ValidateRequiredParams(params map[string]interface{}, required []string) error {
-	var missing []string
+// DecodeParams validates params against the registered schema of a service
+// operation and stores the converted values in out
+func (b *BaseService) DecodeParams(service, operation string, params map[string]interface{}, out interface{}) error {
+	schema, ok := LookupSchema(service, operation)
+	if !ok {
+		return fmt.Errorf("no parameter schema registered for %s %s", service, operation)
+	}
+	return schema.Decode(params, out)
+}
+
+// configFailure builds the result returned when no client can be configured for a region
+func configFailure(region string, err error) *ResourceResult {
+	return &ResourceResult{
+		Success: false,
+		Error:   "ConfigurationError",
+		Message: fmt.Sprintf("Failed to configure AWS client for region %s: %s", region, err.Error()),
+		Err:     err,
+	}
+}
 
-	for _, param := range required {
-		if value, exists := params[param]; !exists || value == nil || value == "" {
-			missing = append(missing, param)
-		}
+// validationFailure builds the result returned when parameters are invalid
+func validationFailure(err error) *ResourceResult {
+	result := &ResourceResult{
+		Success: false,
+		Error:   "ValidationError",
+		Message: err.Error(),
+		Err:     err,
 	}
 
-	if len(missing) > 0 {
-		return fmt.Errorf("missing required parameters: %v", missing)
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		result.Data = map[string]interface{}{
+			"errors": verr.Errors,
+		}
 	}
 
-	return nil
+	return result
 }
@@ -13,7 +13,7 @@ func TestNewBaseService(t *testing.T) {
 	}
 }
 
-func TestValidateRequiredParams(t *testing.T) {
+func TestDecodeParams(t *testing.T) {
 	service := NewBaseService("us-east-1")
 
 	// Test successful validation
@@ -21,18 +21,20 @@ func TestValidateRequiredParams(t *testing.T) {
 		"bucket_name": "test-bucket",
 		"region":      "us-east-1",
 	}
-	required := []string{"bucket_name"}
 
-	err := service.ValidateRequiredParams(params, required)
+	var input S3CreateBucketInput
+	err := service.DecodeParams(S3ServiceName, OperationCreate, params, &input)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
+	if input.BucketName != "test-bucket" || input.Region != "us-east-1" {
+		t.Errorf("Expected decoded input, got %+v", input)
+	}
 
 	// Test missing parameter
 	params = map[string]interface{}{}
-	required = []string{"bucket_name"}
 
-	err = service.ValidateRequiredParams(params, required)
+	err = service.DecodeParams(S3ServiceName, OperationCreate, params, &input)
 	if err == nil {
 		t.Error("Expected error for missing parameters")
 	}
@@ -41,9 +43,8 @@ func TestValidateRequiredParams(t *testing.T) {
 	params = map[string]interface{}{
 		"bucket_name": "",
 	}
-	required = []string{"bucket_name"}
 
-	err = service.ValidateRequiredParams(params, required)
+	err = service.DecodeParams(S3ServiceName, OperationCreate, params, &input)
 	if err == nil {
 		t.Error("Expected error for empty string parameter")
 	}
@@ -52,10 +53,15 @@ func TestValidateRequiredParams(t *testing.T) {
 	params = map[string]interface{}{
 		"bucket_name": nil,
 	}
-	required = []string{"bucket_name"}
 
-	err = service.ValidateRequiredParams(params, required)
+	err = service.DecodeParams(S3ServiceName, OperationCreate, params, &input)
 	if err == nil {
 		t.Error("Expected error for nil parameter")
 	}
+
+	// Test unregistered operation
+	err = service.DecodeParams("unknown", OperationCreate, params, &input)
+	if err == nil {
+		t.Error("Expected error for unregistered schema")
+	}
 }
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultBulkWorkers is the number of requests RunBulk runs at once when no
+// worker count is given
+const DefaultBulkWorkers = 8
+
+// BulkRequest is one operation of a bulk run
+type BulkRequest struct {
+	Service   string                 `json:"service" yaml:"service"`
+	Operation string                 `json:"operation" yaml:"operation"`
+	Region    string                 `json:"region" yaml:"region"`
+	Params    map[string]interface{} `json:"params,omitempty" yaml:"params"`
+}
+
+// BulkResult is the outcome of one request of a bulk run
+type BulkResult struct {
+	// Index is the position of the request in the bulk run
+	Index   int             `json:"index"`
+	Request BulkRequest     `json:"request"`
+	Result  *ResourceResult `json:"result"`
+}
+
+// BulkOptions configures RunBulk
+type BulkOptions struct {
+	// Workers is the number of requests run at once, DefaultBulkWorkers when zero
+	Workers int
+	// RateLimits caps the requests started per second for each service,
+	// e.g. {"ec2": 2}. Services without a limit are not throttled.
+	RateLimits map[string]float64
+}
+
+// RunBulk runs requests through a pool of workers, sharing one service per
+// service and region created with opts. The results are in request order.
+// Once ctx is cancelled no new request is started, and the requests that
+// did not run fail with the context error.
+func RunBulk(ctx context.Context, requests []BulkRequest, options BulkOptions, opts ...Option) []BulkResult {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	limiters := make(map[string]*rateLimiter)
+	for service, perSecond := range options.RateLimits {
+		if perSecond > 0 {
+			limiters[service] = newRateLimiter(perSecond)
+		}
+	}
+
+	pool := &servicePool{opts: opts, services: make(map[string]AWSService)}
+	results := make([]BulkResult, len(requests))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = BulkResult{Index: i, Request: requests[i], Result: pool.run(ctx, requests[i], limiters[requests[i].Service])}
+			}
+		}()
+	}
+
+	for i := range requests {
+		if ctx.Err() != nil {
+			results[i] = BulkResult{Index: i, Request: requests[i], Result: cancelled(ctx.Err())}
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			results[i] = BulkResult{Index: i, Request: requests[i], Result: cancelled(ctx.Err())}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// servicePool creates one service per service and region and shares it between workers
+type servicePool struct {
+	opts     []Option
+	mu       sync.Mutex
+	services map[string]AWSService
+}
+
+// run waits for the rate limiter of the request's service, then runs the request
+func (p *servicePool) run(ctx context.Context, request BulkRequest, limiter *rateLimiter) *ResourceResult {
+	if _, ok := LookupSchema(request.Service, request.Operation); !ok {
+		return validationFailure(fmt.Errorf("unknown %s operation %q", request.Service, request.Operation))
+	}
+
+	svc, err := p.service(request.Service, request.Region)
+	if err != nil {
+		return configFailure(request.Region, err)
+	}
+	if limiter != nil {
+		if err := limiter.wait(ctx); err != nil {
+			return cancelled(err)
+		}
+	}
+	return runOperation(ctx, svc, request.Operation, request.Params)
+}
+
+// service returns the shared service for a service and region
+func (p *servicePool) service(name, region string) (AWSService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := name + "/" + region
+	if svc, ok := p.services[key]; ok {
+		return svc, nil
+	}
+	svc, err := NewService(name, region, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.services[key] = svc
+	return svc, nil
+}
+
+// cancelled builds the result of a request that did not run because ctx was cancelled
+func cancelled(err error) *ResourceResult {
+	return &ResourceResult{
+		Success: false,
+		Error:   "Cancelled",
+		Message: "Not run: " + err.Error(),
+		Err:     err,
+	}
+}
+
+// rateLimiter spaces out the requests of a service evenly. It is safe for concurrent use.
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may start a request, or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	start := l.next
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// slowS3 delays bucket creation and records how many creations overlap
+type slowS3 struct {
+	*fake.S3
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (c *slowS3) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.peak {
+		c.peak = c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return c.S3.CreateBucket(ctx, params, optFns...)
+}
+
+// bucketRequests returns requests creating n buckets
+func bucketRequests(n int) []BulkRequest {
+	requests := make([]BulkRequest, n)
+	for i := range requests {
+		requests[i] = BulkRequest{
+			Service:   S3ServiceName,
+			Operation: OperationCreate,
+			Region:    "us-east-1",
+			Params:    map[string]interface{}{"bucket_name": fmt.Sprintf("bulk-bucket-%02d", i)},
+		}
+	}
+	return requests
+}
+
+func TestRunBulk(t *testing.T) {
+	client := &slowS3{S3: fake.NewS3()}
+	requests := bucketRequests(20)
+	requests[3].Params = map[string]interface{}{"bucket_name": "Invalid_Name"}
+
+	results := RunBulk(context.Background(), requests, BulkOptions{Workers: 4}, WithS3Client(client))
+	if len(results) != len(requests) {
+		t.Fatalf("Expected %d results, got %d", len(requests), len(results))
+	}
+	for i, result := range results {
+		if result.Index != i || result.Request.Params["bucket_name"] != requests[i].Params["bucket_name"] {
+			t.Errorf("Expected result %d in request order, got %+v", i, result)
+		}
+		if result.Result.Success != (i != 3) {
+			t.Errorf("Unexpected outcome of request %d: %+v", i, result.Result)
+		}
+	}
+	if client.peak > 4 {
+		t.Errorf("Expected at most 4 concurrent creations, got %d", client.peak)
+	}
+	if client.peak < 2 {
+		t.Errorf("Expected creations to run concurrently, got a peak of %d", client.peak)
+	}
+}
+
+func TestRunBulkRateLimit(t *testing.T) {
+	requests := bucketRequests(5)
+
+	start := time.Now()
+	results := RunBulk(context.Background(), requests, BulkOptions{Workers: 5, RateLimits: map[string]float64{S3ServiceName: 50}},
+		WithS3Client(fake.NewS3()))
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected 5 requests at 50 per second to take at least 80ms, took %s", elapsed)
+	}
+	for _, result := range results {
+		if !result.Result.Success {
+			t.Errorf("Expected success, got %+v", result.Result)
+		}
+	}
+}
+
+func TestRunBulkCancelled(t *testing.T) {
+	backend := fake.NewS3()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := RunBulk(ctx, bucketRequests(3), BulkOptions{}, WithS3Client(backend))
+	for _, result := range results {
+		if result.Result.Success || result.Result.Error != "Cancelled" {
+			t.Errorf("Expected a cancelled result, got %+v", result.Result)
+		}
+	}
+	if _, ok := backend.Bucket("bulk-bucket-00"); ok {
+		t.Error("Expected no bucket to be created after cancellation")
+	}
+}
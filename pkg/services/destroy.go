@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+)
+
+// DestroyStatus is the outcome of destroying one recorded resource
+type DestroyStatus string
+
+const (
+	DestroyPlanned   DestroyStatus = "planned"
+	DestroyDestroyed DestroyStatus = "destroyed"
+	DestroyGone      DestroyStatus = "already_gone"
+	DestroyFailed    DestroyStatus = "failed"
+	DestroySkipped   DestroyStatus = "skipped"
+)
+
+// DestroyResult reports what Destroy did with a recorded resource
+type DestroyResult struct {
+	Service string        `json:"service"`
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Region  string        `json:"region"`
+	Status  DestroyStatus `json:"status"`
+	Message string        `json:"message"`
+	Error   string        `json:"error,omitempty"`
+	Err     error         `json:"-"`
+}
+
+// DestroyOptions configures Destroy
+type DestroyOptions struct {
+	// DryRun only reports what would be destroyed
+	DryRun bool
+	// Wait waits until terminated instances are gone, up to WaitTimeout
+	Wait        bool
+	WaitTimeout time.Duration
+	// IncludeImported also destroys imported resources, which existed before
+	// the tool and are left alone by default
+	IncludeImported bool
+}
+
+// Destroy deletes the live resources recorded in store that match filter,
+// creating one service per service, account and region with opts. Instances
+// are terminated first, since they may use the buckets, then buckets are
+// emptied of every object version and deleted. Resources already deleted
+// outside this tool are marked deleted in the inventory. Imported resources
+// are only destroyed with options.IncludeImported. Resources recorded in
+// another account are reached with the role set by WithAccountRole, and
+// skipped without it.
+func Destroy(ctx context.Context, store *inventory.Store, filter inventory.Filter, options DestroyOptions, opts ...Option) ([]DestroyResult, error) {
+	filter.IncludeDeleted = false
+	filter.ExcludeImported = !options.IncludeImported
+	records, err := store.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances, buckets []inventory.Record
+	for i := len(records) - 1; i >= 0; i-- {
+		switch records[i].Service {
+		case EC2ServiceName:
+			instances = append(instances, records[i])
+		case S3ServiceName:
+			buckets = append(buckets, records[i])
+		}
+	}
+
+	// The services mark destroyed resources deleted in store
+	scope := newAccountScope(ctx, append(opts[:len(opts):len(opts)], WithInventory(store)))
+
+	results := make([]DestroyResult, 0, len(instances)+len(buckets))
+	if options.DryRun {
+		for _, record := range instances {
+			results = append(results, plannedDestroy(scope, record, fmt.Sprintf("Would terminate instance %s", record.ID)))
+		}
+		for _, record := range buckets {
+			results = append(results, plannedDestroy(scope, record, fmt.Sprintf("Would delete bucket %s and every object version in it", record.ID)))
+		}
+		return results, nil
+	}
+
+	results = append(results, destroyInstances(ctx, store, scope, instances, options)...)
+	return append(results, destroyBuckets(ctx, store, scope, buckets)...), nil
+}
+
+// plannedDestroy reports what a dry run would do with a record
+func plannedDestroy(scope *accountScope, record inventory.Record, message string) DestroyResult {
+	if _, err := scope.optionsFor(record); err != nil {
+		return destroySkipped(record, err)
+	}
+	return newDestroyResult(record, DestroyPlanned, message)
+}
+
+// newDestroyResult starts the result of a record
+func newDestroyResult(record inventory.Record, status DestroyStatus, message string) DestroyResult {
+	return DestroyResult{
+		Service: record.Service,
+		Type:    record.Type,
+		ID:      record.ID,
+		Region:  record.Region,
+		Status:  status,
+		Message: message,
+	}
+}
+
+// destroyOutcome converts the result of a delete operation
+func destroyOutcome(store *inventory.Store, record inventory.Record, result *ResourceResult) DestroyResult {
+	switch {
+	case result.Success:
+		return newDestroyResult(record, DestroyDestroyed, result.Message)
+	case errors.Is(result.Err, ErrNotFound):
+		deleteRecord(store, map[string]interface{}{}, record.Service, record.ID)
+		return newDestroyResult(record, DestroyGone, "Already deleted outside this tool")
+	}
+
+	failed := newDestroyResult(record, DestroyFailed, result.Message)
+	failed.Error = result.Message
+	failed.Err = result.Err
+	return failed
+}
+
+// destroyFailure builds the result of a record whose service cannot be created
+func destroyFailure(record inventory.Record, err error) DestroyResult {
+	failed := newDestroyResult(record, DestroyFailed, err.Error())
+	failed.Error = err.Error()
+	failed.Err = err
+	return failed
+}
+
+// destroySkipped builds the result of a record whose account cannot be
+// reached. The record is kept, since the resource may still exist.
+func destroySkipped(record inventory.Record, err error) DestroyResult {
+	skipped := newDestroyResult(record, DestroySkipped, "Skipped, "+err.Error())
+	skipped.Error = err.Error()
+	skipped.Err = err
+	return skipped
+}
+
+// destroyInstances terminates instances one by one, so one missing instance
+// does not fail the others, then waits for each region at once
+func destroyInstances(ctx context.Context, store *inventory.Store, scope *accountScope, records []inventory.Record, options DestroyOptions) []DestroyResult {
+	results := make([]DestroyResult, len(records))
+	services := make(map[string]*EC2Service)
+	terminated := make(map[string][]int)
+
+	for i, record := range records {
+		opts, err := scope.optionsFor(record)
+		if err != nil {
+			results[i] = destroySkipped(record, err)
+			continue
+		}
+
+		key := scope.key(record)
+		svc, ok := services[key]
+		if !ok {
+			if svc, err = NewEC2Service(record.Region, opts...); err != nil {
+				results[i] = destroyFailure(record, err)
+				continue
+			}
+			services[key] = svc
+		}
+
+		result, _ := svc.DeleteResource(ctx, map[string]interface{}{"instance_id": []string{record.ID}})
+		results[i] = destroyOutcome(store, record, result)
+		if results[i].Status == DestroyDestroyed {
+			terminated[key] = append(terminated[key], i)
+		}
+	}
+
+	if !options.Wait {
+		return results
+	}
+
+	timeout := options.WaitTimeout
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	for key, indexes := range terminated {
+		ids := make([]string, len(indexes))
+		for j, i := range indexes {
+			ids[j] = results[i].ID
+		}
+
+		region := results[indexes[0]].Region
+		client, err := services[key].clientFor(ctx, region)
+		if err == nil {
+			err = waitForTermination(ctx, client, ids, timeout)
+		}
+		if err != nil {
+			for _, i := range indexes {
+				results[i].Status = DestroyFailed
+				results[i].Message = fmt.Sprintf("Termination requested but not complete within %s", timeout)
+				results[i].Error = err.Error()
+				results[i].Err = err
+			}
+		}
+	}
+	return results
+}
+
+// destroyBuckets empties and deletes buckets
+func destroyBuckets(ctx context.Context, store *inventory.Store, scope *accountScope, records []inventory.Record) []DestroyResult {
+	results := make([]DestroyResult, len(records))
+	services := make(map[string]*S3Service)
+
+	for i, record := range records {
+		opts, err := scope.optionsFor(record)
+		if err != nil {
+			results[i] = destroySkipped(record, err)
+			continue
+		}
+
+		key := scope.key(record)
+		svc, ok := services[key]
+		if !ok {
+			if svc, err = NewS3Service(record.Region, opts...); err != nil {
+				results[i] = destroyFailure(record, err)
+				continue
+			}
+			services[key] = svc
+		}
+
+		result, _ := svc.DeleteResource(ctx, map[string]interface{}{"bucket_name": record.ID, "force": true})
+		results[i] = destroyOutcome(store, record, result)
+	}
+	return results
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestDestroy(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	s3Backend := fake.NewS3()
+	ec2Backend := fake.NewEC2("us-east-1")
+	opts := []Option{WithS3Client(s3Backend), WithEC2Client(ec2Backend), WithInventory(store)}
+
+	s3Service, _ := NewS3Service("us-east-1", append(opts, WithSession("first"))...)
+	ec2Service, _ := NewEC2Service("us-east-1", append(opts, WithSession("first"))...)
+	s3Service.CreateResource(ctx, map[string]interface{}{"bucket_name": "data-bucket", "versioning": true})
+	s3Service.CreateResource(ctx, map[string]interface{}{"bucket_name": "gone-bucket"})
+	ec2Service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+	})
+	s3Backend.AddObjectVersion("data-bucket", "report.csv", false)
+	s3Backend.AddObjectVersion("data-bucket", "report.csv", true)
+
+	other, _ := NewS3Service("us-east-1", append(opts, WithSession("second"))...)
+	other.CreateResource(ctx, map[string]interface{}{"bucket_name": "other-bucket"})
+
+	// Deleted by hand, outside the tool
+	s3Backend.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String("gone-bucket")})
+
+	filter := inventory.Filter{Session: "first"}
+	results, err := Destroy(ctx, store, filter, DestroyOptions{DryRun: true}, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 3 || results[0].Service != EC2ServiceName || results[0].Status != DestroyPlanned {
+		t.Fatalf("Expected the instance first and 3 planned results, got %+v", results)
+	}
+	if _, ok := s3Backend.Bucket("data-bucket"); !ok {
+		t.Fatal("Expected a dry run to delete nothing")
+	}
+
+	results, err = Destroy(ctx, store, filter, DestroyOptions{Wait: true}, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	statuses := map[string]DestroyStatus{}
+	for _, result := range results {
+		statuses[result.ID] = result.Status
+	}
+	if statuses["data-bucket"] != DestroyDestroyed || statuses["gone-bucket"] != DestroyGone {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if instance := ec2Backend.Instances()[0]; instance.State.Name != "terminated" {
+		t.Errorf("Expected the instance to be terminated, got %s", instance.State.Name)
+	}
+	if _, ok := s3Backend.Bucket("other-bucket"); !ok {
+		t.Error("Expected resources of other sessions to be kept")
+	}
+
+	live, _ := store.List(inventory.Filter{})
+	if len(live) != 1 || live[0].ID != "other-bucket" {
+		t.Errorf("Expected only other-bucket left in the inventory, got %+v", live)
+	}
+}
+
+func TestDestroyLeavesImportedResources(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+
+	existing, _ := NewS3Service("us-east-1", WithS3Client(backend))
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "legacy-bucket"})
+	if _, err := Import(ctx, store, S3ServiceName, "us-east-1", ImportQuery{NamePattern: "legacy-*"}, ImportOptions{}, WithS3Client(backend)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	created, _ := NewS3Service("us-east-1", WithS3Client(backend), WithInventory(store))
+	created.CreateResource(ctx, map[string]interface{}{"bucket_name": "new-bucket"})
+
+	results, err := Destroy(ctx, store, inventory.Filter{Service: S3ServiceName}, DestroyOptions{}, WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "new-bucket" || results[0].Status != DestroyDestroyed {
+		t.Fatalf("Expected only new-bucket to be destroyed, got %+v", results)
+	}
+	if _, ok := backend.Bucket("legacy-bucket"); !ok {
+		t.Fatal("Expected the imported bucket to survive")
+	}
+
+	results, _ = Destroy(ctx, store, inventory.Filter{Service: S3ServiceName}, DestroyOptions{IncludeImported: true}, WithS3Client(backend))
+	if len(results) != 1 || results[0].ID != "legacy-bucket" || results[0].Status != DestroyDestroyed {
+		t.Errorf("Expected the imported bucket to be destroyed on request, got %+v", results)
+	}
+}
+
+func TestDestroyLeavesBucketsThatAlreadyExisted(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+
+	// The bucket predates the tool; a create finds it already owned
+	existing, _ := NewS3Service("us-east-1", WithS3Client(backend))
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "precious-bucket"})
+	backend.AddObjectVersion("precious-bucket", "data.csv", false)
+
+	created, _ := NewS3Service("us-east-1", WithS3Client(backend), WithInventory(store))
+	result, _ := created.CreateResource(ctx, map[string]interface{}{"bucket_name": "precious-bucket"})
+	if !result.Success || result.Data["already_existed"] != true {
+		t.Fatalf("Expected the bucket to be found already owned, got %+v", result)
+	}
+	if record, _ := store.Get(S3ServiceName, "precious-bucket"); !record.Imported {
+		t.Errorf("Expected the bucket to be recorded as imported, got %+v", record)
+	}
+
+	results, err := Destroy(ctx, store, inventory.Filter{Service: S3ServiceName}, DestroyOptions{}, WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected nothing to be destroyed, got %+v", results)
+	}
+	if bucket, ok := backend.Bucket("precious-bucket"); !ok || len(bucket.Objects) != 1 {
+		t.Errorf("Expected the bucket and its objects to survive, got %+v", bucket)
+	}
+}
+
+func TestDestroyInOtherAccounts(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+	provider := awsconfig.NewProvider().ForAccount(awsconfig.Account{ID: "111111111111"})
+	opts := []Option{WithProvider(provider), WithS3Client(backend)}
+
+	// Created with the credentials of another account
+	backend.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String("foreign-bucket")})
+	store.Put(inventory.Record{Service: S3ServiceName, Type: ResourceTypeBucket, ID: "foreign-bucket", Account: "222222222222", Region: "us-east-1"})
+
+	results, err := Destroy(ctx, store, inventory.Filter{Service: S3ServiceName}, DestroyOptions{}, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Status != DestroySkipped {
+		t.Fatalf("Expected the bucket of another account to be skipped, got %+v", results)
+	}
+	if _, ok := backend.Bucket("foreign-bucket"); !ok {
+		t.Fatal("Expected the bucket of another account to survive")
+	}
+	if record, err := store.Get(S3ServiceName, "foreign-bucket"); err != nil || record.Deleted() {
+		t.Fatalf("Expected the record to be kept, got %+v, %v", record, err)
+	}
+
+	results, _ = Destroy(ctx, store, inventory.Filter{Service: S3ServiceName}, DestroyOptions{}, append(opts, WithAccountRole("OrganizationAccountAccessRole"))...)
+	if len(results) != 1 || results[0].Status != DestroyDestroyed {
+		t.Fatalf("Expected the bucket to be destroyed through the account role, got %+v", results)
+	}
+	if record, _ := store.Get(S3ServiceName, "foreign-bucket"); !record.Deleted() || record.Account != "222222222222" {
+		t.Errorf("Expected the record of the other account to be marked deleted, got %+v", record)
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// DriftStatus summarises how a live resource compares to its recorded configuration
+type DriftStatus string
+
+const (
+	DriftInSync   DriftStatus = "in_sync"
+	DriftDetected DriftStatus = "drifted"
+	DriftMissing  DriftStatus = "missing"
+	DriftError    DriftStatus = "error"
+	DriftSkipped  DriftStatus = "skipped"
+)
+
+// Difference is a setting whose live value differs from the recorded one.
+// A nil Desired or Actual means the setting is absent on that side.
+type Difference struct {
+	Field   string      `json:"field"`
+	Desired interface{} `json:"desired"`
+	Actual  interface{} `json:"actual"`
+}
+
+// DriftReport is the result of comparing a resource with its inventory record
+type DriftReport struct {
+	Service     string       `json:"service"`
+	Type        string       `json:"type"`
+	ID          string       `json:"id"`
+	Region      string       `json:"region"`
+	Status      DriftStatus  `json:"status"`
+	Differences []Difference `json:"differences,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	CheckedAt   time.Time    `json:"checked_at"`
+	Err         error        `json:"-"`
+}
+
+// DriftChecker is implemented by services that can compare a resource with
+// the configuration recorded when it was created
+type DriftChecker interface {
+	CheckDrift(ctx context.Context, record inventory.Record) DriftReport
+}
+
+var (
+	_ DriftChecker = (*S3Service)(nil)
+	_ DriftChecker = (*EC2Service)(nil)
+)
+
+// newDriftReport starts a report for a record
+func newDriftReport(record inventory.Record) DriftReport {
+	return DriftReport{
+		Service:   record.Service,
+		Type:      record.Type,
+		ID:        record.ID,
+		Region:    record.Region,
+		Status:    DriftInSync,
+		CheckedAt: time.Now().UTC(),
+	}
+}
+
+// fail marks the report as failed with err, or as missing when the resource does not exist
+func (r DriftReport) fail(err error) DriftReport {
+	awsErr := ClassifyError(err)
+	if awsErr.Kind == KindNotFound {
+		r.Status = DriftMissing
+		return r
+	}
+	r.Status = DriftError
+	r.Error = awsErr.Error()
+	r.Err = awsErr
+	return r
+}
+
+// compare records a difference when desired and actual differ
+func (r *DriftReport) compare(field string, desired, actual interface{}) {
+	if desired != actual {
+		r.Differences = append(r.Differences, Difference{Field: field, Desired: desired, Actual: actual})
+	}
+}
+
+// compareTags records every tag added, removed or changed since creation
+func (r *DriftReport) compareTags(desired, actual map[string]string) {
+	for _, key := range sortedTagKeys(MergeTags(desired, actual)) {
+		desiredValue, inDesired := desired[key]
+		actualValue, inActual := actual[key]
+
+		switch {
+		case inDesired && !inActual:
+			r.Differences = append(r.Differences, Difference{Field: "tags." + key, Desired: desiredValue})
+		case !inDesired && inActual:
+			r.Differences = append(r.Differences, Difference{Field: "tags." + key, Actual: actualValue})
+		case desiredValue != actualValue:
+			r.Differences = append(r.Differences, Difference{Field: "tags." + key, Desired: desiredValue, Actual: actualValue})
+		}
+	}
+}
+
+// finish sets the status from the differences found
+func (r DriftReport) finish() DriftReport {
+	if len(r.Differences) > 0 {
+		r.Status = DriftDetected
+	}
+	return r
+}
+
+// CheckDrift compares a bucket with its recorded versioning, encryption and tags
+func (s *S3Service) CheckDrift(ctx context.Context, record inventory.Record) DriftReport {
+	report := newDriftReport(record)
+
+	client, err := s.clientFor(ctx, record.Region)
+	if err != nil {
+		return report.fail(err)
+	}
+	bucket := aws.String(record.ID)
+
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: bucket}); err != nil {
+		return report.fail(err)
+	}
+
+	if desired, ok := record.Params["versioning"].(bool); ok {
+		versioning, err := client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: bucket})
+		if err != nil {
+			return report.fail(err)
+		}
+		report.compare("versioning", desired, versioning.Status == s3types.BucketVersioningStatusEnabled)
+	}
+
+	if desired, ok := record.Params["encryption"].(bool); ok {
+		encrypted := true
+		if _, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: bucket}); err != nil {
+			if ClassifyError(err).Kind != KindNotFound {
+				return report.fail(err)
+			}
+			encrypted = false
+		}
+		report.compare("encryption", desired, encrypted)
+	}
+
+	actualTags := map[string]string{}
+	tagging, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: bucket})
+	switch {
+	case err == nil:
+		actualTags = tagsFromS3(tagging.TagSet)
+	case ClassifyError(err).Kind != KindNotFound:
+		return report.fail(err)
+	}
+	report.compareTags(record.Tags, actualTags)
+
+	return report.finish()
+}
+
+// CheckDrift compares an instance with its recorded instance type, state and tags
+func (e *EC2Service) CheckDrift(ctx context.Context, record inventory.Record) DriftReport {
+	report := newDriftReport(record)
+
+	client, err := e.clientFor(ctx, record.Region)
+	if err != nil {
+		return report.fail(err)
+	}
+
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{record.ID}})
+	if err != nil {
+		return report.fail(err)
+	}
+
+	var instance *ec2types.Instance
+	for _, reservation := range output.Reservations {
+		for i := range reservation.Instances {
+			instance = &reservation.Instances[i]
+		}
+	}
+	if instance == nil || settledState(stateName(instance.State)) == string(ec2types.InstanceStateNameTerminated) {
+		report.Status = DriftMissing
+		return report
+	}
+
+	if desired, ok := record.Params["instance_type"].(string); ok {
+		report.compare("instance_type", desired, string(instance.InstanceType))
+	}
+	if desired, ok := record.Params["state"].(string); ok {
+		report.compare("state", settledState(desired), settledState(stateName(instance.State)))
+	}
+	report.compareTags(record.Tags, tagsFromEC2(instance.Tags))
+
+	return report.finish()
+}
+
+// settledState maps transitional instance states to the state they lead to
+func settledState(state string) string {
+	switch ec2types.InstanceStateName(state) {
+	case ec2types.InstanceStateNamePending:
+		return string(ec2types.InstanceStateNameRunning)
+	case ec2types.InstanceStateNameStopping:
+		return string(ec2types.InstanceStateNameStopped)
+	case ec2types.InstanceStateNameShuttingDown:
+		return string(ec2types.InstanceStateNameTerminated)
+	}
+	return state
+}
+
+// DetectDrift checks every live inventory record selected by filter,
+// creating one service per service, account and region with opts. Records
+// of another account are checked with the role set by WithAccountRole, and
+// skipped without it.
+func DetectDrift(ctx context.Context, store *inventory.Store, filter inventory.Filter, opts ...Option) ([]DriftReport, error) {
+	records, err := store.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := newAccountScope(ctx, opts)
+	checkers := make(map[string]DriftChecker)
+	reports := make([]DriftReport, 0, len(records))
+	for _, record := range records {
+		opts, err := scope.optionsFor(record)
+		if err != nil {
+			report := newDriftReport(record)
+			report.Status = DriftSkipped
+			report.Error = err.Error()
+			report.Err = err
+			reports = append(reports, report)
+			continue
+		}
+
+		key := record.Service + "/" + scope.key(record)
+		checker, ok := checkers[key]
+		if !ok {
+			checker, err = NewDriftChecker(record.Service, record.Region, opts...)
+			if err != nil {
+				report := newDriftReport(record)
+				report.Status = DriftError
+				report.Error = err.Error()
+				report.Err = err
+				reports = append(reports, report)
+				continue
+			}
+			checkers[key] = checker
+		}
+		reports = append(reports, checker.CheckDrift(ctx, record))
+	}
+	return reports, nil
+}
+
+// NewDriftChecker creates the service that checks drift of a service's resources
+func NewDriftChecker(service, region string, opts ...Option) (DriftChecker, error) {
+	svc, err := NewService(service, region, opts...)
+	if err != nil {
+		return nil, err
+	}
+	checker, ok := svc.(DriftChecker)
+	if !ok {
+		return nil, fmt.Errorf("drift detection is not supported for service %q", service)
+	}
+	return checker, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func openTestInventory(t *testing.T) *inventory.Store {
+	t.Helper()
+
+	store, err := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return store
+}
+
+func TestS3ServiceCheckDrift(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+	service, _ := NewS3Service("us-east-1", WithS3Client(backend), WithInventory(store))
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"bucket_name": "drift-bucket",
+		"versioning":  true,
+		"tags":        "owner=alice",
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful create, got %+v", result)
+	}
+
+	record, _ := store.Get(S3ServiceName, "drift-bucket")
+	if report := service.CheckDrift(ctx, record); report.Status != DriftInSync {
+		t.Fatalf("Expected bucket in sync, got %+v", report)
+	}
+
+	// Change the bucket by hand
+	backend.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket:                  aws.String("drift-bucket"),
+		VersioningConfiguration: &s3types.VersioningConfiguration{Status: s3types.BucketVersioningStatusSuspended},
+	})
+	backend.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String("drift-bucket"),
+		Tagging: &s3types.Tagging{TagSet: []s3types.Tag{{Key: aws.String("owner"), Value: aws.String("bob")}}},
+	})
+
+	report := service.CheckDrift(ctx, record)
+	if report.Status != DriftDetected || len(report.Differences) != 2 {
+		t.Fatalf("Expected two differences, got %+v", report)
+	}
+	if diff := report.Differences[0]; diff.Field != "versioning" || diff.Desired != true || diff.Actual != false {
+		t.Errorf("Unexpected versioning difference: %+v", diff)
+	}
+	if diff := report.Differences[1]; diff.Field != "tags.owner" || diff.Desired != "alice" || diff.Actual != "bob" {
+		t.Errorf("Unexpected tag difference: %+v", diff)
+	}
+
+	backend.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String("drift-bucket")})
+	if report := service.CheckDrift(ctx, record); report.Status != DriftMissing {
+		t.Errorf("Expected missing bucket, got %+v", report)
+	}
+}
+
+func TestDetectDriftEC2(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewEC2("us-east-1")
+	opts := []Option{WithEC2Client(backend), WithInventory(store)}
+	service, _ := NewEC2Service("us-east-1", opts...)
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         2,
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful launch, got %+v", result)
+	}
+	instances := backend.Instances()
+	stopped := aws.ToString(instances[0].InstanceId)
+	terminated := aws.ToString(instances[1].InstanceId)
+
+	backend.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{stopped}})
+	backend.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{stopped},
+		Tags:      []ec2types.Tag{{Key: aws.String("patched"), Value: aws.String("yes")}},
+	})
+	backend.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{terminated}})
+
+	reports, err := DetectDrift(ctx, store, inventory.Filter{}, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+
+	for _, report := range reports {
+		switch report.ID {
+		case stopped:
+			if report.Status != DriftDetected || len(report.Differences) != 2 {
+				t.Errorf("Expected state and tag drift, got %+v", report)
+			}
+		case terminated:
+			if report.Status != DriftMissing {
+				t.Errorf("Expected missing instance, got %+v", report)
+			}
+		}
+	}
+}
+
+func TestDetectDriftSkipsOtherAccounts(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	provider := awsconfig.NewProvider().ForAccount(awsconfig.Account{ID: "111111111111"})
+	opts := []Option{WithProvider(provider), WithS3Client(fake.NewS3())}
+
+	// Not visible with the credentials of this account
+	store.Put(inventory.Record{Service: S3ServiceName, Type: ResourceTypeBucket, ID: "foreign-bucket", Account: "222222222222", Region: "us-east-1"})
+
+	reports, err := DetectDrift(ctx, store, inventory.Filter{}, opts...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(reports) != 1 || reports[0].Status != DriftSkipped {
+		t.Errorf("Expected the bucket of another account to be skipped, not missing, got %+v", reports)
+	}
+}
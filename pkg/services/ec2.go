@@ -2,175 +2,824 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
-	"strconv"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
+// EC2ServiceName is the name under which EC2 parameter schemas are registered
+const EC2ServiceName = "ec2"
+
+// EC2RunInstancesInput holds the parameters of an EC2 create operation
+type EC2RunInstancesInput struct {
+	ImageID      string            `param:"image_id"`
+	InstanceType string            `param:"instance_type"`
+	KeyName      string            `param:"key_name"`
+	Count        int               `param:"count"`
+	Region       string            `param:"region"`
+	Tags         map[string]string `param:"tags"`
+	UserData     string            `param:"user_data"`
+	SubnetID     string            `param:"subnet_id"`
+	// SecurityGroupIDs are the VPC security groups of the instances
+	SecurityGroupIDs []string `param:"security_group_ids"`
+	DryRun           bool     `param:"dry_run"`
+	Wait             bool     `param:"wait"`
+	WaitTimeout      int      `param:"wait_timeout"`
+	ClientToken      string   `param:"client_token"`
+}
+
+// maxUserDataSize is the largest user data EC2 accepts, before base64 encoding
+const maxUserDataSize = 16 * 1024
+
+// launchClockSkew is how much earlier than the start of a RunInstances call
+// an instance must have been launched to be taken for a replayed launch
+const launchClockSkew = time.Minute
+
+// EC2InstanceInput holds the parameters of EC2 operations that target a single instance
+type EC2InstanceInput struct {
+	InstanceID string `param:"instance_id"`
+}
+
+// EC2ListInstancesInput holds the parameters of an EC2 list operation
+type EC2ListInstancesInput struct {
+	State string `param:"state"`
+}
+
+// EC2UpdateInstanceInput holds the parameters of an EC2 update operation
+type EC2UpdateInstanceInput struct {
+	InstanceID   string            `param:"instance_id"`
+	InstanceType string            `param:"instance_type"`
+	State        string            `param:"state"`
+	Tags         map[string]string `param:"tags"`
+	WaitTimeout  int               `param:"wait_timeout"`
+}
+
+// EC2TerminateInstancesInput holds the parameters of an EC2 delete operation
+type EC2TerminateInstancesInput struct {
+	InstanceIDs []string `param:"instance_id"`
+	Wait        bool     `param:"wait"`
+	WaitTimeout int      `param:"wait_timeout"`
+}
+
+var ec2InstanceIDParam = ParamSpec{
+	Name:        "instance_id",
+	Type:        ParamString,
+	Required:    true,
+	Description: "ID of the instance",
+}
+
+func init() {
+	RegisterSchema(ParamSchema{
+		Service:   EC2ServiceName,
+		Operation: OperationCreate,
+		Params: []ParamSpec{
+			{Name: "image_id", Type: ParamString, Required: true, Description: "AMI used to launch the instances"},
+			{Name: "instance_type", Type: ParamString, Required: true, Description: "Instance type, e.g. t2.micro"},
+			{Name: "key_name", Type: ParamString, Required: true, Description: "Name of the key pair"},
+			{Name: "count", Type: ParamInt, Default: 1, Description: "Number of instances to launch"},
+			{Name: "region", Type: ParamString, Description: "Region to launch in, defaults to the service region"},
+			{Name: "tags", Type: ParamStringMap, Description: "Tags applied to the instances and their volumes on top of the default tags"},
+			{Name: "user_data", Type: ParamString, Description: "Script or cloud-init data run at boot, base64 encoded by the service"},
+			{Name: "subnet_id", Type: ParamString, Description: "Subnet to launch in, defaults to the default VPC"},
+			{Name: "security_group_ids", Type: ParamStringList, Description: "IDs of the security groups of the instances"},
+			{Name: "dry_run", Type: ParamBool, Default: false, Description: "Check permissions and parameters without launching"},
+			{Name: "wait", Type: ParamBool, Default: false, Description: "Wait until the instances are running and pass status checks"},
+			{Name: "wait_timeout", Type: ParamInt, Default: 300, Description: "Maximum time to wait, in seconds"},
+			{Name: "client_token", Type: ParamString, Description: "Idempotency token, retries with the same token never launch twice; generated when empty"},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   EC2ServiceName,
+		Operation: OperationDescribe,
+		Params:    []ParamSpec{ec2InstanceIDParam},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   EC2ServiceName,
+		Operation: OperationList,
+		Params: []ParamSpec{
+			{
+				Name:        "state",
+				Type:        ParamString,
+				Enum:        []string{"pending", "running", "shutting-down", "terminated", "stopping", "stopped"},
+				Description: "Only list instances in this state",
+			},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   EC2ServiceName,
+		Operation: OperationUpdate,
+		Params: []ParamSpec{
+			ec2InstanceIDParam,
+			{Name: "instance_type", Type: ParamString, Description: "New instance type, the instance is stopped for the change and started again if it was running"},
+			{Name: "state", Type: ParamString, Enum: []string{"running", "stopped"}, Description: "Desired instance state"},
+			{Name: "tags", Type: ParamStringMap, Description: "Tags to add or overwrite, other tags are kept"},
+			{Name: "wait_timeout", Type: ParamInt, Default: 300, Description: "Maximum time to wait for the instance to stop before a type change, in seconds"},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   EC2ServiceName,
+		Operation: OperationDelete,
+		Params: []ParamSpec{
+			{Name: "instance_id", Type: ParamStringList, Required: true, Description: "IDs of the instances to terminate"},
+			{Name: "wait", Type: ParamBool, Default: false, Description: "Wait until the instances are terminated"},
+			{Name: "wait_timeout", Type: ParamInt, Default: 300, Description: "Maximum time to wait, in seconds"},
+		},
+	})
+}
+
 // EC2Service handles EC2 instance operations
 type EC2Service struct {
 	*BaseService
-	client *ec2.Client
+	provider    *awsconfig.Provider
+	client      EC2API
+	defaultTags map[string]string
+	inventory   *inventory.Store
+	session     string
+	progress    ProgressFunc
+	hooks       hooks
+	logger      *slog.Logger
 }
 
+var _ AWSService = (*EC2Service)(nil)
+
 // NewEC2Service creates a new EC2 service instance
-func NewEC2Service(region string) (*EC2Service, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+func NewEC2Service(region string, opts ...Option) (*EC2Service, error) {
+	options := newServiceOptions(opts)
+
+	if options.ec2Client == nil {
+		if _, err := options.provider.Config(options.loadContext(), region); err != nil {
+			return nil, err
+		}
 	}
 
 	return &EC2Service{
 		BaseService: NewBaseService(region),
-		client:      ec2.NewFromConfig(cfg),
+		provider:    options.provider,
+		client:      options.ec2Client,
+		defaultTags: options.defaultTags,
+		inventory:   options.inventory,
+		session:     options.session,
+		progress:    options.progress,
+		hooks:       options.hooks,
+		logger:      options.logger,
 	}, nil
 }
 
-// CreateResource creates EC2 instances
-func (e *EC2Service) CreateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
-	// Extract required parameters
-	imageID, ok := params["image_id"].(string)
-	if !ok {
-		return &ResourceResult{
-			Success: false,
-			Error:   "invalid image_id parameter",
-			Message: "image_id must be a string",
-		}, nil
+// clientFor returns the injected EC2 client, or the cached client for a region
+// defaulting to the service region
+func (e *EC2Service) clientFor(ctx context.Context, region string) (EC2API, error) {
+	if e.client != nil {
+		return e.client, nil
 	}
-
-	instanceType, ok := params["instance_type"].(string)
-	if !ok {
-		return &ResourceResult{
-			Success: false,
-			Error:   "invalid instance_type parameter",
-			Message: "instance_type must be a string",
-		}, nil
+	if region == "" {
+		region = e.Region
 	}
+	return awsconfig.Client(ctx, e.provider, EC2ServiceName, region, func(cfg aws.Config) EC2API {
+		return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+			o.APIOptions = append(o.APIOptions, addRetryProgress, addRequestLogging)
+			o.TracerProvider = telemetry.SDKTracerProvider()
+		})
+	})
+}
 
-	keyName, ok := params["key_name"].(string)
-	if !ok {
-		return &ResourceResult{
-			Success: false,
-			Error:   "invalid key_name parameter",
-			Message: "key_name must be a string",
-		}, nil
-	}
+// CreateResource creates EC2 instances
+func (e *EC2Service) CreateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, e.logger, EC2ServiceName, OperationCreate, e.Region, params)
+	progress := newProgress(e.progress, EC2ServiceName, OperationCreate, e.Region)
+	result, err := e.hooks.create(ctx, EC2ServiceName, e.Region, params, func(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+		return e.create(progress.context(ctx), params, progress)
+	})
+	progress.done(result)
+	span.end(result, err)
+	return result, err
+}
 
-	// Handle count parameter - default to 1
-	count := 1
-	if countParam, ok := params["count"]; ok {
-		switch v := countParam.(type) {
-		case int:
-			count = v
-		case string:
-			if parsed, err := strconv.Atoi(v); err == nil {
-				count = parsed
-			}
-		}
+// create launches EC2 instances, reporting its steps to progress
+func (e *EC2Service) create(ctx context.Context, params map[string]interface{}, progress *progress) (*ResourceResult, error) {
+	progress.report(StageValidating, "Validating parameters")
+	var input EC2RunInstancesInput
+	if err := e.DecodeParams(EC2ServiceName, OperationCreate, params, &input); err != nil {
+		return validationFailure(err), nil
 	}
 
+	imageID := input.ImageID
+	instanceType := input.InstanceType
+	keyName := input.KeyName
+	count := input.Count
+
 	// Validate count
 	if count < 1 {
-		return &ResourceResult{
-			Success: false,
-			Error:   "ValidationError",
-			Message: "Count must be at least 1",
-		}, nil
+		return validationFailure(newValidationError(EC2ServiceName, OperationCreate, "count", "must be at least 1")), nil
 	}
 
 	// Override region if provided in params
 	targetRegion := e.Region
-	if region, ok := params["region"].(string); ok && region != "" {
-		targetRegion = region
+	if input.Region != "" {
+		targetRegion = input.Region
+	}
+	if !ValidRegion(targetRegion) {
+		return validationFailure(newValidationError(EC2ServiceName, OperationCreate, "region",
+			fmt.Sprintf("%q is not a valid AWS region", targetRegion))), nil
+	}
 
-		// Update client configuration for the new region
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(targetRegion))
-		if err != nil {
-			return &ResourceResult{
-				Success: false,
-				Error:   "ConfigurationError",
-				Message: fmt.Sprintf("Failed to configure AWS client for region %s: %s", targetRegion, err.Error()),
-			}, nil
-		}
-		e.client = ec2.NewFromConfig(cfg)
+	tags := MergeTags(e.defaultTags, input.Tags)
+	if err := ValidateTags(tags); err != nil {
+		return validationFailure(newValidationError(EC2ServiceName, OperationCreate, "tags", err.Error())), nil
 	}
 
-	// Validate required parameters
-	if err := e.ValidateRequiredParams(params, []string{"image_id", "instance_type", "key_name"}); err != nil {
-		return &ResourceResult{
-			Success: false,
-			Error:   "ValidationError",
-			Message: err.Error(),
-		}, nil
+	if len(input.UserData) > maxUserDataSize {
+		return validationFailure(newValidationError(EC2ServiceName, OperationCreate, "user_data",
+			fmt.Sprintf("must be at most %d bytes", maxUserDataSize))), nil
+	}
+
+	// Retries with the same token return the original reservation instead of launching again
+	clientToken := input.ClientToken
+	if clientToken == "" {
+		clientToken = NewClientToken()
+	}
+	if len(clientToken) > 64 {
+		return validationFailure(newValidationError(EC2ServiceName, OperationCreate, "client_token", "must be at most 64 characters")), nil
+	}
+
+	client, err := e.clientFor(ctx, targetRegion)
+	if err != nil {
+		return configFailure(targetRegion, err), nil
 	}
 
 	// Create RunInstances input
-	input := &ec2.RunInstancesInput{
+	runInput := &ec2.RunInstancesInput{
 		ImageId:      aws.String(imageID),
 		MinCount:     aws.Int32(int32(count)),
 		MaxCount:     aws.Int32(int32(count)),
 		InstanceType: types.InstanceType(instanceType),
 		KeyName:      aws.String(keyName),
+		ClientToken:  aws.String(clientToken),
+		// Tagging at launch means no instance ever exists untagged
+		TagSpecifications: ec2TagSpecifications(tags),
+	}
+	if input.UserData != "" {
+		runInput.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(input.UserData)))
+	}
+	if input.SubnetID != "" {
+		runInput.SubnetId = aws.String(input.SubnetID)
+	}
+	if len(input.SecurityGroupIDs) > 0 {
+		runInput.SecurityGroupIds = input.SecurityGroupIDs
+	}
+
+	if input.DryRun {
+		progress.report(StageCalling, "Checking permissions to launch %d instance(s)", count)
+		return planInstances(ctx, client, runInput, targetRegion), nil
 	}
 
 	// Launch instances
-	result, err := e.client.RunInstances(ctx, input)
+	progress.report(StageCalling, "Launching %d instance(s) of type %s in %s", count, instanceType, targetRegion)
+	started := time.Now()
+	result, err := client.RunInstances(ctx, runInput)
 	if err != nil {
-		errorMsg := err.Error()
-		errorCode := "UnknownError"
+		return launchFailure(err, "Failed to launch instances", imageID, keyName), nil
+	}
 
-		// Handle specific EC2 errors
-		if containsError(errorMsg, "InvalidAMIID") {
-			return &ResourceResult{
-				Success: false,
-				Error:   "InvalidAMIID",
-				Message: fmt.Sprintf("Invalid AMI ID: %s", imageID),
-			}, nil
+	// Extract instance information
+	instanceIDs := make([]string, len(result.Instances))
+	instances := make([]map[string]interface{}, len(result.Instances))
+	for i, instance := range result.Instances {
+		instanceIDs[i] = aws.ToString(instance.InstanceId)
+		instances[i] = instanceData(instance)
+	}
+
+	var account string
+	if e.inventory != nil {
+		account = accountID(ctx, e.provider, e.client != nil)
+	}
+
+	data := map[string]interface{}{
+		"instances":     instances,
+		"region":        targetRegion,
+		"image_id":      imageID,
+		"instance_type": instanceType,
+		"key_name":      keyName,
+		"count":         count,
+		"client_token":  clientToken,
+	}
+	if len(tags) > 0 {
+		data["tags"] = tags
+	}
+
+	// A token given by the caller may replay an earlier launch, whose
+	// instances are not ours to terminate on rollback
+	if input.ClientToken != "" && e.replayedLaunch(result.Instances, started, account, targetRegion) {
+		data["replayed"] = true
+	} else {
+		register(ctx, Compensation{
+			Service:     EC2ServiceName,
+			Type:        ResourceTypeInstance,
+			ID:          strings.Join(instanceIDs, ","),
+			Region:      targetRegion,
+			Description: fmt.Sprintf("terminate %s", strings.Join(instanceIDs, ", ")),
+			undo: func(ctx context.Context) *ResourceResult {
+				return e.terminate(ctx, client, instanceIDs)
+			},
+		})
+	}
+
+	if e.inventory != nil {
+		records := make([]inventory.Record, len(instanceIDs))
+		for i, instanceID := range instanceIDs {
+			records[i] = inventory.Record{
+				Service: EC2ServiceName,
+				Type:    ResourceTypeInstance,
+				ID:      instanceID,
+				ARN:     instanceARN(targetRegion, account, instanceID),
+				Region:  targetRegion,
+				Account: account,
+				Params: map[string]interface{}{
+					"image_id":      imageID,
+					"instance_type": instanceType,
+					"key_name":      keyName,
+					"region":        targetRegion,
+					"state":         stateName(result.Instances[i].State),
+					"client_token":  clientToken,
+				},
+				Tags:    tags,
+				Session: e.session,
+			}
+			if input.SubnetID != "" {
+				setRecordParam(&records[i], "subnet_id", input.SubnetID)
+			}
+			if len(input.SecurityGroupIDs) > 0 {
+				setRecordParam(&records[i], "security_group_ids", input.SecurityGroupIDs)
+			}
+		}
+		recordResources(e.inventory, data, records...)
+	}
+
+	progress.setResource(strings.Join(instanceIDs, ","))
+
+	if input.Wait {
+		timeout := waitTimeout(input.WaitTimeout)
+		progress.report(StageWaiting, "Waiting up to %s for the instances to run and pass status checks", timeout)
+		ready, err := waitForInstances(ctx, client, instanceIDs, timeout)
+		if ready != nil {
+			data["instances"] = ready
 		}
+		data["ready"] = err == nil
 
-		if containsError(errorMsg, "InvalidKeyPair") {
+		if err != nil {
 			return &ResourceResult{
 				Success: false,
-				Error:   "InvalidKeyPair",
-				Message: fmt.Sprintf("Invalid key pair: %s", keyName),
+				Error:   "ResourceNotReady",
+				Message: fmt.Sprintf("Launched %d EC2 instance(s) in region '%s' but they were not ready within %s: %s",
+					count, targetRegion, timeout, err.Error()),
+				Data: data,
+				Err:  err,
 			}, nil
 		}
 
 		return &ResourceResult{
-			Success: false,
-			Error:   errorCode,
-			Message: fmt.Sprintf("Failed to launch instances: %s", errorMsg),
+			Success: true,
+			Message: fmt.Sprintf("Successfully launched %d EC2 instance(s) in region '%s', all running with status checks passed", count, targetRegion),
+			Data:    data,
 		}, nil
 	}
 
-	// Extract instance information
-	instances := make([]map[string]interface{}, len(result.Instances))
-	for i, instance := range result.Instances {
-		instances[i] = map[string]interface{}{
-			"instance_id":   aws.ToString(instance.InstanceId),
-			"state":         string(instance.State.Name),
-			"image_id":      aws.ToString(instance.ImageId),
-			"instance_type": string(instance.InstanceType),
-			"key_name":      aws.ToString(instance.KeyName),
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Successfully launched %d EC2 instance(s) in region '%s'", count, targetRegion),
+		Data:    data,
+	}, nil
+}
+
+// replayedLaunch reports whether RunInstances returned the instances of an
+// earlier call with the same client token: they are already in the
+// inventory, or were launched well before this call started
+func (e *EC2Service) replayedLaunch(instances []types.Instance, started time.Time, account, region string) bool {
+	for _, instance := range instances {
+		if e.inventory != nil && recorded(e.inventory, EC2ServiceName, account, region, aws.ToString(instance.InstanceId)) {
+			return true
+		}
+		// Launch times have a one second resolution and the clocks may differ
+		if instance.LaunchTime != nil && instance.LaunchTime.Before(started.Add(-launchClockSkew)) {
+			return true
+		}
+	}
+	return false
+}
+
+// launchFailure builds the result of a failed RunInstances call
+func launchFailure(err error, message, imageID, keyName string) *ResourceResult {
+	result := awsFailure(err, message)
+
+	// Give the common EC2 lookup failures a clearer message
+	switch {
+	case strings.HasPrefix(result.Error, "InvalidAMIID"):
+		result.Message = fmt.Sprintf("Invalid AMI ID: %s", imageID)
+	case strings.HasPrefix(result.Error, "InvalidKeyPair"):
+		result.Message = fmt.Sprintf("Invalid key pair: %s", keyName)
+	}
+
+	return result
+}
+
+// planInstances asks EC2 to check a launch with the DryRun flag, which
+// verifies permissions and parameters without starting any instance
+func planInstances(ctx context.Context, client EC2API, runInput *ec2.RunInstancesInput, region string) *ResourceResult {
+	data := dryRunData("create")
+	data["region"] = region
+	data["image_id"] = aws.ToString(runInput.ImageId)
+	data["instance_type"] = string(runInput.InstanceType)
+	data["key_name"] = aws.ToString(runInput.KeyName)
+	data["count"] = int(aws.ToInt32(runInput.MaxCount))
+	if len(runInput.TagSpecifications) > 0 {
+		data["tags"] = tagsFromEC2(runInput.TagSpecifications[0].Tags)
+	}
+
+	dryRunInput := *runInput
+	dryRunInput.DryRun = aws.Bool(true)
+
+	_, err := client.RunInstances(ctx, &dryRunInput)
+	if err == nil {
+		return &ResourceResult{
+			Success: false,
+			Error:   "DryRunNotHonored",
+			Message: "Dry run: EC2 did not report a dry-run outcome, instances may have been launched",
+			Data:    data,
+		}
+	}
+
+	if awsErr := ClassifyError(err); awsErr.Code == "DryRunOperation" {
+		return &ResourceResult{
+			Success: true,
+			Message: fmt.Sprintf("Dry run: %d EC2 instance(s) of type %s would be launched in region '%s'",
+				data["count"], data["instance_type"], region),
+			Data:      data,
+			RequestID: awsErr.RequestID,
+		}
+	}
+
+	result := launchFailure(err, "Dry run failed", aws.ToString(runInput.ImageId), aws.ToString(runInput.KeyName))
+	result.Data = data
+	return result
+}
+
+// DescribeResource returns the current details of a single EC2 instance
+func (e *EC2Service) DescribeResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, e.logger, EC2ServiceName, OperationDescribe, e.Region, params)
+	result, err := e.describe(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// describe reads the details of an instance
+func (e *EC2Service) describe(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input EC2InstanceInput
+	if err := e.DecodeParams(EC2ServiceName, OperationDescribe, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return configFailure(e.Region, err), nil
+	}
+	instanceID := input.InstanceID
+
+	result, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return awsFailure(err, fmt.Sprintf("Failed to describe instance %s", instanceID)), nil
+	}
+
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			return &ResourceResult{
+				Success: true,
+				Message: fmt.Sprintf("Instance %s is %s", instanceID, instanceState(instance)),
+				Data:    instanceData(instance),
+			}, nil
+		}
+	}
+
+	return instanceNotFound(instanceID), nil
+}
+
+// instanceNotFound is the result of an operation on a missing instance
+func instanceNotFound(instanceID string) *ResourceResult {
+	return &ResourceResult{
+		Success: false,
+		Error:   "InstanceNotFound",
+		Message: fmt.Sprintf("Instance %s not found", instanceID),
+		Err: &AWSError{
+			Kind:    KindNotFound,
+			Code:    "InstanceNotFound",
+			Message: fmt.Sprintf("instance %s not found", instanceID),
+		},
+	}
+}
+
+// ListResources lists EC2 instances, optionally filtered by state
+func (e *EC2Service) ListResources(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, e.logger, EC2ServiceName, OperationList, e.Region, params)
+	result, err := e.list(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// list lists the instances matching the filters
+func (e *EC2Service) list(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input EC2ListInstancesInput
+	if err := e.DecodeParams(EC2ServiceName, OperationList, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return configFailure(e.Region, err), nil
+	}
+
+	describeInput := &ec2.DescribeInstancesInput{}
+	if input.State != "" {
+		describeInput.Filters = []types.Filter{{
+			Name:   aws.String("instance-state-name"),
+			Values: []string{input.State},
+		}}
+	}
+
+	var instances []map[string]interface{}
+	paginator := ec2.NewDescribeInstancesPaginator(client, describeInput)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return awsFailure(err, "Failed to list instances"), nil
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instances = append(instances, instanceData(instance))
+			}
 		}
 	}
 
 	return &ResourceResult{
 		Success: true,
-		Message: fmt.Sprintf("Successfully launched %d EC2 instance(s) in region '%s'", count, targetRegion),
+		Message: fmt.Sprintf("Found %d EC2 instance(s) in region '%s'", len(instances), e.Region),
 		Data: map[string]interface{}{
-			"instances":     instances,
-			"region":        targetRegion,
-			"image_id":      imageID,
-			"instance_type": instanceType,
-			"key_name":      keyName,
-			"count":         count,
+			"instances": instances,
+			"region":    e.Region,
+			"count":     len(instances),
 		},
 	}, nil
 }
+
+// UpdateResource changes the instance type, the running state and/or the tags of an EC2 instance.
+// Changing the instance type stops the instance first and waits until it is
+// stopped; it is started again when it was running, unless state is stopped.
+func (e *EC2Service) UpdateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, e.logger, EC2ServiceName, OperationUpdate, e.Region, params)
+	result, err := e.update(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// update applies the requested changes to an instance
+func (e *EC2Service) update(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input EC2UpdateInstanceInput
+	if err := e.DecodeParams(EC2ServiceName, OperationUpdate, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return configFailure(e.Region, err), nil
+	}
+	instanceID := input.InstanceID
+
+	instanceType := input.InstanceType
+	state := input.State
+	if instanceType == "" && state == "" && len(input.Tags) == 0 {
+		return validationFailure(newValidationError(EC2ServiceName, OperationUpdate, "", "at least one of instance_type, state or tags must be provided")), nil
+	}
+	if err := ValidateTags(input.Tags); err != nil {
+		return validationFailure(newValidationError(EC2ServiceName, OperationUpdate, "tags", err.Error())), nil
+	}
+
+	data := map[string]interface{}{
+		"instance_id": instanceID,
+	}
+
+	stopped := false
+	if instanceType != "" {
+		previous, failure := resizeInstance(ctx, client, instanceID, instanceType, waitTimeout(input.WaitTimeout))
+		if failure != nil {
+			return failure, nil
+		}
+		data["instance_type"] = instanceType
+		stopped = true
+		if state == "" && previous == string(types.InstanceStateNameRunning) {
+			state = previous
+		}
+	}
+
+	if len(input.Tags) > 0 {
+		_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags:      ec2Tags(input.Tags),
+		})
+		if err != nil {
+			return awsFailure(err, fmt.Sprintf("Failed to tag instance %s", instanceID)), nil
+		}
+		data["tags"] = input.Tags
+	}
+
+	switch state {
+	case "":
+	case string(types.InstanceStateNameRunning):
+		if _, err := client.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+			return awsFailure(err, fmt.Sprintf("Failed to start instance %s", instanceID)), nil
+		}
+		data["state"] = state
+	case string(types.InstanceStateNameStopped):
+		if !stopped {
+			if _, err := client.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+				return awsFailure(err, fmt.Sprintf("Failed to stop instance %s", instanceID)), nil
+			}
+		}
+		data["state"] = state
+	}
+
+	updateRecord(e.inventory, data, EC2ServiceName, instanceID, func(r *inventory.Record) {
+		if instanceType != "" {
+			setRecordParam(r, "instance_type", instanceType)
+		}
+		if state != "" {
+			setRecordParam(r, "state", state)
+		}
+		if len(input.Tags) > 0 {
+			r.Tags = MergeTags(r.Tags, input.Tags)
+		}
+	})
+
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Successfully updated instance %s", instanceID),
+		Data:    data,
+	}, nil
+}
+
+// resizeInstance changes the type of an instance, which EC2 only allows on
+// stopped instances: the instance is stopped and waited for unless it is
+// already stopped. It returns the state the instance was in before, so
+// the caller can start it again.
+func resizeInstance(ctx context.Context, client EC2API, instanceID, instanceType string, timeout time.Duration) (string, *ResourceResult) {
+	instances, err := describeInstances(ctx, client, []string{instanceID})
+	if err != nil {
+		return "", awsFailure(err, fmt.Sprintf("Failed to describe instance %s", instanceID))
+	}
+	if len(instances) == 0 {
+		return "", instanceNotFound(instanceID)
+	}
+	previous, _ := instances[0]["state"].(string)
+
+	if previous != string(types.InstanceStateNameStopped) {
+		if previous != string(types.InstanceStateNameStopping) {
+			if _, err := client.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+				return "", awsFailure(err, fmt.Sprintf("Failed to stop instance %s", instanceID))
+			}
+		}
+		if err := waitForStopped(ctx, client, []string{instanceID}, timeout); err != nil {
+			return "", &ResourceResult{
+				Success: false,
+				Error:   "ResourceNotReady",
+				Message: fmt.Sprintf("Instance %s did not stop within %s to change its type: %s", instanceID, timeout, err.Error()),
+				Err:     err,
+			}
+		}
+	}
+
+	_, err = client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   aws.String(instanceID),
+		InstanceType: &types.AttributeValue{Value: aws.String(instanceType)},
+	})
+	if err != nil {
+		return "", awsFailure(err, fmt.Sprintf("Failed to change instance type of %s", instanceID))
+	}
+	return previous, nil
+}
+
+// DeleteResource terminates one or more EC2 instances
+func (e *EC2Service) DeleteResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, e.logger, EC2ServiceName, OperationDelete, e.Region, params)
+	progress := newProgress(e.progress, EC2ServiceName, OperationDelete, e.Region)
+	result, err := e.delete(progress.context(ctx), params, progress)
+	progress.done(result)
+	span.end(result, err)
+	return result, err
+}
+
+// delete terminates EC2 instances, reporting its steps to progress
+func (e *EC2Service) delete(ctx context.Context, params map[string]interface{}, progress *progress) (*ResourceResult, error) {
+	progress.report(StageValidating, "Validating parameters")
+	var input EC2TerminateInstancesInput
+	if err := e.DecodeParams(EC2ServiceName, OperationDelete, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+	progress.setResource(strings.Join(input.InstanceIDs, ","))
+
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return configFailure(e.Region, err), nil
+	}
+
+	progress.report(StageCalling, "Terminating %d instance(s)", len(input.InstanceIDs))
+	result := e.terminate(ctx, client, input.InstanceIDs)
+	if !result.Success || !input.Wait {
+		return result, nil
+	}
+
+	timeout := waitTimeout(input.WaitTimeout)
+	progress.report(StageWaiting, "Waiting up to %s for the instances to terminate", timeout)
+	err = waitForTermination(ctx, client, input.InstanceIDs, timeout)
+	result.Data["terminated"] = err == nil
+	if err != nil {
+		return &ResourceResult{
+			Success: false,
+			Error:   "ResourceNotReady",
+			Message: fmt.Sprintf("Requested termination of %d EC2 instance(s) but they were not terminated within %s: %s",
+				len(input.InstanceIDs), timeout, err.Error()),
+			Data: result.Data,
+			Err:  err,
+		}, nil
+	}
+	result.Message = fmt.Sprintf("Successfully terminated %d EC2 instance(s) and waited for termination to complete", len(input.InstanceIDs))
+	return result, nil
+}
+
+// terminate terminates instances and marks their inventory records deleted
+func (e *EC2Service) terminate(ctx context.Context, client EC2API, instanceIDs []string) *ResourceResult {
+	result, err := client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return awsFailure(err, "Failed to terminate instances")
+	}
+
+	changes := make([]map[string]interface{}, len(result.TerminatingInstances))
+	for i, change := range result.TerminatingInstances {
+		changes[i] = map[string]interface{}{
+			"instance_id":    aws.ToString(change.InstanceId),
+			"previous_state": stateName(change.PreviousState),
+			"current_state":  stateName(change.CurrentState),
+		}
+	}
+
+	data := map[string]interface{}{
+		"instances": changes,
+	}
+	for _, instanceID := range instanceIDs {
+		deleteRecord(e.inventory, data, EC2ServiceName, instanceID)
+	}
+
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Successfully terminated %d EC2 instance(s)", len(instanceIDs)),
+		Data:    data,
+	}
+}
+
+// instanceData converts an EC2 instance into the map representation used in results
+func instanceData(instance types.Instance) map[string]interface{} {
+	return map[string]interface{}{
+		"instance_id":   aws.ToString(instance.InstanceId),
+		"state":         instanceState(instance),
+		"image_id":      aws.ToString(instance.ImageId),
+		"instance_type": string(instance.InstanceType),
+		"key_name":      aws.ToString(instance.KeyName),
+		"public_ip":     aws.ToString(instance.PublicIpAddress),
+		"private_ip":    aws.ToString(instance.PrivateIpAddress),
+		"public_dns":    aws.ToString(instance.PublicDnsName),
+		"private_dns":   aws.ToString(instance.PrivateDnsName),
+		"launch_time":   aws.ToTime(instance.LaunchTime),
+		"tags":          tagsFromEC2(instance.Tags),
+	}
+}
+
+// instanceState returns the state name of an instance
+func instanceState(instance types.Instance) string {
+	return stateName(instance.State)
+}
+
+// stateName safely returns the name of an instance state
+func stateName(state *types.InstanceState) string {
+	if state == nil {
+		return ""
+	}
+	return string(state.Name)
+}
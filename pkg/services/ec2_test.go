@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+var _ EC2API = (*fake.EC2)(nil)
+
+func newTestEC2Service(t *testing.T) (*EC2Service, *fake.EC2) {
+	t.Helper()
+
+	backend := fake.NewEC2("us-east-1")
+	service, err := NewEC2Service("us-east-1", WithEC2Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return service, backend
+}
+
+func TestEC2ServiceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestEC2Service(t)
+
+	result, err := service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         "2",
+	})
+	if err != nil || !result.Success {
+		t.Fatalf("Expected successful launch, got %+v (%v)", result, err)
+	}
+
+	instances := backend.Instances()
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(instances))
+	}
+	id := *instances[0].InstanceId
+
+	result, _ = service.DescribeResource(ctx, map[string]interface{}{"instance_id": id})
+	if !result.Success || result.Data["state"] != "running" {
+		t.Errorf("Expected running instance, got %+v", result)
+	}
+
+	result, _ = service.UpdateResource(ctx, map[string]interface{}{"instance_id": id, "state": "stopped"})
+	if !result.Success {
+		t.Fatalf("Expected successful stop, got %+v", result)
+	}
+
+	result, _ = service.ListResources(ctx, map[string]interface{}{"state": "stopped"})
+	if !result.Success || result.Data["count"] != 1 {
+		t.Errorf("Expected one stopped instance, got %+v", result)
+	}
+
+	result, _ = service.UpdateResource(ctx, map[string]interface{}{"instance_id": id, "instance_type": "t3.small"})
+	if !result.Success {
+		t.Errorf("Expected type change on a stopped instance, got %+v", result)
+	}
+
+	result, _ = service.DeleteResource(ctx, map[string]interface{}{"instance_id": []string{id}})
+	if !result.Success {
+		t.Fatalf("Expected successful terminate, got %+v", result)
+	}
+	if instance, _ := backend.Instance(id); instance.State.Name != "shutting-down" {
+		t.Errorf("Expected instance to be shutting down, got %s", instance.State.Name)
+	}
+}
+
+// recordingEC2 records the calls changing instances
+type recordingEC2 struct {
+	*fake.EC2
+	calls []string
+}
+
+func (c *recordingEC2) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	c.calls = append(c.calls, "StopInstances")
+	return c.EC2.StopInstances(ctx, params, optFns...)
+}
+
+func (c *recordingEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	output, err := c.EC2.DescribeInstances(ctx, params, optFns...)
+	if err == nil && len(output.Reservations) > 0 {
+		c.calls = append(c.calls, "DescribeInstances:"+string(output.Reservations[0].Instances[0].State.Name))
+	}
+	return output, err
+}
+
+func (c *recordingEC2) ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	c.calls = append(c.calls, "ModifyInstanceAttribute")
+	return c.EC2.ModifyInstanceAttribute(ctx, params, optFns...)
+}
+
+func (c *recordingEC2) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	c.calls = append(c.calls, "StartInstances")
+	return c.EC2.StartInstances(ctx, params, optFns...)
+}
+
+func TestEC2UpdateStopsBeforeChangingType(t *testing.T) {
+	ctx := context.Background()
+	backend := &recordingEC2{EC2: fake.NewEC2("us-east-1")}
+	service, _ := NewEC2Service("us-east-1", WithEC2Client(backend))
+
+	service.CreateResource(ctx, map[string]interface{}{"image_id": "ami-12345678", "instance_type": "t2.micro", "key_name": "my-key"})
+	id := *backend.Instances()[0].InstanceId
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{
+			"running instance is started again",
+			map[string]interface{}{"instance_id": id, "instance_type": "t3.small"},
+			"DescribeInstances:running StopInstances DescribeInstances:stopped ModifyInstanceAttribute StartInstances",
+		},
+		{
+			"stop requested",
+			map[string]interface{}{"instance_id": id, "instance_type": "t3.medium", "state": "stopped"},
+			"DescribeInstances:running StopInstances DescribeInstances:stopped ModifyInstanceAttribute",
+		},
+		{
+			"stopped instance stays stopped",
+			map[string]interface{}{"instance_id": id, "instance_type": "t3.large"},
+			"DescribeInstances:stopped ModifyInstanceAttribute",
+		},
+		{
+			"start requested",
+			map[string]interface{}{"instance_id": id, "instance_type": "t3.micro", "state": "running"},
+			"DescribeInstances:stopped ModifyInstanceAttribute StartInstances",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.calls = nil
+			// Settle the instance from the previous case
+			backend.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{id}})
+			backend.calls = nil
+
+			result, _ := service.UpdateResource(ctx, tt.params)
+			if !result.Success {
+				t.Fatalf("Expected success, got %+v", result)
+			}
+			if got := strings.Join(backend.calls, " "); got != tt.want {
+				t.Errorf("Expected calls %q, got %q", tt.want, got)
+			}
+			if instance, _ := backend.Instance(id); string(instance.InstanceType) != tt.params["instance_type"] {
+				t.Errorf("Expected type %s, got %s", tt.params["instance_type"], instance.InstanceType)
+			}
+		})
+	}
+}
+
+func TestEC2ServiceCreateErrors(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestEC2Service(t)
+	backend.AddKeyPair("my-key")
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "other-key",
+	})
+	if result.Success || result.Error != "InvalidKeyPair.NotFound" || result.Message != "Invalid key pair: other-key" {
+		t.Errorf("Expected invalid key pair, got %+v", result)
+	}
+
+	backend.InjectError("RunInstances", fake.APIError("EC2", "RunInstances", "InstanceLimitExceeded", "limit", http.StatusBadRequest))
+	result, _ = service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+	})
+	if result.Success || !errors.Is(result.Err, ErrQuota) || result.RequestID == "" {
+		t.Errorf("Expected quota error with request id, got %+v", result)
+	}
+
+	result, _ = service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         0,
+	})
+	if result.Success || result.Error != "ValidationError" {
+		t.Errorf("Expected validation error for count 0, got %+v", result)
+	}
+}
+
+func TestEC2ServiceDryRun(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestEC2Service(t)
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         3,
+		"dry_run":       true,
+	})
+	if !result.Success || !IsDryRun(result) || result.Data["count"] != 3 {
+		t.Errorf("Expected successful dry run, got %+v", result)
+	}
+	if got := len(backend.Instances()); got != 0 {
+		t.Errorf("Expected no instances after a dry run, got %d", got)
+	}
+
+	backend.InjectError("RunInstances", fake.APIError("EC2", "RunInstances", "UnauthorizedOperation", "not allowed", http.StatusForbidden))
+	result, _ = service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"dry_run":       true,
+	})
+	if result.Success || !IsDryRun(result) || !errors.Is(result.Err, ErrAuth) {
+		t.Errorf("Expected auth failure from dry run, got %+v", result)
+	}
+}
+
+func TestEC2ServiceWait(t *testing.T) {
+	service, _ := newTestEC2Service(t)
+
+	result, _ := service.CreateResource(context.Background(), map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"wait":          true,
+		"wait_timeout":  5,
+	})
+	if !result.Success || result.Data["ready"] != true {
+		t.Fatalf("Expected ready instances, got %+v", result)
+	}
+
+	instances := result.Data["instances"].([]map[string]interface{})
+	if instances[0]["state"] != "running" || instances[0]["public_ip"] == "" || instances[0]["public_dns"] == "" {
+		t.Errorf("Expected running instance with public address, got %+v", instances[0])
+	}
+}
+
+func TestEC2ServiceWaitNotReady(t *testing.T) {
+	service, backend := newTestEC2Service(t)
+	backend.InjectError("DescribeInstances", fake.APIError("EC2", "DescribeInstances", "UnauthorizedOperation", "denied", http.StatusForbidden))
+
+	result, _ := service.CreateResource(context.Background(), map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"wait":          true,
+	})
+	if result.Success || result.Error != "ResourceNotReady" || !errors.Is(result.Err, ErrNotReady) {
+		t.Fatalf("Expected not ready result, got %+v", result)
+	}
+	if instances := result.Data["instances"].([]map[string]interface{}); len(instances) != 1 {
+		t.Errorf("Expected the launched instance to be reported, got %+v", instances)
+	}
+}
+
+func TestEC2ServiceClientToken(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestEC2Service(t)
+	params := map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"client_token":  "launch-1",
+	}
+
+	first, _ := service.CreateResource(ctx, params)
+	second, _ := service.CreateResource(ctx, params)
+	if !first.Success || !second.Success {
+		t.Fatalf("Expected both launches to succeed, got %+v and %+v", first, second)
+	}
+	if got := len(backend.Instances()); got != 1 {
+		t.Errorf("Expected a single instance for a repeated token, got %d", got)
+	}
+	if second.Data["client_token"] != "launch-1" {
+		t.Errorf("Expected client token in result, got %v", second.Data["client_token"])
+	}
+
+	params["count"] = 2
+	result, _ := service.CreateResource(ctx, params)
+	if result.Success || !errors.Is(result.Err, ErrConflict) {
+		t.Errorf("Expected mismatch conflict for a reused token, got %+v", result)
+	}
+
+	delete(params, "client_token")
+	result, _ = service.CreateResource(ctx, params)
+	if token, _ := result.Data["client_token"].(string); !result.Success || token == "" {
+		t.Errorf("Expected a generated client token, got %+v", result)
+	}
+}
+
+func TestEC2ServiceNetworkSettings(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestEC2Service(t)
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"image_id":           "ami-12345678",
+		"instance_type":      "t2.micro",
+		"key_name":           "my-key",
+		"user_data":          "#!/bin/sh\necho hello\n",
+		"subnet_id":          "subnet-12345678",
+		"security_group_ids": "sg-1, sg-2",
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful launch, got %+v", result)
+	}
+
+	instance := backend.Instances()[0]
+	if aws.ToString(instance.SubnetId) != "subnet-12345678" {
+		t.Errorf("Expected subnet-12345678, got %s", aws.ToString(instance.SubnetId))
+	}
+	if len(instance.SecurityGroups) != 2 || aws.ToString(instance.SecurityGroups[1].GroupId) != "sg-2" {
+		t.Errorf("Expected security groups sg-1 and sg-2, got %+v", instance.SecurityGroups)
+	}
+
+	result, _ = service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"user_data":     strings.Repeat("x", maxUserDataSize+1),
+	})
+	if result.Success || result.Error != "ValidationError" {
+		t.Errorf("Expected oversized user data to be rejected, got %+v", result)
+	}
+}
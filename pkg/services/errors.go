@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorKind classifies a failed AWS operation
+type ErrorKind string
+
+const (
+	KindNotFound    ErrorKind = "NotFound"
+	KindConflict    ErrorKind = "Conflict"
+	KindThrottled   ErrorKind = "Throttled"
+	KindAuth        ErrorKind = "Auth"
+	KindValidation  ErrorKind = "Validation"
+	KindQuota       ErrorKind = "Quota"
+	KindUnavailable ErrorKind = "Unavailable"
+	KindUnknown     ErrorKind = "Unknown"
+)
+
+// Sentinel errors matched by errors.Is against an *AWSError of the same kind
+var (
+	ErrNotFound    = errors.New("resource not found")
+	ErrConflict    = errors.New("resource conflict")
+	ErrThrottled   = errors.New("request throttled")
+	ErrAuth        = errors.New("authentication or authorization failure")
+	ErrValidation  = errors.New("invalid request")
+	ErrQuota       = errors.New("service quota exceeded")
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+var kindSentinels = map[ErrorKind]error{
+	KindNotFound:    ErrNotFound,
+	KindConflict:    ErrConflict,
+	KindThrottled:   ErrThrottled,
+	KindAuth:        ErrAuth,
+	KindValidation:  ErrValidation,
+	KindQuota:       ErrQuota,
+	KindUnavailable: ErrUnavailable,
+}
+
+// AWSError is a classified failure returned by an AWS API call
+type AWSError struct {
+	Kind       ErrorKind
+	Code       string
+	Message    string
+	Operation  string
+	StatusCode int
+	RequestID  string
+	Err        error
+}
+
+// Error implements the error interface
+func (e *AWSError) Error() string {
+	var s strings.Builder
+	if e.Operation != "" {
+		s.WriteString(e.Operation + ": ")
+	}
+	if e.Code != "" {
+		s.WriteString(e.Code + ": ")
+	}
+	s.WriteString(e.Message)
+	if e.RequestID != "" {
+		s.WriteString(fmt.Sprintf(" (request id: %s)", e.RequestID))
+	}
+	return s.String()
+}
+
+// Unwrap returns the underlying SDK error
+func (e *AWSError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error of this error's kind
+func (e *AWSError) Is(target error) bool {
+	sentinel, ok := kindSentinels[e.Kind]
+	return ok && sentinel == target
+}
+
+// Is makes parameter validation failures match ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ResultCode returns the code reported in ResourceResult.Error
+func (e *AWSError) ResultCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	if e.Kind == KindUnknown {
+		return "UnknownError"
+	}
+	return string(e.Kind)
+}
+
+var errorCodeKinds = map[string]ErrorKind{
+	// Not found
+	"NoSuchBucket":      KindNotFound,
+	"NoSuchKey":         KindNotFound,
+	"NotFound":          KindNotFound,
+	"NoSuchTagSet":      KindNotFound,
+	"NoSuchTagSetError": KindNotFound,
+	"ServerSideEncryptionConfigurationNotFoundError": KindNotFound,
+
+	// Conflict
+	"BucketAlreadyExists":         KindConflict,
+	"BucketAlreadyOwnedByYou":     KindConflict,
+	"BucketNotEmpty":              KindConflict,
+	"OperationAborted":            KindConflict,
+	"IncorrectInstanceState":      KindConflict,
+	"IncorrectState":              KindConflict,
+	"IdempotentParameterMismatch": KindConflict,
+	"ConcurrentTagAccess":         KindConflict,
+
+	// Throttled
+	"Throttling":                             KindThrottled,
+	"ThrottlingException":                    KindThrottled,
+	"ThrottledException":                     KindThrottled,
+	"RequestThrottled":                       KindThrottled,
+	"RequestThrottledException":              KindThrottled,
+	"RequestLimitExceeded":                   KindThrottled,
+	"SlowDown":                               KindThrottled,
+	"TooManyRequestsException":               KindThrottled,
+	"ProvisionedThroughputExceededException": KindThrottled,
+
+	// Authentication and authorization
+	"AccessDenied":                KindAuth,
+	"AccessDeniedException":       KindAuth,
+	"AllAccessDisabled":           KindAuth,
+	"AuthFailure":                 KindAuth,
+	"UnauthorizedOperation":       KindAuth,
+	"InvalidAccessKeyId":          KindAuth,
+	"InvalidClientTokenId":        KindAuth,
+	"SignatureDoesNotMatch":       KindAuth,
+	"ExpiredToken":                KindAuth,
+	"ExpiredTokenException":       KindAuth,
+	"UnrecognizedClientException": KindAuth,
+	"OptInRequired":               KindAuth,
+
+	// Validation
+	"InvalidParameter":                   KindValidation,
+	"InvalidParameterValue":              KindValidation,
+	"InvalidParameterCombination":        KindValidation,
+	"MissingParameter":                   KindValidation,
+	"InvalidArgument":                    KindValidation,
+	"InvalidRequest":                     KindValidation,
+	"InvalidBucketName":                  KindValidation,
+	"InvalidLocationConstraint":          KindValidation,
+	"IllegalLocationConstraintException": KindValidation,
+	"MalformedXML":                       KindValidation,
+	"ValidationError":                    KindValidation,
+	"ValidationException":                KindValidation,
+	"Unsupported":                        KindValidation,
+
+	// Quota
+	"InstanceLimitExceeded":         KindQuota,
+	"VcpuLimitExceeded":             KindQuota,
+	"MaxSpotInstanceCountExceeded":  KindQuota,
+	"InsufficientInstanceCapacity":  KindQuota,
+	"TooManyBuckets":                KindQuota,
+	"LimitExceeded":                 KindQuota,
+	"LimitExceededException":        KindQuota,
+	"ServiceQuotaExceededException": KindQuota,
+
+	// Unavailable
+	"ServiceUnavailable":          KindUnavailable,
+	"ServiceUnavailableException": KindUnavailable,
+}
+
+// ClassifyError converts an error returned by the AWS SDK into an *AWSError.
+// The kind is derived from the API error code, falling back to the HTTP
+// status code when the code is not recognised.
+func ClassifyError(err error) *AWSError {
+	if err == nil {
+		return nil
+	}
+
+	var awsErr *AWSError
+	if errors.As(err, &awsErr) {
+		return awsErr
+	}
+
+	awsErr = &AWSError{
+		Kind:    KindUnknown,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		awsErr.Operation = opErr.Operation()
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		awsErr.Code = apiErr.ErrorCode()
+		if message := apiErr.ErrorMessage(); message != "" {
+			awsErr.Message = message
+		}
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		awsErr.StatusCode = statusErr.HTTPStatusCode()
+	}
+
+	var requestErr interface{ ServiceRequestID() string }
+	if errors.As(err, &requestErr) {
+		awsErr.RequestID = requestErr.ServiceRequestID()
+	}
+
+	awsErr.Kind = classifyKind(awsErr.Code, awsErr.StatusCode)
+	return awsErr
+}
+
+// classifyKind maps an AWS error code and HTTP status code to an ErrorKind
+func classifyKind(code string, statusCode int) ErrorKind {
+	if kind, ok := errorCodeKinds[code]; ok {
+		return kind
+	}
+
+	switch {
+	case strings.HasSuffix(code, ".NotFound"):
+		return KindNotFound
+	case strings.HasSuffix(code, ".Duplicate"), strings.HasSuffix(code, ".InUse"):
+		return KindConflict
+	case strings.HasSuffix(code, ".Malformed"), strings.HasPrefix(code, "Invalid"):
+		return KindValidation
+	case strings.HasSuffix(code, "LimitExceeded"):
+		return KindQuota
+	case strings.HasPrefix(code, "Throttling"):
+		return KindThrottled
+	}
+
+	switch statusCode {
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict:
+		return KindConflict
+	case http.StatusTooManyRequests:
+		return KindThrottled
+	case http.StatusServiceUnavailable:
+		// S3 answers 503 SlowDown when throttling, matched by code above;
+		// any other 503 is an outage, not a request rate problem
+		return KindUnavailable
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return KindAuth
+	case http.StatusBadRequest:
+		return KindValidation
+	}
+
+	return KindUnknown
+}
+
+// awsFailure builds the result returned when an AWS API call fails
+func awsFailure(err error, message string) *ResourceResult {
+	awsErr := ClassifyError(err)
+	return &ResourceResult{
+		Success:   false,
+		Error:     awsErr.ResultCode(),
+		Message:   fmt.Sprintf("%s: %s", message, awsErr.Message),
+		RequestID: awsErr.RequestID,
+		Err:       awsErr,
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// apiError builds an error shaped like the ones returned by SDK clients
+func apiError(operation, code string, status int) error {
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: operation,
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      &smithy.GenericAPIError{Code: code, Message: "test failure"},
+			},
+			RequestID: "req-123",
+		},
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     ErrorKind
+		sentinel error
+	}{
+		{"bucket exists", apiError("CreateBucket", "BucketAlreadyExists", 409), KindConflict, ErrConflict},
+		{"missing instance", apiError("DescribeInstances", "InvalidInstanceID.NotFound", 400), KindNotFound, ErrNotFound},
+		{"duplicate key pair", apiError("ImportKeyPair", "InvalidKeyPair.Duplicate", 400), KindConflict, ErrConflict},
+		{"malformed ami", apiError("RunInstances", "InvalidAMIID.Malformed", 400), KindValidation, ErrValidation},
+		{"throttled", apiError("RunInstances", "RequestLimitExceeded", 503), KindThrottled, ErrThrottled},
+		{"slow down", apiError("PutObject", "SlowDown", 503), KindThrottled, ErrThrottled},
+		{"throttling prefix", apiError("ListAccounts", "ThrottlingError", 400), KindThrottled, ErrThrottled},
+		{"unavailable", apiError("HeadBucket", "", 503), KindUnavailable, ErrUnavailable},
+		{"unavailable code", apiError("ListBuckets", "ServiceUnavailable", 503), KindUnavailable, ErrUnavailable},
+		{"access denied", apiError("CreateBucket", "AccessDenied", 403), KindAuth, ErrAuth},
+		{"quota", apiError("RunInstances", "InstanceLimitExceeded", 400), KindQuota, ErrQuota},
+		{"status fallback", apiError("HeadBucket", "", 404), KindNotFound, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awsErr := ClassifyError(tt.err)
+			if awsErr.Kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, awsErr.Kind)
+			}
+			if !errors.Is(awsErr, tt.sentinel) {
+				t.Errorf("Expected error to match %v", tt.sentinel)
+			}
+			if awsErr.RequestID != "req-123" {
+				t.Errorf("Expected request id req-123, got %q", awsErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorUnknown(t *testing.T) {
+	awsErr := ClassifyError(errors.New("connection reset"))
+	if awsErr.Kind != KindUnknown {
+		t.Errorf("Expected kind %s, got %s", KindUnknown, awsErr.Kind)
+	}
+	if awsErr.ResultCode() != "UnknownError" {
+		t.Errorf("Expected UnknownError, got %s", awsErr.ResultCode())
+	}
+	if ClassifyError(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+}
+
+func TestAWSFailure(t *testing.T) {
+	result := awsFailure(apiError("CreateBucket", "BucketAlreadyOwnedByYou", 409), "Failed to create bucket")
+
+	if result.Success {
+		t.Error("Expected failed result")
+	}
+	if result.Error != "BucketAlreadyOwnedByYou" {
+		t.Errorf("Expected error code BucketAlreadyOwnedByYou, got %s", result.Error)
+	}
+	if result.RequestID != "req-123" {
+		t.Errorf("Expected request id req-123, got %q", result.RequestID)
+	}
+
+	var awsErr *AWSError
+	if !errors.As(result.Err, &awsErr) || awsErr.Kind != KindConflict {
+		t.Errorf("Expected conflict AWSError, got %v", result.Err)
+	}
+}
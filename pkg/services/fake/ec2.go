@@ -0,0 +1,505 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// EC2 is an in-memory EC2 backend. Instances are launched in the pending
+// state and become running the next time they are described; terminated
+// instances move from shutting-down to terminated the same way.
+type EC2 struct {
+	faults
+
+	mu        sync.Mutex
+	region    string
+	regions   []string
+	nextID    int
+	keyPairs  map[string]bool
+	instances map[string]*types.Instance
+	order     []string
+	tokens    map[string]launch
+}
+
+// launch remembers the request and instances of a RunInstances call made
+// with a client token
+type launch struct {
+	request     string
+	reservation string
+	instanceIDs []string
+}
+
+// NewEC2 creates an empty EC2 backend for a region
+func NewEC2(region string) *EC2 {
+	return &EC2{
+		region:    region,
+		keyPairs:  make(map[string]bool),
+		instances: make(map[string]*types.Instance),
+		tokens:    make(map[string]launch),
+	}
+}
+
+// InjectError makes the next call to operation (e.g. "RunInstances") return err
+func (f *EC2) InjectError(operation string, err error) {
+	f.inject(operation, err)
+}
+
+// AddKeyPair registers a key pair. Once any key pair is registered,
+// launches referencing unknown key pairs fail with InvalidKeyPair.NotFound.
+func (f *EC2) AddKeyPair(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keyPairs[name] = true
+}
+
+// SetRegions sets the regions enabled for the account, returned by
+// DescribeRegions. By default only the region of the backend is enabled.
+func (f *EC2) SetRegions(regions ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.regions = regions
+}
+
+// DescribeRegions implements services.EC2API. It ignores filters.
+func (f *EC2) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	if err := f.next("DescribeRegions"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	regions := f.regions
+	if len(regions) == 0 {
+		regions = []string{f.region}
+	}
+	output := &ec2.DescribeRegionsOutput{}
+	for _, region := range regions {
+		output.Regions = append(output.Regions, types.Region{
+			RegionName:  aws.String(region),
+			Endpoint:    aws.String(fmt.Sprintf("ec2.%s.amazonaws.com", region)),
+			OptInStatus: aws.String("opt-in-not-required"),
+		})
+	}
+	return output, nil
+}
+
+// Instance returns a copy of the state of an instance
+func (f *EC2) Instance(id string) (types.Instance, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	instance, ok := f.instances[id]
+	if !ok {
+		return types.Instance{}, false
+	}
+	return *instance, true
+}
+
+// Instances returns copies of all instances in launch order
+func (f *EC2) Instances() []types.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	instances := make([]types.Instance, 0, len(f.order))
+	for _, id := range f.order {
+		instances = append(instances, *f.instances[id])
+	}
+	return instances
+}
+
+// setState changes the state of an instance
+func setState(instance *types.Instance, name types.InstanceStateName) {
+	codes := map[types.InstanceStateName]int32{
+		types.InstanceStateNamePending:      0,
+		types.InstanceStateNameRunning:      16,
+		types.InstanceStateNameShuttingDown: 32,
+		types.InstanceStateNameTerminated:   48,
+		types.InstanceStateNameStopping:     64,
+		types.InstanceStateNameStopped:      80,
+	}
+	instance.State = &types.InstanceState{Name: name, Code: aws.Int32(codes[name])}
+}
+
+// lookup returns the instances for ids or an InvalidInstanceID.NotFound error
+func (f *EC2) lookup(operation string, ids []string) ([]*types.Instance, error) {
+	instances := make([]*types.Instance, 0, len(ids))
+	for _, id := range ids {
+		instance, ok := f.instances[id]
+		if !ok {
+			return nil, APIError("EC2", operation, "InvalidInstanceID.NotFound",
+				fmt.Sprintf("The instance ID '%s' does not exist", id), http.StatusBadRequest)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// RunInstances implements services.EC2API
+func (f *EC2) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	if err := f.next("RunInstances"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	imageID := aws.ToString(params.ImageId)
+	if !strings.HasPrefix(imageID, "ami-") {
+		return nil, APIError("EC2", "RunInstances", "InvalidAMIID.Malformed",
+			fmt.Sprintf("Invalid id: \"%s\" (expecting \"ami-...\")", imageID), http.StatusBadRequest)
+	}
+
+	keyName := aws.ToString(params.KeyName)
+	if len(f.keyPairs) > 0 && keyName != "" && !f.keyPairs[keyName] {
+		return nil, APIError("EC2", "RunInstances", "InvalidKeyPair.NotFound",
+			fmt.Sprintf("The key pair '%s' does not exist", keyName), http.StatusBadRequest)
+	}
+
+	if aws.ToBool(params.DryRun) {
+		return nil, APIError("EC2", "RunInstances", "DryRunOperation",
+			"Request would have succeeded, but DryRun flag is set.", http.StatusPreconditionFailed)
+	}
+
+	// Requests repeated with the same client token return the original launch
+	token := aws.ToString(params.ClientToken)
+	tags := instanceTags(params.TagSpecifications)
+	pairs := make([]string, len(tags))
+	for i, tag := range tags {
+		pairs[i] = aws.ToString(tag.Key) + "=" + aws.ToString(tag.Value)
+	}
+	request := fmt.Sprintf("%s|%s|%s|%d|%s|%s|%s|%s", imageID, params.InstanceType, keyName, aws.ToInt32(params.MaxCount),
+		strings.Join(pairs, ","), aws.ToString(params.UserData), aws.ToString(params.SubnetId), strings.Join(params.SecurityGroupIds, ","))
+	if previous, ok := f.tokens[token]; ok && token != "" {
+		if previous.request != request {
+			return nil, APIError("EC2", "RunInstances", "IdempotentParameterMismatch",
+				"The client token has already been used with different parameters", http.StatusBadRequest)
+		}
+
+		output := &ec2.RunInstancesOutput{ReservationId: aws.String(previous.reservation)}
+		for _, id := range previous.instanceIDs {
+			output.Instances = append(output.Instances, *f.instances[id])
+		}
+		return output, nil
+	}
+
+	count := int(aws.ToInt32(params.MaxCount))
+	output := &ec2.RunInstancesOutput{ReservationId: aws.String(fmt.Sprintf("r-%017x", f.nextID+1))}
+	for i := 0; i < count; i++ {
+		f.nextID++
+		id := fmt.Sprintf("i-%017x", f.nextID)
+		instance := &types.Instance{
+			InstanceId:       aws.String(id),
+			ImageId:          params.ImageId,
+			InstanceType:     params.InstanceType,
+			KeyName:          params.KeyName,
+			LaunchTime:       aws.Time(time.Now()),
+			PrivateIpAddress: aws.String(fmt.Sprintf("10.0.%d.%d", f.nextID/250, f.nextID%250+4)),
+			PrivateDnsName:   aws.String(fmt.Sprintf("ip-10-0-%d-%d.ec2.internal", f.nextID/250, f.nextID%250+4)),
+			Placement:        &types.Placement{AvailabilityZone: aws.String(f.region + "a")},
+			Tags:             append([]types.Tag{}, tags...),
+			SubnetId:         params.SubnetId,
+		}
+		for _, groupID := range params.SecurityGroupIds {
+			instance.SecurityGroups = append(instance.SecurityGroups, types.GroupIdentifier{GroupId: aws.String(groupID)})
+		}
+		setState(instance, types.InstanceStateNamePending)
+
+		f.instances[id] = instance
+		f.order = append(f.order, id)
+		output.Instances = append(output.Instances, *instance)
+	}
+
+	if token != "" {
+		ids := make([]string, len(output.Instances))
+		for i, instance := range output.Instances {
+			ids[i] = aws.ToString(instance.InstanceId)
+		}
+		f.tokens[token] = launch{request: request, reservation: aws.ToString(output.ReservationId), instanceIDs: ids}
+	}
+
+	return output, nil
+}
+
+// instanceTags returns the tags that TagSpecifications apply to instances
+func instanceTags(specs []types.TagSpecification) []types.Tag {
+	var tags []types.Tag
+	for _, spec := range specs {
+		if spec.ResourceType == types.ResourceTypeInstance {
+			tags = append(tags, spec.Tags...)
+		}
+	}
+	return tags
+}
+
+// DescribeInstances implements services.EC2API. It supports the
+// instance-id, instance-state-name and tag:<key> filters.
+func (f *EC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	if err := f.next("DescribeInstances"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := params.InstanceIds
+	if len(ids) == 0 {
+		ids = append([]string{}, f.order...)
+	}
+	instances, err := f.lookup("DescribeInstances", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeInstancesOutput{}
+	for _, instance := range instances {
+		f.advance(instance)
+		if !matchesFilters(instance, params.Filters) {
+			continue
+		}
+		output.Reservations = append(output.Reservations, types.Reservation{
+			Instances: []types.Instance{*instance},
+		})
+	}
+	return output, nil
+}
+
+// DescribeInstanceStatus implements services.EC2API. Running instances
+// always report passing status checks.
+func (f *EC2) DescribeInstanceStatus(ctx context.Context, params *ec2.DescribeInstanceStatusInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error) {
+	if err := f.next("DescribeInstanceStatus"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := params.InstanceIds
+	if len(ids) == 0 {
+		ids = append([]string{}, f.order...)
+	}
+	instances, err := f.lookup("DescribeInstanceStatus", ids)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeInstanceStatusOutput{}
+	for _, instance := range instances {
+		f.advance(instance)
+		if instance.State.Name != types.InstanceStateNameRunning && !aws.ToBool(params.IncludeAllInstances) {
+			continue
+		}
+
+		status := types.SummaryStatusOk
+		if instance.State.Name != types.InstanceStateNameRunning {
+			status = types.SummaryStatusNotApplicable
+		}
+		output.InstanceStatuses = append(output.InstanceStatuses, types.InstanceStatus{
+			InstanceId:       instance.InstanceId,
+			InstanceState:    instance.State,
+			AvailabilityZone: instance.Placement.AvailabilityZone,
+			InstanceStatus:   &types.InstanceStatusSummary{Status: status},
+			SystemStatus:     &types.InstanceStatusSummary{Status: status},
+		})
+	}
+	return output, nil
+}
+
+// advance moves an instance out of a transitional state
+func (f *EC2) advance(instance *types.Instance) {
+	switch instance.State.Name {
+	case types.InstanceStateNamePending:
+		setState(instance, types.InstanceStateNameRunning)
+		instance.PublicIpAddress = aws.String(fmt.Sprintf("203.0.113.%d", f.nextID%250+1))
+		instance.PublicDnsName = aws.String(fmt.Sprintf("ec2-203-0-113-%d.compute-1.amazonaws.com", f.nextID%250+1))
+	case types.InstanceStateNameStopping:
+		setState(instance, types.InstanceStateNameStopped)
+	case types.InstanceStateNameShuttingDown:
+		setState(instance, types.InstanceStateNameTerminated)
+	}
+}
+
+// matchesFilters reports whether an instance matches every filter
+func matchesFilters(instance *types.Instance, filters []types.Filter) bool {
+	for _, filter := range filters {
+		var value string
+		switch name := aws.ToString(filter.Name); {
+		case name == "instance-state-name":
+			value = string(instance.State.Name)
+		case name == "instance-id":
+			value = aws.ToString(instance.InstanceId)
+		case strings.HasPrefix(name, "tag:"):
+			found := false
+			for _, tag := range instance.Tags {
+				if aws.ToString(tag.Key) == strings.TrimPrefix(name, "tag:") {
+					value, found = aws.ToString(tag.Value), true
+				}
+			}
+			if !found {
+				return false
+			}
+		default:
+			return false
+		}
+
+		// Values may use the * and ? wildcards, like EC2 filters
+		matched := false
+		for _, candidate := range filter.Values {
+			if ok, _ := path.Match(candidate, value); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ModifyInstanceAttribute implements services.EC2API. Only instance type
+// changes are supported and they require a stopped instance.
+func (f *EC2) ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	if err := f.next("ModifyInstanceAttribute"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances, err := f.lookup("ModifyInstanceAttribute", []string{aws.ToString(params.InstanceId)})
+	if err != nil {
+		return nil, err
+	}
+
+	instance := instances[0]
+	if params.InstanceType != nil {
+		if instance.State.Name != types.InstanceStateNameStopped {
+			return nil, APIError("EC2", "ModifyInstanceAttribute", "IncorrectInstanceState",
+				fmt.Sprintf("The instance '%s' is not in the 'stopped' state.", aws.ToString(instance.InstanceId)), http.StatusBadRequest)
+		}
+		instance.InstanceType = types.InstanceType(aws.ToString(params.InstanceType.Value))
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+// CreateTags implements services.EC2API. Only instance resources are supported.
+func (f *EC2) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	if err := f.next("CreateTags"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances, err := f.lookup("CreateTags", params.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		for _, tag := range params.Tags {
+			replaced := false
+			for i, existing := range instance.Tags {
+				if aws.ToString(existing.Key) == aws.ToString(tag.Key) {
+					instance.Tags[i] = tag
+					replaced = true
+				}
+			}
+			if !replaced {
+				instance.Tags = append(instance.Tags, tag)
+			}
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+// StartInstances implements services.EC2API
+func (f *EC2) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	if err := f.next("StartInstances"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances, err := f.lookup("StartInstances", params.InstanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.StartInstancesOutput{}
+	for _, instance := range instances {
+		previous := *instance.State
+		setState(instance, types.InstanceStateNamePending)
+		output.StartingInstances = append(output.StartingInstances, types.InstanceStateChange{
+			InstanceId:    instance.InstanceId,
+			PreviousState: &previous,
+			CurrentState:  instance.State,
+		})
+	}
+	return output, nil
+}
+
+// StopInstances implements services.EC2API
+func (f *EC2) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	if err := f.next("StopInstances"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances, err := f.lookup("StopInstances", params.InstanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.StopInstancesOutput{}
+	for _, instance := range instances {
+		previous := *instance.State
+		setState(instance, types.InstanceStateNameStopping)
+		output.StoppingInstances = append(output.StoppingInstances, types.InstanceStateChange{
+			InstanceId:    instance.InstanceId,
+			PreviousState: &previous,
+			CurrentState:  instance.State,
+		})
+	}
+	return output, nil
+}
+
+// TerminateInstances implements services.EC2API
+func (f *EC2) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	if err := f.next("TerminateInstances"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances, err := f.lookup("TerminateInstances", params.InstanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.TerminateInstancesOutput{}
+	for _, instance := range instances {
+		previous := *instance.State
+		if instance.State.Name != types.InstanceStateNameTerminated {
+			setState(instance, types.InstanceStateNameShuttingDown)
+		}
+		output.TerminatingInstances = append(output.TerminatingInstances, types.InstanceStateChange{
+			InstanceId:    instance.InstanceId,
+			PreviousState: &previous,
+			CurrentState:  instance.State,
+		})
+	}
+	return output, nil
+}
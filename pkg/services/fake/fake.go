@@ -0,0 +1,60 @@
+// Package fake provides stateful in-memory implementations of the AWS APIs
+// used by the services package, so services and the TUI can be tested offline.
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+var requestCounter int64
+
+// APIError builds an error shaped like the ones returned by SDK clients, so
+// it is classified the same way by the services package
+func APIError(service, operation, code, message string, status int) error {
+	return &smithy.OperationError{
+		ServiceID:     service,
+		OperationName: operation,
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      &smithy.GenericAPIError{Code: code, Message: message},
+			},
+			RequestID: fmt.Sprintf("fake-%06d", atomic.AddInt64(&requestCounter, 1)),
+		},
+	}
+}
+
+// faults holds one-shot errors injected per operation
+type faults struct {
+	mu     sync.Mutex
+	errors map[string][]error
+}
+
+// inject queues err to be returned by the next call to operation
+func (f *faults) inject(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.errors == nil {
+		f.errors = make(map[string][]error)
+	}
+	f.errors[operation] = append(f.errors[operation], err)
+}
+
+// next pops the next injected error for operation, if any
+func (f *faults) next(operation string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	queue := f.errors[operation]
+	if len(queue) == 0 {
+		return nil
+	}
+	f.errors[operation] = queue[1:]
+	return queue[0]
+}
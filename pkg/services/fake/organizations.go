@@ -0,0 +1,52 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+// Organizations is an in-memory Organizations backend listing member accounts
+type Organizations struct {
+	faults
+
+	mu       sync.Mutex
+	accounts []types.Account
+}
+
+// NewOrganizations creates an organization without accounts
+func NewOrganizations() *Organizations {
+	return &Organizations{}
+}
+
+// InjectError makes the next call to operation (e.g. "ListAccounts") return err
+func (f *Organizations) InjectError(operation string, err error) {
+	f.inject(operation, err)
+}
+
+// AddAccount adds a member account in the given state, e.g. types.AccountStateActive
+func (f *Organizations) AddAccount(id, name string, state types.AccountState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accounts = append(f.accounts, types.Account{
+		Id:    aws.String(id),
+		Name:  aws.String(name),
+		Arn:   aws.String("arn:aws:organizations::111111111111:account/o-example/" + id),
+		State: state,
+	})
+}
+
+// ListAccounts implements services.OrganizationsAPI. It returns every
+// account in a single page.
+func (f *Organizations) ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	if err := f.next("ListAccounts"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &organizations.ListAccountsOutput{Accounts: append([]types.Account(nil), f.accounts...)}, nil
+}
@@ -0,0 +1,437 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Bucket is the state of a bucket held by the S3 fake
+type Bucket struct {
+	Name         string
+	Region       string
+	CreationDate time.Time
+	Versioning   types.BucketVersioningStatus
+	Encryption   types.ServerSideEncryption
+	Tags         map[string]string
+	// Objects holds every object version and delete marker, oldest first
+	Objects []ObjectVersion
+	// Foreign marks a bucket owned by another account
+	Foreign bool
+}
+
+// ObjectVersion is a version of an object, or a delete marker, in the S3 fake
+type ObjectVersion struct {
+	Key          string
+	VersionID    string
+	DeleteMarker bool
+}
+
+// S3 is an in-memory S3 backend
+type S3 struct {
+	faults
+
+	mu          sync.Mutex
+	buckets     map[string]*Bucket
+	nextVersion int
+}
+
+// NewS3 creates an empty S3 backend
+func NewS3() *S3 {
+	return &S3{
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+// InjectError makes the next call to operation (e.g. "CreateBucket") return err
+func (f *S3) InjectError(operation string, err error) {
+	f.inject(operation, err)
+}
+
+// AddForeignBucket registers a bucket name as owned by another account
+func (f *S3) AddForeignBucket(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buckets[name] = &Bucket{Name: name, Region: "us-east-1", CreationDate: time.Now(), Foreign: true}
+}
+
+// Bucket returns a copy of the state of a bucket
+func (f *S3) Bucket(name string) (Bucket, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, ok := f.buckets[name]
+	if !ok {
+		return Bucket{}, false
+	}
+	return *bucket, true
+}
+
+// lookup returns an owned bucket or a NoSuchBucket error
+func (f *S3) lookup(operation, name string) (*Bucket, error) {
+	bucket, ok := f.buckets[name]
+	if !ok {
+		return nil, APIError("S3", operation, "NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound)
+	}
+	if bucket.Foreign {
+		return nil, APIError("S3", operation, "AccessDenied", "Access Denied", http.StatusForbidden)
+	}
+	return bucket, nil
+}
+
+// CreateBucket implements services.S3API
+func (f *S3) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	if err := f.next("CreateBucket"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Bucket)
+	if existing, ok := f.buckets[name]; ok {
+		if existing.Foreign {
+			return nil, APIError("S3", "CreateBucket", "BucketAlreadyExists", "The requested bucket name is not available", http.StatusConflict)
+		}
+		return nil, APIError("S3", "CreateBucket", "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded", http.StatusConflict)
+	}
+
+	region := "us-east-1"
+	if params.CreateBucketConfiguration != nil && params.CreateBucketConfiguration.LocationConstraint != "" {
+		region = string(params.CreateBucketConfiguration.LocationConstraint)
+	}
+
+	f.buckets[name] = &Bucket{Name: name, Region: region, CreationDate: time.Now()}
+	return &s3.CreateBucketOutput{Location: aws.String("/" + name)}, nil
+}
+
+// HeadBucket implements services.S3API
+func (f *S3) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := f.next("HeadBucket"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, ok := f.buckets[aws.ToString(params.Bucket)]
+	if !ok {
+		return nil, APIError("S3", "HeadBucket", "NotFound", "Not Found", http.StatusNotFound)
+	}
+	if bucket.Foreign {
+		return nil, APIError("S3", "HeadBucket", "Forbidden", "Forbidden", http.StatusForbidden)
+	}
+	return &s3.HeadBucketOutput{BucketRegion: aws.String(bucket.Region)}, nil
+}
+
+// GetBucketLocation implements services.S3API. Buckets in us-east-1 have
+// no location constraint, like in S3.
+func (f *S3) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	if err := f.next("GetBucketLocation"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("GetBucketLocation", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	output := &s3.GetBucketLocationOutput{}
+	if bucket.Region != "us-east-1" {
+		output.LocationConstraint = types.BucketLocationConstraint(bucket.Region)
+	}
+	return output, nil
+}
+
+// ListBuckets implements services.S3API
+func (f *S3) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	if err := f.next("ListBuckets"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := &s3.ListBucketsOutput{}
+	for _, bucket := range f.buckets {
+		if bucket.Foreign || (params.BucketRegion != nil && aws.ToString(params.BucketRegion) != bucket.Region) {
+			continue
+		}
+		output.Buckets = append(output.Buckets, types.Bucket{
+			Name:         aws.String(bucket.Name),
+			BucketRegion: aws.String(bucket.Region),
+			CreationDate: aws.Time(bucket.CreationDate),
+		})
+	}
+	sort.Slice(output.Buckets, func(i, j int) bool {
+		return aws.ToString(output.Buckets[i].Name) < aws.ToString(output.Buckets[j].Name)
+	})
+	return output, nil
+}
+
+// GetBucketVersioning implements services.S3API
+func (f *S3) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	if err := f.next("GetBucketVersioning"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("GetBucketVersioning", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	return &s3.GetBucketVersioningOutput{Status: bucket.Versioning}, nil
+}
+
+// PutBucketVersioning implements services.S3API
+func (f *S3) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	if err := f.next("PutBucketVersioning"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("PutBucketVersioning", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if params.VersioningConfiguration != nil {
+		bucket.Versioning = params.VersioningConfiguration.Status
+	}
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+// GetBucketEncryption implements services.S3API
+func (f *S3) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	if err := f.next("GetBucketEncryption"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("GetBucketEncryption", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if bucket.Encryption == "" {
+		return nil, APIError("S3", "GetBucketEncryption", "ServerSideEncryptionConfigurationNotFoundError",
+			"The server side encryption configuration was not found", http.StatusNotFound)
+	}
+
+	return &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+			Rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{SSEAlgorithm: bucket.Encryption},
+			}},
+		},
+	}, nil
+}
+
+// PutBucketEncryption implements services.S3API
+func (f *S3) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	if err := f.next("PutBucketEncryption"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("PutBucketEncryption", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	bucket.Encryption = types.ServerSideEncryptionAes256
+	if config := params.ServerSideEncryptionConfiguration; config != nil && len(config.Rules) > 0 &&
+		config.Rules[0].ApplyServerSideEncryptionByDefault != nil {
+		bucket.Encryption = config.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm
+	}
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+// DeleteBucketEncryption implements services.S3API
+func (f *S3) DeleteBucketEncryption(ctx context.Context, params *s3.DeleteBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketEncryptionOutput, error) {
+	if err := f.next("DeleteBucketEncryption"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("DeleteBucketEncryption", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	bucket.Encryption = ""
+	return &s3.DeleteBucketEncryptionOutput{}, nil
+}
+
+// GetBucketTagging implements services.S3API
+func (f *S3) GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	if err := f.next("GetBucketTagging"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("GetBucketTagging", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+	if len(bucket.Tags) == 0 {
+		return nil, APIError("S3", "GetBucketTagging", "NoSuchTagSet", "The TagSet does not exist", http.StatusNotFound)
+	}
+
+	keys := make([]string, 0, len(bucket.Tags))
+	for key := range bucket.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	output := &s3.GetBucketTaggingOutput{}
+	for _, key := range keys {
+		output.TagSet = append(output.TagSet, types.Tag{Key: aws.String(key), Value: aws.String(bucket.Tags[key])})
+	}
+	return output, nil
+}
+
+// PutBucketTagging implements services.S3API. Like S3, it replaces the whole tag set.
+func (f *S3) PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	if err := f.next("PutBucketTagging"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("PutBucketTagging", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	bucket.Tags = make(map[string]string)
+	if params.Tagging != nil {
+		for _, tag := range params.Tagging.TagSet {
+			bucket.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+// DeleteBucket implements services.S3API
+func (f *S3) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	if err := f.next("DeleteBucket"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Bucket)
+	if _, err := f.lookup("DeleteBucket", name); err != nil {
+		return nil, err
+	}
+	if len(f.buckets[name].Objects) > 0 {
+		return nil, APIError("S3", "DeleteBucket", "BucketNotEmpty",
+			"The bucket you tried to delete is not empty", http.StatusConflict)
+	}
+	delete(f.buckets, name)
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+// AddObjectVersion stores a new version of an object, or a delete marker, in a bucket
+func (f *S3) AddObjectVersion(bucket, key string, deleteMarker bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if b, ok := f.buckets[bucket]; ok {
+		f.nextVersion++
+		b.Objects = append(b.Objects, ObjectVersion{
+			Key:          key,
+			VersionID:    fmt.Sprintf("v%06d", f.nextVersion),
+			DeleteMarker: deleteMarker,
+		})
+	}
+}
+
+// ListObjectVersions implements services.S3API. Markers are not supported,
+// only the first MaxKeys entries are returned.
+func (f *S3) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	if err := f.next("ListObjectVersions"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("ListObjectVersions", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(aws.ToInt32(params.MaxKeys))
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	output := &s3.ListObjectVersionsOutput{Name: params.Bucket}
+	for i, object := range bucket.Objects {
+		if i == limit {
+			output.IsTruncated = aws.Bool(true)
+			break
+		}
+		if object.DeleteMarker {
+			output.DeleteMarkers = append(output.DeleteMarkers, types.DeleteMarkerEntry{
+				Key:       aws.String(object.Key),
+				VersionId: aws.String(object.VersionID),
+			})
+			continue
+		}
+		output.Versions = append(output.Versions, types.ObjectVersion{
+			Key:       aws.String(object.Key),
+			VersionId: aws.String(object.VersionID),
+		})
+	}
+	return output, nil
+}
+
+// DeleteObjects implements services.S3API
+func (f *S3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := f.next("DeleteObjects"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, err := f.lookup("DeleteObjects", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &s3.DeleteObjectsOutput{}
+	for _, id := range params.Delete.Objects {
+		kept := bucket.Objects[:0]
+		for _, object := range bucket.Objects {
+			if object.Key != aws.ToString(id.Key) || object.VersionID != aws.ToString(id.VersionId) {
+				kept = append(kept, object)
+			}
+		}
+		bucket.Objects = kept
+		output.Deleted = append(output.Deleted, types.DeletedObject{Key: id.Key, VersionId: id.VersionId})
+	}
+	return output, nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// HookPoint is the step of a create operation a hook runs at
+type HookPoint string
+
+const (
+	// HookPreValidate runs before the parameters are validated, on the raw parameters
+	HookPreValidate HookPoint = "pre_validate"
+	// HookPreCreate runs after validation, on the normalized parameters, before any API call
+	HookPreCreate HookPoint = "pre_create"
+	// HookPostCreate runs after a successful create, except for dry runs
+	HookPostCreate HookPoint = "post_create"
+	// HookOnError runs after a failed create, including a create vetoed by a hook
+	HookOnError HookPoint = "on_error"
+)
+
+// HookPoints lists the hook points in the order they run
+var HookPoints = []HookPoint{HookPreValidate, HookPreCreate, HookPostCreate, HookOnError}
+
+// DefaultHookTimeout bounds a command hook run without a timeout
+const DefaultHookTimeout = 30 * time.Second
+
+// HookContext is what a hook receives. Pre hooks may change Params, post
+// and error hooks may change Result; the changes are seen by the
+// following hooks and by the operation.
+type HookContext struct {
+	Point     HookPoint              `json:"point"`
+	Service   string                 `json:"service"`
+	Operation string                 `json:"operation"`
+	Region    string                 `json:"region"`
+	Params    map[string]interface{} `json:"params"`
+	// Result is set for post_create and on_error hooks
+	Result *ResourceResult `json:"result,omitempty"`
+}
+
+// Hook runs custom logic at a point of a create operation. An error
+// returned by a pre hook vetoes the operation; one returned by a
+// post_create hook turns the result into a failure.
+type Hook func(ctx context.Context, hc *HookContext) error
+
+// HookError is the error of a result vetoed or failed by a hook
+type HookError struct {
+	Point HookPoint
+	Err   error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook: %s", e.Point, e.Err.Error())
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// WithHook runs hook at point of every create operation. Hooks of a point
+// run in the order they were added; the first error stops the others.
+func WithHook(point HookPoint, hook Hook) Option {
+	return func(o *serviceOptions) {
+		if o.hooks == nil {
+			o.hooks = make(hooks)
+		}
+		o.hooks[point] = append(o.hooks[point], hook)
+	}
+}
+
+// ServiceHook restricts hook to the operations of one service
+func ServiceHook(service string, hook Hook) Hook {
+	return func(ctx context.Context, hc *HookContext) error {
+		if hc.Service != service {
+			return nil
+		}
+		return hook(ctx, hc)
+	}
+}
+
+// ValidHookPoint reports whether point is a known hook point
+func ValidHookPoint(point HookPoint) bool {
+	for _, known := range HookPoints {
+		if point == known {
+			return true
+		}
+	}
+	return false
+}
+
+// hooks holds the hooks of a service by point
+type hooks map[HookPoint][]Hook
+
+// errResultCleared is the error of a hook that set HookContext.Result to nil
+var errResultCleared = errors.New("the hook set the result to nil")
+
+// run runs the hooks of a point, stopping at the first error. A hook
+// clearing the result is an error too; the previous result is restored.
+func (h hooks) run(ctx context.Context, point HookPoint, hc *HookContext) error {
+	hc.Point = point
+	for _, hook := range h[point] {
+		result := hc.Result
+		if err := hook(ctx, hc); err != nil {
+			return &HookError{Point: point, Err: err}
+		}
+		if result != nil && hc.Result == nil {
+			hc.Result = result
+			return &HookError{Point: point, Err: errResultCleared}
+		}
+	}
+	return nil
+}
+
+// create runs a create operation between the hooks. Without hooks the
+// operation runs as is.
+func (h hooks) create(ctx context.Context, service, region string, params map[string]interface{},
+	create func(ctx context.Context, params map[string]interface{}) (*ResourceResult, error)) (*ResourceResult, error) {
+	if len(h) == 0 {
+		return create(ctx, params)
+	}
+
+	hc := &HookContext{Service: service, Operation: OperationCreate, Region: region, Params: make(map[string]interface{}, len(params))}
+	for name, value := range params {
+		hc.Params[name] = value
+	}
+
+	result, err := h.before(ctx, hc)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result, err = create(ctx, hc.Params)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	hc.Result = result
+	if result.Success && !IsDryRun(result) {
+		if err := h.run(ctx, HookPostCreate, hc); err != nil {
+			hc.Result = &ResourceResult{
+				Success: false,
+				Error:   "HookFailed",
+				Message: fmt.Sprintf("%s, but the %s hook failed: %s", result.Message, HookPostCreate, errors.Unwrap(err)),
+				Data:    result.Data,
+				Err:     err,
+			}
+		}
+	}
+	if !hc.Result.Success {
+		if err := h.run(ctx, HookOnError, hc); err != nil {
+			hc.Result.Message += fmt.Sprintf(" (the %s hook failed: %s)", HookOnError, errors.Unwrap(err))
+		}
+	}
+	return hc.Result, nil
+}
+
+// before runs the pre hooks around validation, returning a failed result
+// when a hook vetoes the operation or the parameters are invalid
+func (h hooks) before(ctx context.Context, hc *HookContext) (*ResourceResult, error) {
+	if err := h.run(ctx, HookPreValidate, hc); err != nil {
+		return hookRejected(err), nil
+	}
+
+	schema, ok := LookupSchema(hc.Service, hc.Operation)
+	if !ok {
+		return nil, fmt.Errorf("no parameter schema registered for %s %s", hc.Service, hc.Operation)
+	}
+	normalized, err := schema.Normalize(hc.Params)
+	if err != nil {
+		return validationFailure(err), nil
+	}
+	hc.Params = normalized
+
+	if err := h.run(ctx, HookPreCreate, hc); err != nil {
+		return hookRejected(err), nil
+	}
+	return nil, nil
+}
+
+// hookRejected builds the result of an operation vetoed by a pre hook
+func hookRejected(err error) *ResourceResult {
+	return &ResourceResult{
+		Success: false,
+		Error:   "HookRejected",
+		Message: "Rejected by the " + err.Error(),
+		Err:     err,
+	}
+}
+
+// hookOutput is what a command hook may print to change the operation
+type hookOutput struct {
+	// Params replaces the parameters of the operation, for pre hooks
+	Params map[string]interface{} `json:"params"`
+	// Data is merged into the data of the result, for post_create and on_error hooks
+	Data map[string]interface{} `json:"data"`
+}
+
+// CommandHook returns a hook running an external command, e.g. a script
+// registering instances in a CMDB. The command reads the HookContext as
+// JSON on stdin. A non-zero exit status is a hook error, with the trimmed
+// stderr as the reason. The command may print a JSON object with "params"
+// replacing the parameters, or "data" merged into the result data.
+func CommandHook(command []string, timeout time.Duration) Hook {
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	return func(ctx context.Context, hc *HookContext) error {
+		if len(command) == 0 {
+			return errors.New("no command configured")
+		}
+		input, err := json.Marshal(hc)
+		if err != nil {
+			return fmt.Errorf("failed to encode hook input: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if reason := strings.TrimSpace(stderr.String()); reason != "" {
+				return errors.New(reason)
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("%s timed out after %s", command[0], timeout)
+			}
+			return fmt.Errorf("%s failed: %w", command[0], err)
+		}
+
+		if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+			return nil
+		}
+		var output hookOutput
+		if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+			return fmt.Errorf("%s printed invalid JSON: %w", command[0], err)
+		}
+		if output.Params != nil {
+			hc.Params = output.Params
+		}
+		if output.Data != nil && hc.Result != nil {
+			if hc.Result.Data == nil {
+				hc.Result.Data = make(map[string]interface{}, len(output.Data))
+			}
+			for key, value := range output.Data {
+				hc.Result.Data[key] = value
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+// requireClassification vetoes resources without a data-classification tag
+func requireClassification(ctx context.Context, hc *HookContext) error {
+	tags, _ := hc.Params["tags"].(map[string]string)
+	if tags["data-classification"] == "" {
+		return errors.New("the data-classification tag is required")
+	}
+	return nil
+}
+
+func TestCreateHooks(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	var failures []string
+	svc, _ := NewS3Service("us-east-1", WithS3Client(backend),
+		WithHook(HookPreValidate, func(ctx context.Context, hc *HookContext) error {
+			hc.Params["versioning"] = true
+			return nil
+		}),
+		WithHook(HookPreCreate, ServiceHook(S3ServiceName, requireClassification)),
+		WithHook(HookPostCreate, func(ctx context.Context, hc *HookContext) error {
+			hc.Result.Data["cmdb_id"] = "CI-" + hc.Params["bucket_name"].(string)
+			return nil
+		}),
+		WithHook(HookOnError, func(ctx context.Context, hc *HookContext) error {
+			failures = append(failures, hc.Result.Error)
+			return nil
+		}),
+	)
+
+	result, _ := svc.CreateResource(ctx, map[string]interface{}{"bucket_name": "unclassified"})
+	var hookErr *HookError
+	if result.Success || result.Error != "HookRejected" || !errors.As(result.Err, &hookErr) || hookErr.Point != HookPreCreate {
+		t.Fatalf("Expected the pre_create hook to veto, got %+v", result)
+	}
+	if _, ok := backend.Bucket("unclassified"); ok {
+		t.Error("Expected a vetoed bucket not to be created")
+	}
+
+	result, _ = svc.CreateResource(ctx, map[string]interface{}{"bucket_name": "classified", "tags": "data-classification=internal"})
+	if !result.Success || result.Data["cmdb_id"] != "CI-classified" {
+		t.Fatalf("Expected the post_create hook to add data, got %+v", result)
+	}
+	if bucket, _ := backend.Bucket("classified"); bucket.Versioning != "Enabled" {
+		t.Error("Expected the pre_validate hook to enable versioning")
+	}
+
+	if len(failures) != 1 || failures[0] != "HookRejected" {
+		t.Errorf("Expected on_error to run once for the veto, got %v", failures)
+	}
+}
+
+func TestPostCreateHookFailure(t *testing.T) {
+	svc, _ := NewS3Service("us-east-1", WithS3Client(fake.NewS3()),
+		WithHook(HookPostCreate, func(ctx context.Context, hc *HookContext) error {
+			return errors.New("CMDB unavailable")
+		}))
+
+	result, _ := svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "orphan-bucket"})
+	if result.Success || result.Error != "HookFailed" || result.Data["bucket_name"] != "orphan-bucket" {
+		t.Errorf("Expected a failed result keeping the bucket data, got %+v", result)
+	}
+
+	// Dry runs create nothing, so post_create hooks do not run
+	result, _ = svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "planned-bucket", "dry_run": true})
+	if !result.Success {
+		t.Errorf("Expected a dry run to succeed, got %+v", result)
+	}
+}
+
+func TestHookClearingResult(t *testing.T) {
+	clearResult := func(ctx context.Context, hc *HookContext) error {
+		hc.Result = nil
+		return nil
+	}
+	svc, _ := NewS3Service("us-east-1", WithS3Client(fake.NewS3()), WithHook(HookPostCreate, clearResult), WithHook(HookOnError, clearResult))
+
+	result, err := svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "cleared-bucket"})
+	if err != nil || result == nil {
+		t.Fatalf("Expected a result, got %v (%v)", result, err)
+	}
+	if result.Success || result.Error != "HookFailed" || !errors.Is(result.Err, errResultCleared) {
+		t.Errorf("Expected a nil result to be a hook failure, got %+v", result)
+	}
+}
+
+func TestCommandHook(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewEC2("us-east-1")
+
+	veto := CommandHook([]string{"sh", "-c", `grep -q '"Owner"' || { echo "an Owner tag is required" >&2; exit 1; }`}, time.Second)
+	svc, _ := NewEC2Service("us-east-1", WithEC2Client(backend), WithHook(HookPreCreate, veto))
+	params := map[string]interface{}{"image_id": "ami-12345678", "instance_type": "t2.micro", "key_name": "my-key"}
+
+	result, _ := svc.CreateResource(ctx, params)
+	if result.Success || result.Message != "Rejected by the pre_create hook: an Owner tag is required" {
+		t.Fatalf("Expected the command to veto, got %+v", result)
+	}
+
+	register := CommandHook([]string{"sh", "-c", `cat >/dev/null; echo '{"data": {"cmdb_id": "CI-42"}}'`}, time.Second)
+	svc, _ = NewEC2Service("us-east-1", WithEC2Client(backend), WithHook(HookPreCreate, veto), WithHook(HookPostCreate, register))
+	params["tags"] = map[string]string{"Owner": "data-team"}
+
+	result, _ = svc.CreateResource(ctx, params)
+	if !result.Success || result.Data["cmdb_id"] != "CI-42" {
+		t.Errorf("Expected the command output to be merged into the result, got %+v", result)
+	}
+	if len(backend.Instances()) != 1 {
+		t.Errorf("Expected one instance, got %d", len(backend.Instances()))
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ImportQuery selects the existing resources to import. Every set field
+// must match; an empty query selects every resource of the region.
+type ImportQuery struct {
+	// IDs are bucket names or instance IDs
+	IDs []string
+	// NamePattern matches bucket names, or the Name tag of instances, with
+	// the * and ? wildcards, e.g. logs-*
+	NamePattern string
+	// Tags only selects resources carrying every given tag with the same value
+	Tags map[string]string
+}
+
+// matchesName reports whether a resource name matches NamePattern
+func (q ImportQuery) matchesName(name string) bool {
+	if q.NamePattern == "" {
+		return true
+	}
+	ok, _ := path.Match(q.NamePattern, name)
+	return ok
+}
+
+// matchesTags reports whether tags hold every tag of the query
+func (q ImportQuery) matchesTags(tags map[string]string) bool {
+	for key, value := range q.Tags {
+		if tag, ok := tags[key]; !ok || tag != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Importer is implemented by services that can discover existing resources
+// and describe them as inventory records
+type Importer interface {
+	Discover(ctx context.Context, query ImportQuery) ([]inventory.Record, error)
+}
+
+var (
+	_ Importer = (*S3Service)(nil)
+	_ Importer = (*EC2Service)(nil)
+)
+
+// ImportStatus is the outcome of importing one discovered resource
+type ImportStatus string
+
+const (
+	ImportPlanned  ImportStatus = "planned"
+	ImportImported ImportStatus = "imported"
+	ImportExisting ImportStatus = "already_recorded"
+)
+
+// ImportResult reports what Import did with a discovered resource
+type ImportResult struct {
+	Record  inventory.Record `json:"record"`
+	Status  ImportStatus     `json:"status"`
+	Message string           `json:"message"`
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	// DryRun only reports what would be imported
+	DryRun bool
+}
+
+// Import discovers the resources of service in region selected by query
+// and records them in store, marked as imported, so they can be managed
+// like the resources this tool created. Resources that already have a live
+// record are left untouched.
+func Import(ctx context.Context, store *inventory.Store, service, region string, query ImportQuery, options ImportOptions, opts ...Option) ([]ImportResult, error) {
+	svc, err := NewService(service, region, opts...)
+	if err != nil {
+		return nil, err
+	}
+	importer, ok := svc.(Importer)
+	if !ok {
+		return nil, fmt.Errorf("import is not supported for service %q", service)
+	}
+
+	records, err := importer.Discover(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ImportResult, 0, len(records))
+	for _, record := range records {
+		record.Imported = true
+		if existing, err := store.Get(record.Service, record.ID); err == nil && !existing.Deleted() {
+			results = append(results, ImportResult{Record: existing, Status: ImportExisting, Message: "Already in the inventory"})
+			continue
+		}
+
+		if options.DryRun {
+			results = append(results, ImportResult{Record: record, Status: ImportPlanned, Message: fmt.Sprintf("Would import %s %s", record.Type, record.ID)})
+			continue
+		}
+		if err := store.Put(record); err != nil {
+			return results, err
+		}
+		results = append(results, ImportResult{Record: record, Status: ImportImported, Message: fmt.Sprintf("Imported %s %s", record.Type, record.ID)})
+	}
+	return results, nil
+}
+
+// Discover finds the buckets selected by query and reads their versioning,
+// encryption and tags. Buckets given by name are found in any region, the
+// others are listed in the service region.
+func (s *S3Service) Discover(ctx context.Context, query ImportQuery) ([]inventory.Record, error) {
+	client, err := s.clientFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Bucket names and their regions, in discovery order
+	var names, regions []string
+	if len(query.IDs) > 0 {
+		for _, name := range query.IDs {
+			region, err := bucketRegion(ctx, client, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find bucket %s: %w", name, ClassifyError(err))
+			}
+			names = append(names, name)
+			regions = append(regions, region)
+		}
+	} else {
+		paginator := s3.NewListBucketsPaginator(client, &s3.ListBucketsInput{BucketRegion: aws.String(s.Region)})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list buckets: %w", ClassifyError(err))
+			}
+			for _, bucket := range page.Buckets {
+				names = append(names, aws.ToString(bucket.Name))
+				regions = append(regions, aws.ToString(bucket.BucketRegion))
+			}
+		}
+	}
+
+	account := accountID(ctx, s.provider, s.client != nil)
+	var records []inventory.Record
+	for i, name := range names {
+		if !query.matchesName(name) {
+			continue
+		}
+
+		record, err := s.describeBucket(ctx, name, regions[i])
+		if err != nil {
+			return nil, err
+		}
+		if !query.matchesTags(record.Tags) {
+			continue
+		}
+		record.Account = account
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// describeBucket reads the live configuration of a bucket into a record
+func (s *S3Service) describeBucket(ctx context.Context, name, region string) (inventory.Record, error) {
+	if region == "" {
+		region = s.Region
+	}
+	client, err := s.clientFor(ctx, region)
+	if err != nil {
+		return inventory.Record{}, err
+	}
+	bucket := aws.String(name)
+
+	versioning, err := client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: bucket})
+	if err != nil {
+		return inventory.Record{}, fmt.Errorf("failed to read versioning of bucket %s: %w", name, ClassifyError(err))
+	}
+
+	encrypted := true
+	if _, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: bucket}); err != nil {
+		if ClassifyError(err).Kind != KindNotFound {
+			return inventory.Record{}, fmt.Errorf("failed to read encryption of bucket %s: %w", name, ClassifyError(err))
+		}
+		encrypted = false
+	}
+
+	// Buckets without tags return NoSuchTagSet
+	tags := map[string]string{}
+	tagging, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: bucket})
+	switch {
+	case err == nil:
+		tags = tagsFromS3(tagging.TagSet)
+	case ClassifyError(err).Kind != KindNotFound:
+		return inventory.Record{}, fmt.Errorf("failed to read tags of bucket %s: %w", name, ClassifyError(err))
+	}
+
+	return inventory.Record{
+		Service: S3ServiceName,
+		Type:    ResourceTypeBucket,
+		ID:      name,
+		ARN:     bucketARN(region, name),
+		Region:  region,
+		Params: map[string]interface{}{
+			"bucket_name": name,
+			"region":      region,
+			"versioning":  versioning.Status == s3types.BucketVersioningStatusEnabled,
+			"encryption":  encrypted,
+		},
+		Tags:    tags,
+		Session: s.session,
+	}, nil
+}
+
+// importableStates are the states of instances that can be imported
+var importableStates = []string{
+	string(ec2types.InstanceStateNamePending),
+	string(ec2types.InstanceStateNameRunning),
+	string(ec2types.InstanceStateNameStopping),
+	string(ec2types.InstanceStateNameStopped),
+}
+
+// Discover finds the instances of the service region selected by query,
+// skipping terminated ones, and reads their configuration and tags
+func (e *EC2Service) Discover(ctx context.Context, query ImportQuery) ([]inventory.Record, error) {
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The query is turned into filters so EC2 does the selection
+	input := &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("instance-state-name"), Values: importableStates}},
+	}
+	if len(query.IDs) > 0 {
+		input.Filters = append(input.Filters, ec2types.Filter{Name: aws.String("instance-id"), Values: query.IDs})
+	}
+	if query.NamePattern != "" {
+		input.Filters = append(input.Filters, ec2types.Filter{Name: aws.String("tag:Name"), Values: []string{query.NamePattern}})
+	}
+	for key, value := range query.Tags {
+		input.Filters = append(input.Filters, ec2types.Filter{Name: aws.String("tag:" + key), Values: []string{value}})
+	}
+
+	account := accountID(ctx, e.provider, e.client != nil)
+	var records []inventory.Record
+	paginator := ec2.NewDescribeInstancesPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances: %w", ClassifyError(err))
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				records = append(records, e.instanceRecord(instance, account))
+			}
+		}
+	}
+	return records, nil
+}
+
+// instanceRecord describes the live configuration of an instance as a record
+func (e *EC2Service) instanceRecord(instance ec2types.Instance, account string) inventory.Record {
+	instanceID := aws.ToString(instance.InstanceId)
+	record := inventory.Record{
+		Service: EC2ServiceName,
+		Type:    ResourceTypeInstance,
+		ID:      instanceID,
+		ARN:     instanceARN(e.Region, account, instanceID),
+		Region:  e.Region,
+		Account: account,
+		Params: map[string]interface{}{
+			"image_id":      aws.ToString(instance.ImageId),
+			"instance_type": string(instance.InstanceType),
+			"key_name":      aws.ToString(instance.KeyName),
+			"region":        e.Region,
+			"state":         settledState(instanceState(instance)),
+		},
+		Tags:    tagsFromEC2(instance.Tags),
+		Session: e.session,
+	}
+	if instance.SubnetId != nil {
+		setRecordParam(&record, "subnet_id", aws.ToString(instance.SubnetId))
+	}
+	if len(instance.SecurityGroups) > 0 {
+		groups := make([]string, len(instance.SecurityGroups))
+		for i, group := range instance.SecurityGroups {
+			groups[i] = aws.ToString(group.GroupId)
+		}
+		setRecordParam(&record, "security_group_ids", groups)
+	}
+	return record
+}
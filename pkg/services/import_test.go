@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+func TestImportBuckets(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+
+	// Created without an inventory, like buckets that predate the tool
+	existing, _ := NewS3Service("us-east-1", WithS3Client(backend))
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "logs-app", "versioning": true, "tags": "team=data"})
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "logs-web", "tags": "team=web"})
+	existing.CreateResource(ctx, map[string]interface{}{"bucket_name": "assets"})
+
+	query := ImportQuery{NamePattern: "logs-*", Tags: map[string]string{"team": "data"}}
+	results, err := Import(ctx, store, S3ServiceName, "us-east-1", query, ImportOptions{DryRun: true}, WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Record.ID != "logs-app" || results[0].Status != ImportPlanned {
+		t.Fatalf("Expected logs-app to be planned, got %+v", results)
+	}
+	if records, _ := store.List(inventory.Filter{}); len(records) != 0 {
+		t.Errorf("Expected a dry run to record nothing, got %d record(s)", len(records))
+	}
+
+	results, err = Import(ctx, store, S3ServiceName, "us-east-1", query, ImportOptions{}, WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Status != ImportImported {
+		t.Fatalf("Expected logs-app to be imported, got %+v", results)
+	}
+
+	record, err := store.Get(S3ServiceName, "logs-app")
+	if err != nil {
+		t.Fatalf("Expected a record, got %v", err)
+	}
+	if !record.Imported || record.Params["versioning"] != true || record.Tags["team"] != "data" {
+		t.Errorf("Expected the live configuration to be recorded, got %+v", record)
+	}
+
+	// Imported buckets are checked for drift like created ones
+	checker, _ := NewDriftChecker(S3ServiceName, "us-east-1", WithS3Client(backend))
+	if report := checker.CheckDrift(ctx, record); report.Status != DriftInSync {
+		t.Errorf("Expected an imported bucket to be in sync, got %+v", report)
+	}
+
+	results, _ = Import(ctx, store, S3ServiceName, "us-east-1", ImportQuery{IDs: []string{"logs-app", "assets"}}, ImportOptions{}, WithS3Client(backend))
+	if len(results) != 2 || results[0].Status != ImportExisting || results[1].Status != ImportImported {
+		t.Errorf("Expected logs-app to be kept and assets imported, got %+v", results)
+	}
+
+	if _, err := Import(ctx, store, S3ServiceName, "us-east-1", ImportQuery{IDs: []string{"missing"}}, ImportOptions{}, WithS3Client(backend)); err == nil {
+		t.Error("Expected an error for a missing bucket")
+	}
+}
+
+func TestImportInstances(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewEC2("us-east-1")
+
+	existing, _ := NewEC2Service("us-east-1", WithEC2Client(backend))
+	for _, name := range []string{"web-1", "web-2", "db-1"} {
+		existing.CreateResource(ctx, map[string]interface{}{
+			"image_id":           "ami-12345678",
+			"instance_type":      "t2.micro",
+			"key_name":           "my-key",
+			"subnet_id":          "subnet-1234",
+			"security_group_ids": []string{"sg-1234"},
+			"tags":               map[string]string{"Name": name},
+		})
+	}
+	terminated := backend.Instances()[1]
+	existing.DeleteResource(ctx, map[string]interface{}{"instance_id": []string{*terminated.InstanceId}})
+
+	results, err := Import(ctx, store, EC2ServiceName, "us-east-1", ImportQuery{NamePattern: "web-*"}, ImportOptions{}, WithEC2Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Record.Tags["Name"] != "web-1" {
+		t.Fatalf("Expected only the live web instance to be imported, got %+v", results)
+	}
+
+	record := results[0].Record
+	if record.Params["instance_type"] != "t2.micro" || record.Params["subnet_id"] != "subnet-1234" || record.Params["state"] != "running" {
+		t.Errorf("Expected the live configuration to be recorded, got %+v", record.Params)
+	}
+	if _, err := store.Get(EC2ServiceName, record.ID); err != nil {
+		t.Errorf("Expected a record, got %v", err)
+	}
+}
+
+func TestDiscoverBucketByNameInItsRegion(t *testing.T) {
+	// A CA bundle cannot be applied to a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	transport := &regionalS3{hosts: map[string]string{}}
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(transport),
+	))
+	service, _ := NewS3Service("us-east-1", WithProvider(provider))
+
+	records, err := service.Discover(context.Background(), ImportQuery{IDs: []string{"far-bucket"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 1 || records[0].Region != "sa-east-1" {
+		t.Fatalf("Expected far-bucket in sa-east-1, got %+v", records)
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	if host := transport.hosts["GET versioning"]; !strings.Contains(host, "sa-east-1") {
+		t.Errorf("Expected the bucket to be read in sa-east-1, got host %q", host)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+)
+
+// Resource types recorded in the inventory
+const (
+	ResourceTypeBucket   = "bucket"
+	ResourceTypeInstance = "instance"
+)
+
+// partition returns the ARN partition of a region
+func partition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
+
+// bucketARN returns the ARN of an S3 bucket
+func bucketARN(region, bucketName string) string {
+	return fmt.Sprintf("arn:%s:s3:::%s", partition(region), bucketName)
+}
+
+// instanceARN returns the ARN of an EC2 instance, or an empty string when
+// the account is unknown
+func instanceARN(region, account, instanceID string) string {
+	if account == "" {
+		return ""
+	}
+	return fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", partition(region), region, account, instanceID)
+}
+
+// accountID returns the account that owns created resources. Injected
+// clients are not bound to the provider's credentials, so STS is only
+// called when the service builds its own clients.
+func accountID(ctx context.Context, provider *awsconfig.Provider, injected bool) string {
+	if injected {
+		return provider.KnownAccountID()
+	}
+	account, err := provider.AccountID(ctx)
+	if err != nil {
+		return ""
+	}
+	return account
+}
+
+// recordResources saves records in the inventory. The resources already
+// exist, so a failure is reported in data instead of failing the operation.
+func recordResources(store *inventory.Store, data map[string]interface{}, records ...inventory.Record) {
+	if store == nil {
+		return
+	}
+	for _, record := range records {
+		if err := store.Put(record); err != nil {
+			data["inventory_error"] = err.Error()
+			return
+		}
+	}
+}
+
+// recorded reports whether the inventory holds a live record of a resource
+// in an account and region
+func recorded(store *inventory.Store, service, account, region, id string) bool {
+	records, err := store.List(inventory.Filter{Service: service, Account: account, Region: region, IDs: []string{id}})
+	return err == nil && len(records) > 0
+}
+
+// updateRecord applies fn to the inventory record of a resource, if any
+func updateRecord(store *inventory.Store, data map[string]interface{}, service, id string, fn func(*inventory.Record)) {
+	if store == nil {
+		return
+	}
+	if err := store.Update(service, id, fn); err != nil && !errors.Is(err, inventory.ErrNotFound) {
+		data["inventory_error"] = err.Error()
+	}
+}
+
+// setRecordParam stores a parameter of the desired configuration of a resource
+func setRecordParam(record *inventory.Record, name string, value interface{}) {
+	if record.Params == nil {
+		record.Params = make(map[string]interface{})
+	}
+	record.Params[name] = value
+}
+
+// deleteRecord marks the inventory record of a resource, if any, as deleted
+func deleteRecord(store *inventory.Store, data map[string]interface{}, service, id string) {
+	if store == nil {
+		return
+	}
+	if err := store.MarkDeleted(service, id); err != nil && !errors.Is(err, inventory.ErrNotFound) {
+		data["inventory_error"] = err.Error()
+	}
+}
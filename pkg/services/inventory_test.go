@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+func TestServicesRecordInventory(t *testing.T) {
+	ctx := context.Background()
+	store, err := inventory.Open(filepath.Join(t.TempDir(), "inventory.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	opts := []Option{
+		WithProvider(awsconfig.NewProvider(awsconfig.WithAccountID("123456789012"))),
+		WithInventory(store),
+	}
+
+	s3Service, _ := NewS3Service("us-east-1", append(opts, WithS3Client(fake.NewS3()))...)
+	s3Service.CreateResource(ctx, map[string]interface{}{"bucket_name": "logs-bucket", "tags": "team=data"})
+	s3Service.UpdateResource(ctx, map[string]interface{}{"bucket_name": "logs-bucket", "versioning": true})
+
+	bucket, err := store.Get(S3ServiceName, "logs-bucket")
+	if err != nil {
+		t.Fatalf("Expected bucket record, got %v", err)
+	}
+	if bucket.ARN != "arn:aws:s3:::logs-bucket" || bucket.Account != "123456789012" || bucket.Tags["team"] != "data" {
+		t.Errorf("Unexpected bucket record: %+v", bucket)
+	}
+	if bucket.Params["versioning"] != true {
+		t.Errorf("Expected update to be recorded, got %+v", bucket.Params)
+	}
+
+	ec2Service, _ := NewEC2Service("eu-west-1", append(opts, WithEC2Client(fake.NewEC2("eu-west-1")))...)
+	result, _ := ec2Service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         2,
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful launch, got %+v", result)
+	}
+
+	records, _ := store.List(inventory.Filter{Service: EC2ServiceName})
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 instance records, got %d", len(records))
+	}
+	instanceID := records[0].ID
+	if records[0].ARN != "arn:aws:ec2:eu-west-1:123456789012:instance/"+instanceID {
+		t.Errorf("Unexpected instance ARN %s", records[0].ARN)
+	}
+	if records[0].Params["state"] != "pending" {
+		t.Errorf("Expected the state returned by RunInstances, got %v", records[0].Params["state"])
+	}
+
+	ec2Service.DeleteResource(ctx, map[string]interface{}{"instance_id": instanceID})
+	records, _ = store.List(inventory.Filter{Service: EC2ServiceName})
+	if len(records) != 1 {
+		t.Errorf("Expected terminated instance to be hidden, got %d records", len(records))
+	}
+}
+
+func TestS3CreateRecordsOnlyAppliedSettings(t *testing.T) {
+	ctx := context.Background()
+	store := openTestInventory(t)
+	backend := fake.NewS3()
+	service, _ := NewS3Service("us-east-1", WithS3Client(backend), WithInventory(store))
+
+	backend.InjectError("PutBucketVersioning", fake.APIError("S3", "PutBucketVersioning", "AccessDenied", "Access Denied", http.StatusForbidden))
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"bucket_name": "half-bucket",
+		"tags":        "team=data",
+		"versioning":  true,
+		"encryption":  true,
+	})
+	if result.Success {
+		t.Fatalf("Expected the versioning step to fail, got %+v", result)
+	}
+
+	record, err := store.Get(S3ServiceName, "half-bucket")
+	if err != nil {
+		t.Fatalf("Expected the created bucket to be recorded, got %v", err)
+	}
+	if record.Tags["team"] != "data" {
+		t.Errorf("Expected the applied tags to be recorded, got %+v", record.Tags)
+	}
+	if _, ok := record.Params["versioning"]; ok {
+		t.Errorf("Expected the failed versioning not to be recorded, got %+v", record.Params)
+	}
+	if _, ok := record.Params["encryption"]; ok {
+		t.Errorf("Expected the skipped encryption not to be recorded, got %+v", record.Params)
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithLogger sets the logger of service operations. Operations log their
+// parameters, with credentials and user data redacted, their outcome and
+// every AWS call with its request ID. Services created without a logger
+// log nothing.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *serviceOptions) {
+		o.logger = logger
+	}
+}
+
+type loggerKey struct{}
+
+// withLogger returns a context whose AWS calls are logged to logger
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// addRequestLogging adds middleware to SDK clients logging every API call
+// made with a logger in its context, with its AWS request ID
+func addRequestLogging(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RequestLogging",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+			if !ok {
+				return next.HandleInitialize(ctx, in)
+			}
+
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			attrs := []any{
+				"aws_service", awsmiddleware.GetServiceID(ctx),
+				"api", awsmiddleware.GetOperationName(ctx),
+				"request_id", requestID,
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			}
+			logger.DebugContext(ctx, "AWS call", attrs...)
+			return out, metadata, err
+		}), middleware.After)
+}
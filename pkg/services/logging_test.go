@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+// recordLogs returns a debug logger and a function decoding what it logged
+func recordLogs() (*slog.Logger, func() []map[string]interface{}) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, func() []map[string]interface{} {
+		var records []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var record map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &record); err == nil {
+				records = append(records, record)
+			}
+		}
+		return records
+	}
+}
+
+func findRecord(records []map[string]interface{}, msg string) map[string]interface{} {
+	for _, record := range records {
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	return nil
+}
+
+func TestOperationLogsRequestIDs(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(&flakyTransport{}),
+	))
+	logger, records := recordLogs()
+	svc, err := NewS3Service("us-east-1", WithProvider(provider), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, _ := svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "logged-bucket"})
+	if !result.Success {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	call := findRecord(records(), "AWS call")
+	if call == nil {
+		t.Fatalf("Expected an AWS call record, got %v", records())
+	}
+	if call["api"] != "CreateBucket" || call["request_id"] != "REQ123" {
+		t.Errorf("Expected CreateBucket with request ID REQ123, got %v", call)
+	}
+	if call["service"] != S3ServiceName || call["operation"] != OperationCreate {
+		t.Errorf("Expected the operation attributes, got %v", call)
+	}
+	if findRecord(records(), "Operation succeeded") == nil {
+		t.Errorf("Expected the outcome to be logged, got %v", records())
+	}
+}
+
+func TestOperationLogsRedactUserData(t *testing.T) {
+	logger, records := recordLogs()
+	svc, err := NewEC2Service("us-east-1", WithEC2Client(fake.NewEC2("us-east-1")), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	svc.CreateResource(context.Background(), map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"user_data":     "#!/bin/sh\nexport TOKEN=hunter2\n",
+	})
+	svc.DescribeResource(context.Background(), map[string]interface{}{"instance_id": "i-missing"})
+
+	for _, record := range records() {
+		if data, _ := json.Marshal(record); strings.Contains(string(data), "hunter2") {
+			t.Errorf("Expected user data to be redacted, got %s", data)
+		}
+	}
+	started := findRecord(records(), "Operation started")
+	if params, _ := started["params"].(map[string]interface{}); params["user_data"] != "[REDACTED]" || params["image_id"] != "ami-12345678" {
+		t.Errorf("Expected only user_data to be redacted, got %v", started)
+	}
+	failed := findRecord(records(), "Operation failed")
+	if failed == nil || failed["level"] != "WARN" || failed["operation"] != OperationDescribe {
+		t.Errorf("Expected a warning for the failed describe, got %v", failed)
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/logging"
+)
+
+// Option configures the optional dependencies of a service
+type Option func(*serviceOptions)
+
+// serviceOptions holds the dependencies shared by all services
+type serviceOptions struct {
+	provider    *awsconfig.Provider
+	s3Client    S3API
+	ec2Client   EC2API
+	orgsClient  OrganizationsAPI
+	defaultTags map[string]string
+	inventory   *inventory.Store
+	session     string
+	accountRole string
+	progress    ProgressFunc
+	hooks       hooks
+	logger      *slog.Logger
+	ctx         context.Context
+}
+
+// WithProvider sets the AWS configuration provider used to build clients.
+// Services created without a provider use the default credential chain.
+func WithProvider(provider *awsconfig.Provider) Option {
+	return func(o *serviceOptions) {
+		o.provider = provider
+	}
+}
+
+// WithS3Client makes S3Service use client for every region instead of
+// building clients from the provider, e.g. to inject a fake in tests
+func WithS3Client(client S3API) Option {
+	return func(o *serviceOptions) {
+		o.s3Client = client
+	}
+}
+
+// WithEC2Client makes EC2Service use client for every region instead of
+// building clients from the provider, e.g. to inject a fake in tests
+func WithEC2Client(client EC2API) Option {
+	return func(o *serviceOptions) {
+		o.ec2Client = client
+	}
+}
+
+// WithOrganizationsClient makes OrganizationAccounts use client instead of
+// building one from the provider, e.g. to inject a fake in tests
+func WithOrganizationsClient(client OrganizationsAPI) Option {
+	return func(o *serviceOptions) {
+		o.orgsClient = client
+	}
+}
+
+// WithDefaultTags sets tags applied to every resource a service creates.
+// Tags passed to a create operation override defaults with the same key.
+func WithDefaultTags(tags map[string]string) Option {
+	return func(o *serviceOptions) {
+		o.defaultTags = MergeTags(o.defaultTags, tags)
+	}
+}
+
+// WithInventory records every created resource in store, and keeps the
+// records up to date when resources are updated or deleted
+func WithInventory(store *inventory.Store) Option {
+	return func(o *serviceOptions) {
+		o.inventory = store
+	}
+}
+
+// WithSession stores session in the inventory records of created
+// resources, so everything created by one run can be selected together
+func WithSession(session string) Option {
+	return func(o *serviceOptions) {
+		o.session = session
+	}
+}
+
+// WithAccountRole makes Destroy and DetectDrift reach resources recorded
+// in another account than the provider's by assuming roleName in that
+// account, e.g. OrganizationAccountAccessRole. Without it those resources
+// are skipped.
+func WithAccountRole(roleName string) Option {
+	return func(o *serviceOptions) {
+		o.accountRole = roleName
+	}
+}
+
+// WithLoadContext sets the context of the AWS configuration loaded when a
+// service is created, e.g. to make it part of a trace
+func WithLoadContext(ctx context.Context) Option {
+	return func(o *serviceOptions) {
+		o.ctx = ctx
+	}
+}
+
+// loadContext returns the context set with WithLoadContext, or context.TODO()
+func (o serviceOptions) loadContext() context.Context {
+	if o.ctx == nil {
+		return context.TODO()
+	}
+	return o.ctx
+}
+
+// newServiceOptions applies opts on top of the defaults
+func newServiceOptions(opts []Option) serviceOptions {
+	var options serviceOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.provider == nil {
+		options.provider = awsconfig.NewProvider()
+	}
+	if options.logger == nil {
+		options.logger = logging.Discard()
+	}
+	return options
+}
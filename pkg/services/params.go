@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Operation names used to register parameter schemas
+const (
+	OperationCreate   = "create"
+	OperationDescribe = "describe"
+	OperationList     = "list"
+	OperationUpdate   = "update"
+	OperationDelete   = "delete"
+)
+
+// ParamType identifies the type of a service parameter
+type ParamType string
+
+const (
+	ParamString     ParamType = "string"
+	ParamInt        ParamType = "int"
+	ParamBool       ParamType = "bool"
+	ParamStringList ParamType = "[]string"
+	ParamStringMap  ParamType = "map[string]string"
+)
+
+// ParamSpec describes a single parameter accepted by a service operation
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        ParamType   `json:"type"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// ParamSchema describes all parameters accepted by a service operation
+type ParamSchema struct {
+	Service   string      `json:"service"`
+	Operation string      `json:"operation"`
+	Params    []ParamSpec `json:"params"`
+}
+
+// FieldError describes why a single parameter is invalid
+type FieldError struct {
+	// Param is empty when the error concerns several parameters together
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when parameters do not match a schema
+type ValidationError struct {
+	Service   string       `json:"service"`
+	Operation string       `json:"operation"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		parts[i] = fieldErr.Message
+		if fieldErr.Param != "" {
+			parts[i] = fmt.Sprintf("%s: %s", fieldErr.Param, fieldErr.Message)
+		}
+	}
+	return fmt.Sprintf("invalid parameters for %s %s: %s", e.Service, e.Operation, strings.Join(parts, "; "))
+}
+
+// newValidationError creates a ValidationError for a single parameter
+func newValidationError(service, operation, param, message string) *ValidationError {
+	verr := &ValidationError{Service: service, Operation: operation}
+	verr.add(param, "%s", message)
+	return verr
+}
+
+func (e *ValidationError) add(param, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Param: param, Message: fmt.Sprintf(format, args...)})
+}
+
+// Normalize validates params against the schema and returns a copy with
+// defaults applied and every value converted to its declared type.
+// Unknown parameters are rejected.
+func (s ParamSchema) Normalize(params map[string]interface{}) (map[string]interface{}, error) {
+	verr := &ValidationError{Service: s.Service, Operation: s.Operation}
+	values := make(map[string]interface{}, len(s.Params))
+	known := make(map[string]bool, len(s.Params))
+
+	for _, spec := range s.Params {
+		known[spec.Name] = true
+
+		raw, present := params[spec.Name]
+		if !present || isEmptyParam(raw) {
+			if spec.Required {
+				verr.add(spec.Name, "is required")
+			} else if spec.Default != nil {
+				values[spec.Name] = spec.Default
+			}
+			continue
+		}
+
+		value, err := convertParam(spec.Type, raw)
+		if err != nil {
+			verr.add(spec.Name, "%s", err.Error())
+			continue
+		}
+
+		if len(spec.Enum) > 0 && !containsString(spec.Enum, fmt.Sprint(value)) {
+			verr.add(spec.Name, "must be one of %v, got %v", spec.Enum, value)
+			continue
+		}
+
+		values[spec.Name] = value
+	}
+
+	var unknown []string
+	for name := range params {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.add(name, "unknown parameter")
+	}
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+
+	return values, nil
+}
+
+// Decode validates params against the schema and stores them in the struct
+// pointed to by out. Struct fields are matched by their `param` tag; pointer
+// fields are only set when the parameter was provided or has a default.
+func (s ParamSchema) Decode(params map[string]interface{}, out interface{}) error {
+	values, err := s.Normalize(params)
+	if err != nil {
+		return err
+	}
+
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a pointer to a struct, got %T", out)
+	}
+	target = target.Elem()
+
+	for i := 0; i < target.NumField(); i++ {
+		name := target.Type().Field(i).Tag.Get("param")
+		value, ok := values[name]
+		if name == "" || !ok {
+			continue
+		}
+
+		field := target.Field(i)
+		fieldType := field.Type()
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		v := reflect.ValueOf(value)
+		if !v.Type().ConvertibleTo(fieldType) {
+			return fmt.Errorf("parameter %s of type %T cannot be stored in field %s", name, value, target.Type().Field(i).Name)
+		}
+
+		if field.Kind() == reflect.Ptr {
+			ptr := reflect.New(fieldType)
+			ptr.Elem().Set(v.Convert(fieldType))
+			field.Set(ptr)
+		} else {
+			field.Set(v.Convert(fieldType))
+		}
+	}
+
+	return nil
+}
+
+// convertParam converts a raw parameter value to the given type
+func convertParam(paramType ParamType, raw interface{}) (interface{}, error) {
+	switch paramType {
+	case ParamString:
+		if v, ok := raw.(string); ok {
+			return v, nil
+		}
+	case ParamInt:
+		switch v := raw.(type) {
+		case int:
+			return v, nil
+		case int32:
+			return int(v), nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		case string:
+			parsed, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("must be an integer, got %q", v)
+			}
+			return parsed, nil
+		}
+	case ParamBool:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("must be a boolean, got %q", v)
+			}
+			return parsed, nil
+		}
+	case ParamStringList:
+		switch v := raw.(type) {
+		case []string:
+			return v, nil
+		case string:
+			var list []string
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			return list, nil
+		case []interface{}:
+			list := make([]string, len(v))
+			for i, item := range v {
+				str, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be a list of strings, got element %T", item)
+				}
+				list[i] = str
+			}
+			return list, nil
+		}
+	case ParamStringMap:
+		switch v := raw.(type) {
+		case map[string]string:
+			return v, nil
+		case string:
+			return ParseTags(v)
+		case map[string]interface{}:
+			m := make(map[string]string, len(v))
+			for key, item := range v {
+				str, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be a map of strings, got %T for key %q", item, key)
+				}
+				m[key] = str
+			}
+			return m, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parameter type %s", paramType)
+	}
+
+	return nil, fmt.Errorf("must be of type %s, got %T", paramType, raw)
+}
+
+// isEmptyParam reports whether a parameter value should be treated as not provided
+func isEmptyParam(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]ParamSchema)
+)
+
+// RegisterSchema registers the parameter schema of a service operation,
+// replacing any schema previously registered for the same operation
+func RegisterSchema(schema ParamSchema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas[schema.Service+"/"+schema.Operation] = schema
+}
+
+// LookupSchema returns the parameter schema of a service operation
+func LookupSchema(service, operation string) (ParamSchema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	schema, ok := schemas[service+"/"+operation]
+	return schema, ok
+}
+
+// Schemas returns all registered schemas sorted by service and operation
+func Schemas() []ParamSchema {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	list := make([]ParamSchema, 0, len(schemas))
+	for _, schema := range schemas {
+		list = append(list, schema)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Service != list[j].Service {
+			return list[i].Service < list[j].Service
+		}
+		return list[i].Operation < list[j].Operation
+	})
+	return list
+}
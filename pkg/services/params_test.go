@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParamSchemaNormalize(t *testing.T) {
+	schema, ok := LookupSchema(EC2ServiceName, OperationCreate)
+	if !ok {
+		t.Fatal("Expected ec2 create schema to be registered")
+	}
+
+	values, err := schema.Normalize(map[string]interface{}{
+		"image_id":      "ami-123",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         "3",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if values["count"] != 3 {
+		t.Errorf("Expected count 3, got %v", values["count"])
+	}
+
+	// Test default value
+	values, err = schema.Normalize(map[string]interface{}{
+		"image_id":      "ami-123",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if values["count"] != 1 {
+		t.Errorf("Expected default count 1, got %v", values["count"])
+	}
+
+	// Test invalid type and unknown parameter
+	_, err = schema.Normalize(map[string]interface{}{
+		"image_id":      "ami-123",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         "many",
+		"colour":        "blue",
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if len(verr.Errors) != 2 {
+		t.Fatalf("Expected 2 field errors, got %v", verr.Errors)
+	}
+	if verr.Errors[0].Param != "count" || verr.Errors[1].Param != "colour" {
+		t.Errorf("Unexpected field errors: %v", verr.Errors)
+	}
+}
+
+func TestParamSchemaEnum(t *testing.T) {
+	schema, _ := LookupSchema(EC2ServiceName, OperationUpdate)
+
+	_, err := schema.Normalize(map[string]interface{}{
+		"instance_id": "i-123",
+		"state":       "rebooting",
+	})
+	if err == nil {
+		t.Error("Expected error for value outside enum")
+	}
+}
+
+func TestParamSchemaDecodePointers(t *testing.T) {
+	schema, _ := LookupSchema(S3ServiceName, OperationUpdate)
+
+	var input S3UpdateBucketInput
+	err := schema.Decode(map[string]interface{}{
+		"bucket_name": "test-bucket",
+		"versioning":  "true",
+	}, &input)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if input.Versioning == nil || !*input.Versioning {
+		t.Errorf("Expected versioning to be set to true, got %v", input.Versioning)
+	}
+	if input.Encryption != nil {
+		t.Errorf("Expected encryption to be unset, got %v", *input.Encryption)
+	}
+}
+
+func TestParamSchemaStringList(t *testing.T) {
+	schema, _ := LookupSchema(EC2ServiceName, OperationDelete)
+
+	var input EC2TerminateInstancesInput
+	if err := schema.Decode(map[string]interface{}{"instance_id": "i-1, i-2"}, &input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(input.InstanceIDs) != 2 || input.InstanceIDs[1] != "i-2" {
+		t.Errorf("Expected two instance IDs, got %v", input.InstanceIDs)
+	}
+}
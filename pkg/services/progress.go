@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// ProgressStage is the step a service operation is at
+type ProgressStage string
+
+const (
+	StageValidating ProgressStage = "validating"
+	StageCalling    ProgressStage = "calling_api"
+	StageWaiting    ProgressStage = "waiting"
+	StageRetrying   ProgressStage = "retrying"
+	StageDone       ProgressStage = "done"
+)
+
+// ProgressEvent reports the progress of a create or delete operation
+type ProgressEvent struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	Region    string `json:"region"`
+	// Resource is the bucket name or instance IDs, once known
+	Resource string        `json:"resource,omitempty"`
+	Stage    ProgressStage `json:"stage"`
+	Message  string        `json:"message"`
+	// Attempt is the attempt number of the API call of a retrying event
+	Attempt int `json:"attempt,omitempty"`
+	// Result is the outcome of the operation of a done event
+	Result *ResourceResult `json:"-"`
+	Time   time.Time       `json:"time"`
+}
+
+// String renders the event as one line, e.g. for line-by-line CLI output
+func (e ProgressEvent) String() string {
+	target := e.Service + " " + e.Operation
+	if e.Resource != "" {
+		target += " " + e.Resource
+	}
+	return fmt.Sprintf("[%s %s] %s: %s", target, e.Region, e.Stage, e.Message)
+}
+
+// ProgressFunc receives progress events. It is called from the goroutine
+// running the operation and from concurrent operations, so it must be
+// safe for concurrent use and should return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// WithProgress makes services report the progress of their create and
+// delete operations to fn
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *serviceOptions) {
+		o.progress = fn
+	}
+}
+
+// WithProgressChannel makes services send the progress of their create and
+// delete operations to ch. Sends block, so ch must be drained while
+// operations run.
+func WithProgressChannel(ch chan<- ProgressEvent) Option {
+	return WithProgress(func(event ProgressEvent) {
+		ch <- event
+	})
+}
+
+// progress reports the events of one operation. A nil progress reports nothing.
+type progress struct {
+	fn        ProgressFunc
+	service   string
+	operation string
+	region    string
+	resource  string
+}
+
+// newProgress starts reporting an operation to fn, returning nil when fn is nil
+func newProgress(fn ProgressFunc, service, operation, region string) *progress {
+	if fn == nil {
+		return nil
+	}
+	return &progress{fn: fn, service: service, operation: operation, region: region}
+}
+
+// setResource names the resource of the following events
+func (p *progress) setResource(resource string) {
+	if p != nil {
+		p.resource = resource
+	}
+}
+
+// report sends an event
+func (p *progress) report(stage ProgressStage, format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	p.send(ProgressEvent{Stage: stage, Message: fmt.Sprintf(format, args...)})
+}
+
+// done sends the final event of an operation with its result
+func (p *progress) done(result *ResourceResult) {
+	if p == nil || result == nil {
+		return
+	}
+	p.send(ProgressEvent{Stage: StageDone, Message: result.Message, Result: result})
+}
+
+func (p *progress) send(event ProgressEvent) {
+	event.Service = p.service
+	event.Operation = p.operation
+	event.Region = p.region
+	event.Resource = p.resource
+	event.Time = time.Now().UTC()
+	p.fn(event)
+}
+
+type progressKey struct{}
+
+type attemptsKey struct{}
+
+// context returns ctx carrying p, so the API calls made with it report their retries
+func (p *progress) context(ctx context.Context) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// addRetryProgress adds middleware to SDK clients reporting every retried
+// attempt of an API call to the progress of the call's context
+func addRetryProgress(stack *middleware.Stack) error {
+	// A counter per API call, since one context is shared by several calls
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ProgressAttempts",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			if _, ok := ctx.Value(progressKey{}).(*progress); ok {
+				ctx = context.WithValue(ctx, attemptsKey{}, new(int32))
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+
+	// Runs once per attempt, after the retry middleware
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("ProgressRetries",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			p, _ := ctx.Value(progressKey{}).(*progress)
+			if attempts, ok := ctx.Value(attemptsKey{}).(*int32); ok && p != nil {
+				if attempt := atomic.AddInt32(attempts, 1); attempt > 1 {
+					p.send(ProgressEvent{
+						Stage:   StageRetrying,
+						Message: fmt.Sprintf("Retrying %s, attempt %d", awsmiddleware.GetOperationName(ctx), attempt),
+						Attempt: int(attempt),
+					})
+				}
+			}
+			return next.HandleFinalize(ctx, in)
+		}), "Retry", middleware.After)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+// recordProgress returns an option collecting events and a function returning them
+func recordProgress() (Option, func() []ProgressEvent) {
+	var mu sync.Mutex
+	var events []ProgressEvent
+	option := WithProgress(func(event ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return option, func() []ProgressEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]ProgressEvent(nil), events...)
+	}
+}
+
+func stages(events []ProgressEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event.Stage)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestCreateBucketProgress(t *testing.T) {
+	option, events := recordProgress()
+	svc, _ := NewS3Service("us-east-1", WithS3Client(fake.NewS3()), option)
+
+	result, _ := svc.CreateResource(context.Background(), map[string]interface{}{
+		"bucket_name": "progress-bucket",
+		"versioning":  true,
+		"wait":        true,
+	})
+	if !result.Success {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	got := events()
+	want := "validating,calling_api,calling_api,waiting,done"
+	if stages(got) != want {
+		t.Fatalf("Expected stages %s, got %s", want, stages(got))
+	}
+	last := got[len(got)-1]
+	if last.Result != result || last.Resource != "progress-bucket" || last.Service != S3ServiceName || last.Operation != OperationCreate {
+		t.Errorf("Expected the done event to carry the result, got %+v", last)
+	}
+
+	// Failed validation ends the operation with a done event too
+	svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "Invalid_Name"})
+	if got := stages(events()[len(got):]); got != "validating,done" {
+		t.Errorf("Expected validating,done for an invalid bucket, got %s", got)
+	}
+}
+
+func TestTerminateInstancesProgress(t *testing.T) {
+	backend := fake.NewEC2("us-east-1")
+	option, events := recordProgress()
+	svc, _ := NewEC2Service("us-east-1", WithEC2Client(backend), option)
+
+	created, _ := svc.CreateResource(context.Background(), map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+	})
+	if !created.Success {
+		t.Fatalf("Expected success, got %+v", created)
+	}
+	id := *backend.Instances()[0].InstanceId
+
+	svc.DeleteResource(context.Background(), map[string]interface{}{"instance_id": []string{id}, "wait": true})
+	got := events()
+	if stages(got) != "validating,calling_api,done,validating,calling_api,waiting,done" {
+		t.Fatalf("Unexpected stages %s", stages(got))
+	}
+	if got[2].Resource != id || got[4].Resource != id {
+		t.Errorf("Expected events to name instance %s, got %+v", id, got)
+	}
+}
+
+// flakyTransport fails the first requests with 503 Service Unavailable
+type flakyTransport struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyTransport) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := http.StatusOK
+	if f.failures > 0 {
+		f.failures--
+		status = http.StatusServiceUnavailable
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Location": []string{"/retried-bucket"}, "X-Amz-Request-Id": []string{"REQ123"}},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestCreateBucketReportsRetries(t *testing.T) {
+	// A CA bundle cannot be applied to a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(&flakyTransport{failures: 2}),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		}),
+	))
+	option, events := recordProgress()
+	svc, err := NewS3Service("us-east-1", WithProvider(provider), option)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, _ := svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "retried-bucket"})
+	if !result.Success {
+		t.Fatalf("Expected success after retries, got %+v", result)
+	}
+
+	var retries []ProgressEvent
+	for _, event := range events() {
+		if event.Stage == StageRetrying {
+			retries = append(retries, event)
+		}
+	}
+	if len(retries) != 2 || retries[0].Attempt != 2 || retries[1].Attempt != 3 {
+		t.Fatalf("Expected retries for attempts 2 and 3, got %+v", retries)
+	}
+	if !strings.Contains(retries[0].Message, "CreateBucket") {
+		t.Errorf("Expected the retried operation to be named, got %q", retries[0].Message)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// AllRegions in a region set stands for every region enabled for the account
+const AllRegions = "all"
+
+// ParseRegions splits a comma separated region set, e.g. "us-east-1,eu-west-1" or "all"
+func ParseRegions(s string) []string {
+	var regions []string
+	for _, region := range strings.Split(s, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// ResolveRegions expands a region set into sorted, distinct region names.
+// AllRegions is replaced with the regions enabled for the account, found
+// with DescribeRegions from home, the region of the default configuration.
+func ResolveRegions(ctx context.Context, home string, regions []string, opts ...Option) ([]string, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("at least one region is required")
+	}
+
+	seen := make(map[string]bool)
+	var resolved []string
+	for _, region := range regions {
+		names := []string{region}
+		if region == AllRegions {
+			svc, err := NewEC2Service(home, opts...)
+			if err != nil {
+				return nil, err
+			}
+			if names, err = svc.EnabledRegions(ctx); err != nil {
+				return nil, err
+			}
+		} else if !ValidRegion(region) {
+			return nil, fmt.Errorf("%q is not a valid AWS region", region)
+		}
+
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				resolved = append(resolved, name)
+			}
+		}
+	}
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// EnabledRegions returns the regions enabled for the account, leaving out
+// opt-in regions that were not opted in
+func (e *EC2Service) EnabledRegions(ctx context.Context) ([]string, error) {
+	client, err := e.clientFor(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(false)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list enabled regions: %w", ClassifyError(err))
+	}
+
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// RegionResult is the outcome of an operation in one region
+type RegionResult struct {
+	Region string          `json:"region"`
+	Result *ResourceResult `json:"result"`
+}
+
+// MultiRegionReport aggregates the outcome of an operation run in several regions
+type MultiRegionReport struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	// Results holds one result per region, sorted by region
+	Results   []RegionResult `json:"results"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+}
+
+// Success reports whether the operation succeeded in every region
+func (r *MultiRegionReport) Success() bool {
+	return r.Failed == 0
+}
+
+// Failures returns the results of the regions where the operation failed
+func (r *MultiRegionReport) Failures() []RegionResult {
+	var failures []RegionResult
+	for _, result := range r.Results {
+		if !result.Result.Success {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// regionPlaceholder in a string parameter of a fanned out operation is
+// replaced with the region, e.g. to give buckets distinct names
+const regionPlaceholder = "{region}"
+
+// FanOut runs a create or list operation of service in every region
+// concurrently, with one service per region created with opts. The region
+// parameter of each call is set to its region, and {region} in string
+// parameters is replaced with it. A failure in one region does not stop
+// the others; it is reported in the region's result.
+func FanOut(ctx context.Context, service, operation string, regions []string, params map[string]interface{}, opts ...Option) (*MultiRegionReport, error) {
+	if operation != OperationCreate && operation != OperationList {
+		return nil, fmt.Errorf("%s operations cannot run in several regions", operation)
+	}
+	schema, ok := LookupSchema(service, operation)
+	if !ok {
+		return nil, fmt.Errorf("unknown service %q", service)
+	}
+
+	report := &MultiRegionReport{Service: service, Operation: operation, Results: make([]RegionResult, len(regions))}
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			report.Results[i] = RegionResult{Region: region, Result: runInRegion(ctx, schema, region, params, opts)}
+		}(i, region)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Region < report.Results[j].Region
+	})
+	for _, result := range report.Results {
+		if result.Result.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// runInRegion runs an operation with its own service and parameters in one region
+func runInRegion(ctx context.Context, schema ParamSchema, region string, params map[string]interface{}, opts []Option) *ResourceResult {
+	svc, err := NewService(schema.Service, region, opts...)
+	if err != nil {
+		return configFailure(region, err)
+	}
+
+	regional := make(map[string]interface{}, len(params)+1)
+	for name, value := range params {
+		if s, ok := value.(string); ok {
+			value = strings.ReplaceAll(s, regionPlaceholder, region)
+		}
+		regional[name] = value
+	}
+	for _, spec := range schema.Params {
+		if spec.Name == "region" {
+			regional["region"] = region
+		}
+	}
+
+	return runOperation(ctx, svc, schema.Operation, regional)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+func TestResolveRegions(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewEC2("us-east-1")
+	backend.SetRegions("us-west-2", "eu-west-1", "us-east-1")
+
+	regions, err := ResolveRegions(ctx, "us-east-1", ParseRegions("all, us-east-1"), WithEC2Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []string{"eu-west-1", "us-east-1", "us-west-2"}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Errorf("Expected %v, got %v", expected, regions)
+	}
+
+	if _, err := ResolveRegions(ctx, "us-east-1", []string{"mars-1"}, WithEC2Client(backend)); err == nil {
+		t.Error("Expected an error for an invalid region")
+	}
+	if _, err := ResolveRegions(ctx, "us-east-1", nil, WithEC2Client(backend)); err == nil {
+		t.Error("Expected an error for an empty region set")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	backend.AddForeignBucket("logs-eu-west-1")
+	regions := []string{"us-west-2", "us-east-1", "eu-west-1"}
+
+	report, err := FanOut(ctx, S3ServiceName, OperationCreate, regions,
+		map[string]interface{}{"bucket_name": "logs-{region}"}, WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Succeeded != 2 || report.Failed != 1 || report.Success() {
+		t.Fatalf("Expected 2 successes and 1 failure, got %+v", report)
+	}
+	if failures := report.Failures(); failures[0].Region != "eu-west-1" {
+		t.Errorf("Expected eu-west-1 to fail, got %+v", failures)
+	}
+	if bucket, ok := backend.Bucket("logs-us-west-2"); !ok || bucket.Region != "us-west-2" {
+		t.Errorf("Expected logs-us-west-2 in us-west-2, got %+v", bucket)
+	}
+
+	report, _ = FanOut(ctx, S3ServiceName, OperationList, regions, nil, WithS3Client(backend))
+	for _, result := range report.Results {
+		if result.Region == "us-west-2" && result.Result.Data["count"] != 1 {
+			t.Errorf("Expected one bucket listed in us-west-2, got %v", result.Result.Data["count"])
+		}
+	}
+
+	if _, err := FanOut(ctx, S3ServiceName, OperationDelete, regions, nil, WithS3Client(backend)); err == nil {
+		t.Error("Expected an error for a delete operation")
+	}
+}
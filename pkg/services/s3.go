@@ -3,117 +3,833 @@ package services
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/inventory"
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// S3ServiceName is the name under which S3 parameter schemas are registered
+const S3ServiceName = "s3"
+
+// S3CreateBucketInput holds the parameters of an S3 create operation
+type S3CreateBucketInput struct {
+	BucketName  string            `param:"bucket_name"`
+	Region      string            `param:"region"`
+	Tags        map[string]string `param:"tags"`
+	Versioning  *bool             `param:"versioning"`
+	Encryption  *bool             `param:"encryption"`
+	DryRun      bool              `param:"dry_run"`
+	Wait        bool              `param:"wait"`
+	WaitTimeout int               `param:"wait_timeout"`
+}
+
+// S3BucketInput holds the parameters of S3 operations that target a single bucket
+type S3BucketInput struct {
+	BucketName string `param:"bucket_name"`
+}
+
+// S3DeleteBucketInput holds the parameters of an S3 delete operation
+type S3DeleteBucketInput struct {
+	BucketName string `param:"bucket_name"`
+	Force      bool   `param:"force"`
+}
+
+// S3ListBucketsInput holds the parameters of an S3 list operation
+type S3ListBucketsInput struct {
+	Region string `param:"region"`
+}
+
+// S3UpdateBucketInput holds the parameters of an S3 update operation
+type S3UpdateBucketInput struct {
+	BucketName string            `param:"bucket_name"`
+	Versioning *bool             `param:"versioning"`
+	Encryption *bool             `param:"encryption"`
+	Tags       map[string]string `param:"tags"`
+}
+
+var s3BucketNameParam = ParamSpec{
+	Name:        "bucket_name",
+	Type:        ParamString,
+	Required:    true,
+	Description: "Globally unique name of the bucket",
+}
+
+func init() {
+	RegisterSchema(ParamSchema{
+		Service:   S3ServiceName,
+		Operation: OperationCreate,
+		Params: []ParamSpec{
+			s3BucketNameParam,
+			{Name: "region", Type: ParamString, Description: "Region of the bucket, defaults to the service region"},
+			{Name: "tags", Type: ParamStringMap, Description: "Tags applied to the bucket on top of the default tags"},
+			{Name: "versioning", Type: ParamBool, Description: "Enable object versioning"},
+			{Name: "encryption", Type: ParamBool, Description: "Enable SSE-S3 default encryption, or remove it when false"},
+			{Name: "dry_run", Type: ParamBool, Default: false, Description: "Validate the request and report what would be created"},
+			{Name: "wait", Type: ParamBool, Default: false, Description: "Wait until the bucket is reachable"},
+			{Name: "wait_timeout", Type: ParamInt, Default: 300, Description: "Maximum time to wait, in seconds"},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   S3ServiceName,
+		Operation: OperationDescribe,
+		Params:    []ParamSpec{s3BucketNameParam},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   S3ServiceName,
+		Operation: OperationList,
+		Params: []ParamSpec{
+			{Name: "region", Type: ParamString, Description: "Only list buckets located in this region"},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   S3ServiceName,
+		Operation: OperationUpdate,
+		Params: []ParamSpec{
+			s3BucketNameParam,
+			{Name: "versioning", Type: ParamBool, Description: "Enable or suspend object versioning"},
+			{Name: "encryption", Type: ParamBool, Description: "Enable or remove SSE-S3 default encryption"},
+			{Name: "tags", Type: ParamStringMap, Description: "Tags to add or overwrite, other tags are kept"},
+		},
+	})
+	RegisterSchema(ParamSchema{
+		Service:   S3ServiceName,
+		Operation: OperationDelete,
+		Params: []ParamSpec{
+			s3BucketNameParam,
+			{Name: "force", Type: ParamBool, Default: false, Description: "Delete every object version and delete marker first; the data cannot be recovered"},
+		},
+	})
+}
+
 // S3Service handles S3 bucket operations
 type S3Service struct {
 	*BaseService
-	client *s3.Client
+	provider    *awsconfig.Provider
+	client      S3API
+	defaultTags map[string]string
+	inventory   *inventory.Store
+	session     string
+	progress    ProgressFunc
+	hooks       hooks
+	logger      *slog.Logger
 }
 
+var _ AWSService = (*S3Service)(nil)
+
 // NewS3Service creates a new S3 service instance
-func NewS3Service(region string) (*S3Service, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+func NewS3Service(region string, opts ...Option) (*S3Service, error) {
+	options := newServiceOptions(opts)
+
+	if options.s3Client == nil {
+		if _, err := options.provider.Config(options.loadContext(), region); err != nil {
+			return nil, err
+		}
 	}
 
 	return &S3Service{
 		BaseService: NewBaseService(region),
-		client:      s3.NewFromConfig(cfg),
+		provider:    options.provider,
+		client:      options.s3Client,
+		defaultTags: options.defaultTags,
+		inventory:   options.inventory,
+		session:     options.session,
+		progress:    options.progress,
+		hooks:       options.hooks,
+		logger:      options.logger,
 	}, nil
 }
 
+// clientFor returns the injected S3 client, or the cached client for a region
+// defaulting to the service region
+func (s *S3Service) clientFor(ctx context.Context, region string) (S3API, error) {
+	if s.client != nil {
+		return s.client, nil
+	}
+	if region == "" {
+		region = s.Region
+	}
+	return awsconfig.Client(ctx, s.provider, S3ServiceName, region, func(cfg aws.Config) S3API {
+		return s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, addRetryProgress, addRequestLogging)
+			o.TracerProvider = telemetry.SDKTracerProvider()
+		})
+	})
+}
+
+// bucketClient returns a client for the region of an existing bucket.
+// Requests for a bucket outside the region of the client are redirected
+// or refused by S3, so describe, update and delete look the region up first.
+func (s *S3Service) bucketClient(ctx context.Context, bucketName string) (S3API, string, *ResourceResult) {
+	client, err := s.clientFor(ctx, "")
+	if err != nil {
+		return nil, "", configFailure(s.Region, err)
+	}
+	region, err := bucketRegion(ctx, client, bucketName)
+	if err != nil {
+		return nil, "", awsFailure(err, fmt.Sprintf("Failed to find the region of bucket '%s'", bucketName))
+	}
+	if region != s.Region {
+		if client, err = s.clientFor(ctx, region); err != nil {
+			return nil, "", configFailure(region, err)
+		}
+	}
+	return client, region, nil
+}
+
+// bucketRegion returns the region of a bucket. GetBucketLocation answers
+// from any region; buckets in us-east-1 have no location constraint and EU
+// is the legacy name of eu-west-1.
+func bucketRegion(ctx context.Context, client S3API, bucketName string) (string, error) {
+	location, err := client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return "", err
+	}
+	switch location.LocationConstraint {
+	case "":
+		return "us-east-1", nil
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1", nil
+	}
+	return string(location.LocationConstraint), nil
+}
+
 // CreateResource creates an S3 bucket
 func (s *S3Service) CreateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
-	// Extract bucket name from params
-	bucketName, ok := params["bucket_name"].(string)
-	if !ok {
-		return &ResourceResult{
-			Success: false,
-			Error:   "invalid bucket_name parameter",
-			Message: "bucket_name must be a string",
-		}, nil
+	ctx, span := startOperation(ctx, s.logger, S3ServiceName, OperationCreate, s.Region, params)
+	progress := newProgress(s.progress, S3ServiceName, OperationCreate, s.Region)
+	result, err := s.hooks.create(ctx, S3ServiceName, s.Region, params, func(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+		return s.create(progress.context(ctx), params, progress)
+	})
+	progress.done(result)
+	span.end(result, err)
+	return result, err
+}
+
+// create creates an S3 bucket, reporting its steps to progress
+func (s *S3Service) create(ctx context.Context, params map[string]interface{}, progress *progress) (*ResourceResult, error) {
+	progress.report(StageValidating, "Validating parameters")
+	var input S3CreateBucketInput
+	if err := s.DecodeParams(S3ServiceName, OperationCreate, params, &input); err != nil {
+		return validationFailure(err), nil
 	}
 
-	// Override region if provided in params
-	targetRegion := s.Region
-	if region, ok := params["region"].(string); ok && region != "" {
-		targetRegion = region
+	bucketName := input.BucketName
+	progress.setResource(bucketName)
+	targetRegion := input.Region
+	if targetRegion == "" {
+		targetRegion = s.Region
 	}
 
-	// Validate required parameters
-	if err := s.ValidateRequiredParams(params, []string{"bucket_name"}); err != nil {
-		return &ResourceResult{
-			Success: false,
-			Error:   "ValidationError",
-			Message: err.Error(),
-		}, nil
+	if err := ValidateBucketName(bucketName); err != nil {
+		return validationFailure(newValidationError(S3ServiceName, OperationCreate, "bucket_name", err.Error())), nil
+	}
+	if !ValidRegion(targetRegion) {
+		return validationFailure(newValidationError(S3ServiceName, OperationCreate, "region",
+			fmt.Sprintf("%q is not a valid AWS region", targetRegion))), nil
+	}
+
+	tags := MergeTags(s.defaultTags, input.Tags)
+	if err := ValidateTags(tags); err != nil {
+		return validationFailure(newValidationError(S3ServiceName, OperationCreate, "tags", err.Error())), nil
+	}
+
+	client, err := s.clientFor(ctx, targetRegion)
+	if err != nil {
+		return configFailure(targetRegion, err), nil
+	}
+
+	if input.DryRun {
+		progress.report(StageCalling, "Checking whether bucket %s can be created", bucketName)
+		result := planBucket(ctx, client, bucketName, targetRegion, tags)
+		if input.Versioning != nil {
+			result.Data["versioning"] = *input.Versioning
+		}
+		if input.Encryption != nil {
+			result.Data["encryption"] = *input.Encryption
+		}
+		return result, nil
 	}
 
 	// Create bucket configuration
-	input := &s3.CreateBucketInput{
+	createInput := &s3.CreateBucketInput{
 		Bucket: aws.String(bucketName),
 	}
 
 	// For regions other than us-east-1, specify location constraint
 	if targetRegion != "" && targetRegion != "us-east-1" {
-		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+		createInput.CreateBucketConfiguration = &types.CreateBucketConfiguration{
 			LocationConstraint: types.BucketLocationConstraint(targetRegion),
 		}
 	}
 
 	// Create the bucket
-	result, err := s.client.CreateBucket(ctx, input)
+	progress.report(StageCalling, "Creating bucket %s in %s", bucketName, targetRegion)
+	result, err := client.CreateBucket(ctx, createInput)
+	alreadyOwned := false
 	if err != nil {
-		// Handle specific AWS errors
-		errorMsg := err.Error()
-		errorCode := "UnknownError"
+		failure := awsFailure(err, "Failed to create bucket")
 
-		// Check for common S3 errors
-		if containsError(errorMsg, "BucketAlreadyExists") {
-			return &ResourceResult{
-				Success: false,
-				Error:   "BucketAlreadyExists",
-				Message: fmt.Sprintf("Bucket '%s' already exists and is owned by another account", bucketName),
-			}, nil
+		switch failure.Error {
+		case "BucketAlreadyOwnedByYou":
+			// A retried create of our own bucket is an idempotent success
+			alreadyOwned = true
+			result = &s3.CreateBucketOutput{Location: aws.String("/" + bucketName)}
+		case "BucketAlreadyExists":
+			failure.Message = fmt.Sprintf("Bucket '%s' already exists and is owned by another account", bucketName)
+			return failure, nil
+		default:
+			return failure, nil
+		}
+	}
+
+	data := map[string]interface{}{
+		"bucket_name": bucketName,
+		"region":      targetRegion,
+		"location":    aws.ToString(result.Location),
+	}
+	if alreadyOwned {
+		data["already_existed"] = true
+	} else {
+		// Only a bucket created by this call is ours to remove on rollback
+		register(ctx, Compensation{
+			Service:     S3ServiceName,
+			Type:        ResourceTypeBucket,
+			ID:          bucketName,
+			Region:      targetRegion,
+			Description: fmt.Sprintf("delete bucket %s", bucketName),
+			undo: func(ctx context.Context) *ResourceResult {
+				return s.deleteBucket(ctx, client, bucketName)
+			},
+		})
+	}
+
+	// A bucket that already existed is only recorded when the inventory does
+	// not know it yet, and then as imported: it was not created by the tool,
+	// so destroy leaves it alone by default. The record is written once the
+	// configuration steps are done and holds only the settings they applied,
+	// so it never claims a setting whose call failed.
+	var account string
+	if s.inventory != nil {
+		account = accountID(ctx, s.provider, s.client != nil)
+	}
+	// The recorded params are the desired configuration checked for drift
+	desired := map[string]interface{}{"bucket_name": bucketName, "region": targetRegion}
+	var appliedTags map[string]string
+	record := func() {
+		if s.inventory == nil || (alreadyOwned && recorded(s.inventory, S3ServiceName, account, targetRegion, bucketName)) {
+			return
+		}
+		recordResources(s.inventory, data, inventory.Record{
+			Service:  S3ServiceName,
+			Type:     ResourceTypeBucket,
+			ID:       bucketName,
+			ARN:      bucketARN(targetRegion, bucketName),
+			Region:   targetRegion,
+			Account:  account,
+			Params:   desired,
+			Tags:     appliedTags,
+			Session:  s.session,
+			Imported: alreadyOwned,
+		})
+	}
+
+	if len(tags) > 0 {
+		progress.report(StageCalling, "Tagging bucket %s", bucketName)
+		if err := tagBucket(ctx, client, bucketName, tags); err != nil {
+			record()
+			failure := awsFailure(err, fmt.Sprintf("Created S3 bucket '%s' but failed to tag it", bucketName))
+			failure.Data = data
+			return failure, nil
+		}
+		data["tags"] = tags
+		appliedTags = tags
+	}
+
+	if input.Versioning != nil {
+		progress.report(StageCalling, "Configuring versioning of bucket %s", bucketName)
+		status, err := putBucketVersioning(ctx, client, bucketName, *input.Versioning)
+		if err != nil {
+			record()
+			failure := awsFailure(err, fmt.Sprintf("Created S3 bucket '%s' but failed to configure versioning", bucketName))
+			failure.Data = data
+			return failure, nil
 		}
+		data["versioning"] = string(status)
+		desired["versioning"] = *input.Versioning
+	}
 
-		if containsError(errorMsg, "BucketAlreadyOwnedByYou") {
+	if input.Encryption != nil {
+		progress.report(StageCalling, "Configuring encryption of bucket %s", bucketName)
+		if err := putBucketEncryption(ctx, client, bucketName, *input.Encryption); err != nil {
+			record()
+			failure := awsFailure(err, fmt.Sprintf("Created S3 bucket '%s' but failed to configure encryption", bucketName))
+			failure.Data = data
+			return failure, nil
+		}
+		data["encryption"] = *input.Encryption
+		desired["encryption"] = *input.Encryption
+	}
+	record()
+
+	if input.Wait {
+		timeout := waitTimeout(input.WaitTimeout)
+		progress.report(StageWaiting, "Waiting up to %s for bucket %s to be reachable", timeout, bucketName)
+		err := waitForBucket(ctx, client, bucketName, timeout)
+		data["ready"] = err == nil
+
+		if err != nil {
 			return &ResourceResult{
 				Success: false,
-				Error:   "BucketAlreadyOwnedByYou",
-				Message: fmt.Sprintf("Bucket '%s' already exists and is owned by you", bucketName),
+				Error:   "ResourceNotReady",
+				Message: fmt.Sprintf("Created S3 bucket '%s' but it was not reachable within %s: %s", bucketName, timeout, err.Error()),
+				Data:    data,
+				Err:     err,
 			}, nil
 		}
+	}
+
+	message := fmt.Sprintf("Successfully created S3 bucket '%s' in region '%s'", bucketName, targetRegion)
+	if alreadyOwned {
+		message = fmt.Sprintf("S3 bucket '%s' already exists and is owned by you, nothing to do", bucketName)
+	}
+
+	return &ResourceResult{
+		Success: true,
+		Message: message,
+		Data:    data,
+	}, nil
+}
 
+// planBucket probes whether a bucket could be created, without creating it
+func planBucket(ctx context.Context, client S3API, bucketName, region string, tags map[string]string) *ResourceResult {
+	data := dryRunData("create")
+	data["bucket_name"] = bucketName
+	data["region"] = region
+	if region != "us-east-1" {
+		data["location_constraint"] = region
+	}
+	if len(tags) > 0 {
+		data["tags"] = tags
+	}
+
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if err == nil {
+		data["action"] = "none"
+		data["already_existed"] = true
+		return &ResourceResult{
+			Success: true,
+			Message: fmt.Sprintf("Dry run: bucket '%s' already exists and is owned by you, nothing would be created", bucketName),
+			Data:    data,
+		}
+	}
+
+	switch awsErr := ClassifyError(err); awsErr.Kind {
+	case KindNotFound:
 		return &ResourceResult{
-			Success: false,
-			Error:   errorCode,
-			Message: fmt.Sprintf("Failed to create bucket: %s", errorMsg),
-		}, nil
+			Success: true,
+			Message: fmt.Sprintf("Dry run: S3 bucket '%s' would be created in region '%s'", bucketName, region),
+			Data:    data,
+		}
+	case KindAuth:
+		// HeadBucket answers 403 for buckets owned by other accounts
+		return &ResourceResult{
+			Success:   false,
+			Error:     "BucketAlreadyExists",
+			Message:   fmt.Sprintf("Dry run: bucket '%s' already exists and is owned by another account, or access to it is denied", bucketName),
+			Data:      data,
+			RequestID: awsErr.RequestID,
+			Err:       &AWSError{Kind: KindConflict, Code: "BucketAlreadyExists", Message: awsErr.Message, RequestID: awsErr.RequestID, Err: awsErr},
+		}
+	default:
+		result := awsFailure(err, fmt.Sprintf("Dry run: failed to check bucket '%s'", bucketName))
+		result.Data = data
+		return result
+	}
+}
+
+// tagBucket adds tags to a bucket, keeping existing tags with other keys.
+// PutBucketTagging replaces the whole tag set, so the current set is read first.
+func tagBucket(ctx context.Context, client S3API, bucketName string, tags map[string]string) error {
+	current, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucketName)})
+	var existing map[string]string
+	switch {
+	case err == nil:
+		existing = tagsFromS3(current.TagSet)
+	case ClassifyError(err).Kind != KindNotFound:
+		return err
+	}
+
+	merged := MergeTags(existing, tags)
+	if err := ValidateTags(merged); err != nil {
+		return err
+	}
+
+	_, err = client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &types.Tagging{TagSet: s3Tags(merged)},
+	})
+	return err
+}
+
+// ValidateBucketName checks a bucket name against the S3 naming rules
+func ValidateBucketName(name string) error {
+	switch {
+	case len(name) < 3 || len(name) > 63:
+		return fmt.Errorf("must be between 3 and 63 characters long")
+	case !bucketNamePattern.MatchString(name):
+		return fmt.Errorf("must contain only lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number")
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("must not contain two adjacent periods")
+	case net.ParseIP(name) != nil:
+		return fmt.Errorf("must not be formatted as an IP address")
+	}
+
+	for _, prefix := range []string{"xn--", "sthree-", "amzn-s3-demo-"} {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("must not start with %q", prefix)
+		}
+	}
+	for _, suffix := range []string{"-s3alias", "--ol-s3", ".mrap", "--x-s3", "--table-s3"} {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("must not end with %q", suffix)
+		}
+	}
+
+	return nil
+}
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+
+// DescribeResource returns the region, versioning, encryption settings and tags of an S3 bucket
+func (s *S3Service) DescribeResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, s.logger, S3ServiceName, OperationDescribe, s.Region, params)
+	result, err := s.describe(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// describe reads the configuration and tags of a bucket
+func (s *S3Service) describe(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input S3BucketInput
+	if err := s.DecodeParams(S3ServiceName, OperationDescribe, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	bucketName := input.BucketName
+	client, region, failure := s.bucketClient(ctx, bucketName)
+	if failure != nil {
+		return failure, nil
+	}
+
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)}); err != nil {
+		return awsFailure(err, fmt.Sprintf("Failed to describe bucket '%s'", bucketName)), nil
+	}
+
+	data := map[string]interface{}{
+		"bucket_name": bucketName,
+		"region":      region,
+	}
+
+	if versioning, err := client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: aws.String(bucketName)}); err == nil {
+		data["versioning"] = string(versioning.Status)
+	}
+
+	// Buckets without a default encryption configuration return an error, which is not fatal here
+	if encryption, err := client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(bucketName)}); err == nil &&
+		encryption.ServerSideEncryptionConfiguration != nil && len(encryption.ServerSideEncryptionConfiguration.Rules) > 0 {
+		rule := encryption.ServerSideEncryptionConfiguration.Rules[0]
+		if rule.ApplyServerSideEncryptionByDefault != nil {
+			data["encryption"] = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+		}
+	}
+
+	// Buckets without tags return NoSuchTagSet
+	if tagging, err := client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: aws.String(bucketName)}); err == nil {
+		data["tags"] = tagsFromS3(tagging.TagSet)
 	}
 
 	return &ResourceResult{
 		Success: true,
-		Message: fmt.Sprintf("Successfully created S3 bucket '%s' in region '%s'", bucketName, targetRegion),
+		Message: fmt.Sprintf("Bucket '%s' found", bucketName),
+		Data:    data,
+	}, nil
+}
+
+// ListResources lists the S3 buckets owned by the caller
+func (s *S3Service) ListResources(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, s.logger, S3ServiceName, OperationList, s.Region, params)
+	result, err := s.list(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// list lists the buckets of the caller
+func (s *S3Service) list(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input S3ListBucketsInput
+	if err := s.DecodeParams(S3ServiceName, OperationList, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	client, err := s.clientFor(ctx, "")
+	if err != nil {
+		return configFailure(s.Region, err), nil
+	}
+
+	listInput := &s3.ListBucketsInput{}
+	if input.Region != "" {
+		listInput.BucketRegion = aws.String(input.Region)
+	}
+
+	var buckets []map[string]interface{}
+	paginator := s3.NewListBucketsPaginator(client, listInput)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return awsFailure(err, "Failed to list buckets"), nil
+		}
+
+		for _, bucket := range page.Buckets {
+			buckets = append(buckets, map[string]interface{}{
+				"bucket_name":   aws.ToString(bucket.Name),
+				"region":        aws.ToString(bucket.BucketRegion),
+				"creation_date": aws.ToTime(bucket.CreationDate),
+			})
+		}
+	}
+
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Found %d S3 bucket(s)", len(buckets)),
 		Data: map[string]interface{}{
-			"bucket_name": bucketName,
-			"region":      targetRegion,
-			"location":    aws.ToString(result.Location),
+			"buckets": buckets,
+			"count":   len(buckets),
 		},
 	}, nil
 }
 
-// containsError checks if the error message contains a specific substring
-func containsError(errorMsg, errorType string) bool {
-	// This is a simplified error checking - in production you'd want more robust error handling
-	return len(errorMsg) > 0 && len(errorType) > 0
+// UpdateResource changes the versioning and default encryption settings and the tags of an S3 bucket
+func (s *S3Service) UpdateResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, s.logger, S3ServiceName, OperationUpdate, s.Region, params)
+	result, err := s.update(ctx, params)
+	span.end(result, err)
+	return result, err
+}
+
+// update applies the requested changes to a bucket
+func (s *S3Service) update(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	var input S3UpdateBucketInput
+	if err := s.DecodeParams(S3ServiceName, OperationUpdate, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+
+	bucketName := input.BucketName
+
+	if input.Versioning == nil && input.Encryption == nil && len(input.Tags) == 0 {
+		return validationFailure(newValidationError(S3ServiceName, OperationUpdate, "", "at least one of versioning, encryption or tags must be provided")), nil
+	}
+	if err := ValidateTags(input.Tags); err != nil {
+		return validationFailure(newValidationError(S3ServiceName, OperationUpdate, "tags", err.Error())), nil
+	}
+
+	client, _, failure := s.bucketClient(ctx, bucketName)
+	if failure != nil {
+		return failure, nil
+	}
+
+	data := map[string]interface{}{
+		"bucket_name": bucketName,
+	}
+
+	if input.Versioning != nil {
+		status, err := putBucketVersioning(ctx, client, bucketName, *input.Versioning)
+		if err != nil {
+			return awsFailure(err, fmt.Sprintf("Failed to update versioning for bucket '%s'", bucketName)), nil
+		}
+		data["versioning"] = string(status)
+	}
+
+	if input.Encryption != nil {
+		if err := putBucketEncryption(ctx, client, bucketName, *input.Encryption); err != nil {
+			return awsFailure(err, fmt.Sprintf("Failed to update encryption for bucket '%s'", bucketName)), nil
+		}
+		data["encryption"] = *input.Encryption
+	}
+
+	if len(input.Tags) > 0 {
+		if err := tagBucket(ctx, client, bucketName, input.Tags); err != nil {
+			return awsFailure(err, fmt.Sprintf("Failed to update tags for bucket '%s'", bucketName)), nil
+		}
+		data["tags"] = input.Tags
+	}
+
+	updateRecord(s.inventory, data, S3ServiceName, bucketName, func(r *inventory.Record) {
+		if input.Versioning != nil {
+			setRecordParam(r, "versioning", *input.Versioning)
+		}
+		if input.Encryption != nil {
+			setRecordParam(r, "encryption", *input.Encryption)
+		}
+		if len(input.Tags) > 0 {
+			r.Tags = MergeTags(r.Tags, input.Tags)
+		}
+	})
+
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Successfully updated S3 bucket '%s'", bucketName),
+		Data:    data,
+	}, nil
+}
+
+// putBucketVersioning enables or suspends versioning on a bucket
+func putBucketVersioning(ctx context.Context, client S3API, bucketName string, enabled bool) (types.BucketVersioningStatus, error) {
+	status := types.BucketVersioningStatusSuspended
+	if enabled {
+		status = types.BucketVersioningStatusEnabled
+	}
+
+	_, err := client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket:                  aws.String(bucketName),
+		VersioningConfiguration: &types.VersioningConfiguration{Status: status},
+	})
+	return status, err
+}
+
+// putBucketEncryption enables SSE-S3 default encryption on a bucket, or removes
+// the default encryption configuration
+func putBucketEncryption(ctx context.Context, client S3API, bucketName string, enabled bool) error {
+	if !enabled {
+		_, err := client.DeleteBucketEncryption(ctx, &s3.DeleteBucketEncryptionInput{Bucket: aws.String(bucketName)})
+		return err
+	}
+
+	_, err := client.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+		ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+			Rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+					SSEAlgorithm: types.ServerSideEncryptionAes256,
+				},
+			}},
+		},
+	})
+	return err
+}
+
+// DeleteResource deletes an empty S3 bucket
+func (s *S3Service) DeleteResource(ctx context.Context, params map[string]interface{}) (*ResourceResult, error) {
+	ctx, span := startOperation(ctx, s.logger, S3ServiceName, OperationDelete, s.Region, params)
+	progress := newProgress(s.progress, S3ServiceName, OperationDelete, s.Region)
+	result, err := s.delete(progress.context(ctx), params, progress)
+	progress.done(result)
+	span.end(result, err)
+	return result, err
+}
+
+// delete deletes an S3 bucket, reporting its steps to progress
+func (s *S3Service) delete(ctx context.Context, params map[string]interface{}, progress *progress) (*ResourceResult, error) {
+	progress.report(StageValidating, "Validating parameters")
+	var input S3DeleteBucketInput
+	if err := s.DecodeParams(S3ServiceName, OperationDelete, params, &input); err != nil {
+		return validationFailure(err), nil
+	}
+	progress.setResource(input.BucketName)
+
+	client, _, failure := s.bucketClient(ctx, input.BucketName)
+	if failure != nil {
+		return failure, nil
+	}
+
+	if !input.Force {
+		progress.report(StageCalling, "Deleting bucket %s", input.BucketName)
+		return s.deleteBucket(ctx, client, input.BucketName), nil
+	}
+
+	progress.report(StageCalling, "Deleting every object version of bucket %s", input.BucketName)
+	deleted, err := emptyBucket(ctx, client, input.BucketName)
+	if err != nil {
+		failure := awsFailure(err, fmt.Sprintf("Failed to empty bucket '%s'", input.BucketName))
+		failure.Data = map[string]interface{}{"bucket_name": input.BucketName, "objects_deleted": deleted}
+		return failure, nil
+	}
+
+	progress.report(StageCalling, "Deleting bucket %s", input.BucketName)
+	result := s.deleteBucket(ctx, client, input.BucketName)
+	if result.Data == nil {
+		result.Data = map[string]interface{}{"bucket_name": input.BucketName}
+	}
+	result.Data["objects_deleted"] = deleted
+	return result, nil
+}
+
+// emptyBucket deletes every object version and delete marker of a bucket and
+// returns how many were deleted. Listing restarts from the beginning after
+// each batch, since the listed entries are gone.
+func emptyBucket(ctx context.Context, client S3API, bucketName string) (int, error) {
+	deleted := 0
+	for {
+		page, err := client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:  aws.String(bucketName),
+			MaxKeys: aws.Int32(1000),
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		var objects []types.ObjectIdentifier
+		for _, version := range page.Versions {
+			objects = append(objects, types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) == 0 {
+			return deleted, nil
+		}
+
+		output, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(output.Errors) > 0 {
+			first := output.Errors[0]
+			return deleted, fmt.Errorf("failed to delete %d object(s), first %s version %s: %s",
+				len(output.Errors), aws.ToString(first.Key), aws.ToString(first.VersionId), aws.ToString(first.Message))
+		}
+		deleted += len(objects)
+
+		if !aws.ToBool(page.IsTruncated) {
+			return deleted, nil
+		}
+	}
+}
+
+// deleteBucket deletes an empty bucket and marks its inventory record deleted
+func (s *S3Service) deleteBucket(ctx context.Context, client S3API, bucketName string) *ResourceResult {
+	if _, err := client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucketName)}); err != nil {
+		return awsFailure(err, fmt.Sprintf("Failed to delete bucket '%s'", bucketName))
+	}
+
+	data := map[string]interface{}{
+		"bucket_name": bucketName,
+	}
+	deleteRecord(s.inventory, data, S3ServiceName, bucketName)
+
+	return &ResourceResult{
+		Success: true,
+		Message: fmt.Sprintf("Successfully deleted S3 bucket '%s'", bucketName),
+		Data:    data,
+	}
 }
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+var _ S3API = (*fake.S3)(nil)
+
+func newTestS3Service(t *testing.T) (*S3Service, *fake.S3) {
+	t.Helper()
+
+	backend := fake.NewS3()
+	service, err := NewS3Service("us-east-1", WithS3Client(backend))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return service, backend
+}
+
+func TestS3ServiceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestS3Service(t)
+
+	result, err := service.CreateResource(ctx, map[string]interface{}{
+		"bucket_name": "test-bucket",
+		"region":      "eu-west-1",
+	})
+	if err != nil || !result.Success {
+		t.Fatalf("Expected successful create, got %+v (%v)", result, err)
+	}
+	if bucket, ok := backend.Bucket("test-bucket"); !ok || bucket.Region != "eu-west-1" {
+		t.Errorf("Expected bucket in eu-west-1, got %+v", bucket)
+	}
+
+	result, _ = service.UpdateResource(ctx, map[string]interface{}{
+		"bucket_name": "test-bucket",
+		"versioning":  true,
+		"encryption":  true,
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful update, got %+v", result)
+	}
+
+	result, _ = service.DescribeResource(ctx, map[string]interface{}{"bucket_name": "test-bucket"})
+	if !result.Success || result.Data["versioning"] != "Enabled" || result.Data["encryption"] != "AES256" {
+		t.Errorf("Unexpected describe result: %+v", result)
+	}
+
+	result, _ = service.ListResources(ctx, map[string]interface{}{})
+	if !result.Success || result.Data["count"] != 1 {
+		t.Errorf("Expected one bucket, got %+v", result)
+	}
+
+	result, _ = service.DeleteResource(ctx, map[string]interface{}{"bucket_name": "test-bucket"})
+	if !result.Success {
+		t.Fatalf("Expected successful delete, got %+v", result)
+	}
+	if _, ok := backend.Bucket("test-bucket"); ok {
+		t.Error("Expected bucket to be deleted")
+	}
+}
+
+func TestS3ServiceCreateConflict(t *testing.T) {
+	service, backend := newTestS3Service(t)
+	backend.AddForeignBucket("taken")
+
+	result, err := service.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "taken"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Success || result.Error != "BucketAlreadyExists" {
+		t.Errorf("Expected BucketAlreadyExists, got %+v", result)
+	}
+	if !errors.Is(result.Err, ErrConflict) {
+		t.Errorf("Expected conflict error, got %v", result.Err)
+	}
+}
+
+func TestS3ServiceDescribeMissing(t *testing.T) {
+	service, _ := newTestS3Service(t)
+
+	result, _ := service.DescribeResource(context.Background(), map[string]interface{}{"bucket_name": "missing"})
+	if result.Success || !errors.Is(result.Err, ErrNotFound) {
+		t.Errorf("Expected not found, got %+v", result)
+	}
+}
+
+func TestS3ServiceValidation(t *testing.T) {
+	service, _ := newTestS3Service(t)
+
+	result, _ := service.CreateResource(context.Background(), map[string]interface{}{"bucket": "typo"})
+	if result.Success || result.Error != "ValidationError" || !errors.Is(result.Err, ErrValidation) {
+		t.Errorf("Expected validation error, got %+v", result)
+	}
+
+	result, _ = service.UpdateResource(context.Background(), map[string]interface{}{"bucket_name": "my-bucket"})
+	if result.Success || !errors.Is(result.Err, ErrValidation) || result.Message != "invalid parameters for s3 update: at least one of versioning, encryption or tags must be provided" {
+		t.Errorf("Expected validation error for an update changing nothing, got %+v", result)
+	}
+}
+
+func TestS3ServiceDryRun(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestS3Service(t)
+	backend.AddForeignBucket("taken-bucket")
+
+	result, _ := service.CreateResource(ctx, map[string]interface{}{
+		"bucket_name": "new-bucket",
+		"region":      "eu-west-1",
+		"dry_run":     true,
+	})
+	if !result.Success || !IsDryRun(result) || result.Data["location_constraint"] != "eu-west-1" {
+		t.Errorf("Expected successful dry run, got %+v", result)
+	}
+	if _, ok := backend.Bucket("new-bucket"); ok {
+		t.Error("Expected dry run not to create the bucket")
+	}
+
+	result, _ = service.CreateResource(ctx, map[string]interface{}{"bucket_name": "taken-bucket", "dry_run": true})
+	if result.Success || result.Error != "BucketAlreadyExists" || !errors.Is(result.Err, ErrConflict) {
+		t.Errorf("Expected conflict for a foreign bucket, got %+v", result)
+	}
+
+	result, _ = service.CreateResource(ctx, map[string]interface{}{"bucket_name": "Bad_Name", "dry_run": true})
+	if result.Success || result.Error != "ValidationError" {
+		t.Errorf("Expected validation error for an invalid name, got %+v", result)
+	}
+}
+
+func TestValidateBucketName(t *testing.T) {
+	valid := []string{"abc", "my-bucket", "logs.example.com", "a1b2c3"}
+	invalid := []string{"ab", "My-Bucket", "-bucket", "bucket-", "my..bucket", "192.168.0.1", "xn--bucket", "bucket-s3alias"}
+
+	for _, name := range valid {
+		if err := ValidateBucketName(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range invalid {
+		if err := ValidateBucketName(name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestS3ServiceWait(t *testing.T) {
+	service, _ := newTestS3Service(t)
+
+	result, _ := service.CreateResource(context.Background(), map[string]interface{}{
+		"bucket_name": "ready-bucket",
+		"wait":        true,
+	})
+	if !result.Success || result.Data["ready"] != true {
+		t.Errorf("Expected reachable bucket, got %+v", result)
+	}
+}
+
+func TestS3ServiceCreateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestS3Service(t)
+	params := map[string]interface{}{"bucket_name": "retry-bucket"}
+
+	first, _ := service.CreateResource(ctx, params)
+	second, _ := service.CreateResource(ctx, params)
+	if !first.Success || !second.Success {
+		t.Fatalf("Expected both creates to succeed, got %+v and %+v", first, second)
+	}
+	if second.Data["already_existed"] != true {
+		t.Errorf("Expected retry to report an existing bucket, got %+v", second.Data)
+	}
+
+	params["dry_run"] = true
+	preview, _ := service.CreateResource(ctx, params)
+	if !preview.Success || preview.Data["action"] != "none" {
+		t.Errorf("Expected dry run to report nothing to do, got %+v", preview)
+	}
+}
+
+func TestS3ServiceForceDelete(t *testing.T) {
+	ctx := context.Background()
+	backend := fake.NewS3()
+	service, _ := NewS3Service("us-east-1", WithS3Client(backend))
+
+	service.CreateResource(ctx, map[string]interface{}{"bucket_name": "full-bucket", "versioning": true})
+	backend.AddObjectVersion("full-bucket", "a.txt", false)
+	backend.AddObjectVersion("full-bucket", "a.txt", false)
+	backend.AddObjectVersion("full-bucket", "a.txt", true)
+
+	result, _ := service.DeleteResource(ctx, map[string]interface{}{"bucket_name": "full-bucket"})
+	if result.Success || result.Error != "BucketNotEmpty" {
+		t.Fatalf("Expected BucketNotEmpty without force, got %+v", result)
+	}
+
+	result, _ = service.DeleteResource(ctx, map[string]interface{}{"bucket_name": "full-bucket", "force": true})
+	if !result.Success {
+		t.Fatalf("Expected successful delete, got %+v", result)
+	}
+	if result.Data["objects_deleted"] != 3 {
+		t.Errorf("Expected 3 versions deleted, got %v", result.Data["objects_deleted"])
+	}
+	if _, ok := backend.Bucket("full-bucket"); ok {
+		t.Error("Expected full-bucket to be deleted")
+	}
+}
+
+// regionalS3 answers S3 requests for a bucket in sa-east-1 and records the
+// host each operation was sent to
+type regionalS3 struct {
+	mu    sync.Mutex
+	hosts map[string]string // request method and query -> host
+}
+
+func (r *regionalS3) Do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hosts[req.Method+" "+strings.SplitN(req.URL.RawQuery, "=", 2)[0]] = req.URL.Host
+	body := ""
+	if req.URL.Query().Has("location") {
+		body = `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">sa-east-1</LocationConstraint>`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestS3ServiceUsesBucketRegion(t *testing.T) {
+	// A CA bundle cannot be applied to a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	transport := &regionalS3{hosts: map[string]string{}}
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(transport),
+	))
+	service, _ := NewS3Service("us-east-1", WithProvider(provider))
+
+	result, _ := service.DescribeResource(context.Background(), map[string]interface{}{"bucket_name": "far-bucket"})
+	if !result.Success || result.Data["region"] != "sa-east-1" {
+		t.Fatalf("Expected the bucket in sa-east-1, got %+v", result)
+	}
+	result, _ = service.UpdateResource(context.Background(), map[string]interface{}{"bucket_name": "far-bucket", "versioning": true})
+	if !result.Success {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	for operation, region := range map[string]string{
+		"GET location":   "us-east-1",
+		"HEAD ":          "sa-east-1",
+		"PUT versioning": "sa-east-1",
+	} {
+		if host := transport.hosts[operation]; !strings.Contains(host, region) {
+			t.Errorf("Expected %s to be sent to %s, got host %q", operation, region, host)
+		}
+	}
+}
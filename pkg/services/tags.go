@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// MaxTags is the maximum number of tags AWS accepts on a resource
+const MaxTags = 50
+
+// ParseTags parses tags written as "key=value,key2=value2"
+func ParseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("tag %q must be written as key=value", pair)
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// FormatTags writes tags as "key=value,key2=value2" sorted by key
+func FormatTags(tags map[string]string) string {
+	keys := sortedTagKeys(tags)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + tags[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ValidateTags checks tags against the AWS tagging rules
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("at most %d tags are allowed, got %d", MaxTags, len(tags))
+	}
+	for _, key := range sortedTagKeys(tags) {
+		switch {
+		case key == "" || len(key) > 128:
+			return fmt.Errorf("tag key %q must be between 1 and 128 characters long", key)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("tag key %q must not start with the reserved prefix \"aws:\"", key)
+		case len(tags[key]) > 256:
+			return fmt.Errorf("value of tag %q must be at most 256 characters long", key)
+		}
+	}
+	return nil
+}
+
+// MergeTags returns the union of the given tag sets; later sets override
+// the values of earlier ones
+func MergeTags(sets ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, set := range sets {
+		for key, value := range set {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// sortedTagKeys returns the keys of tags in sorted order
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// s3Tags converts tags into an S3 tag set
+func s3Tags(tags map[string]string) []s3types.Tag {
+	set := make([]s3types.Tag, 0, len(tags))
+	for _, key := range sortedTagKeys(tags) {
+		set = append(set, s3types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return set
+}
+
+// tagsFromS3 converts an S3 tag set into a map
+func tagsFromS3(set []s3types.Tag) map[string]string {
+	tags := make(map[string]string, len(set))
+	for _, tag := range set {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+// ec2Tags converts tags into EC2 tags
+func ec2Tags(tags map[string]string) []ec2types.Tag {
+	list := make([]ec2types.Tag, 0, len(tags))
+	for _, key := range sortedTagKeys(tags) {
+		list = append(list, ec2types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return list
+}
+
+// ec2TagSpecifications applies tags to launched instances and their volumes
+func ec2TagSpecifications(tags map[string]string) []ec2types.TagSpecification {
+	if len(tags) == 0 {
+		return nil
+	}
+	return []ec2types.TagSpecification{
+		{ResourceType: ec2types.ResourceTypeInstance, Tags: ec2Tags(tags)},
+		{ResourceType: ec2types.ResourceTypeVolume, Tags: ec2Tags(tags)},
+	}
+}
+
+// tagsFromEC2 converts EC2 tags into a map
+func tagsFromEC2(list []ec2types.Tag) map[string]string {
+	tags := make(map[string]string, len(list))
+	for _, tag := range list {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
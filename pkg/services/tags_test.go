@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags(" owner = alice ,cost-center=42,, empty=")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tags) != 3 || tags["owner"] != "alice" || tags["cost-center"] != "42" || tags["empty"] != "" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if FormatTags(tags) != "cost-center=42,empty=,owner=alice" {
+		t.Errorf("Expected sorted tags, got %s", FormatTags(tags))
+	}
+
+	if _, err := ParseTags("owner"); err == nil {
+		t.Error("Expected error for tag without value")
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  map[string]string
+		valid bool
+	}{
+		{"valid", map[string]string{"owner": "alice"}, true},
+		{"empty key", map[string]string{"": "x"}, false},
+		{"reserved prefix", map[string]string{"AWS:owner": "x"}, false},
+		{"long value", map[string]string{"owner": strings.Repeat("x", 257)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTags(tt.tags); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestDefaultTagsAreMerged(t *testing.T) {
+	ctx := context.Background()
+	defaults := WithDefaultTags(map[string]string{"owner": "platform", "created-by": "aws-resources"})
+
+	s3Backend := fake.NewS3()
+	s3Service, err := NewS3Service("us-east-1", WithS3Client(s3Backend), defaults)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, _ := s3Service.CreateResource(ctx, map[string]interface{}{
+		"bucket_name": "tagged-bucket",
+		"tags":        "owner=alice,team=data",
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful create, got %+v", result)
+	}
+	bucket, _ := s3Backend.Bucket("tagged-bucket")
+	if bucket.Tags["owner"] != "alice" || bucket.Tags["team"] != "data" || bucket.Tags["created-by"] != "aws-resources" {
+		t.Errorf("Expected merged bucket tags, got %v", bucket.Tags)
+	}
+
+	ec2Backend := fake.NewEC2("us-east-1")
+	ec2Service, err := NewEC2Service("us-east-1", WithEC2Client(ec2Backend), defaults)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, _ = ec2Service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"tags":          map[string]interface{}{"team": "web"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful launch, got %+v", result)
+	}
+
+	instance := ec2Backend.Instances()[0]
+	tags := tagsFromEC2(instance.Tags)
+	if tags["owner"] != "platform" || tags["team"] != "web" || len(tags) != 3 {
+		t.Errorf("Expected merged instance tags, got %v", tags)
+	}
+}
+
+func TestUpdateTagsKeepsExistingTags(t *testing.T) {
+	ctx := context.Background()
+	service, backend := newTestS3Service(t)
+
+	service.CreateResource(ctx, map[string]interface{}{"bucket_name": "test-bucket", "tags": "owner=alice"})
+	result, _ := service.UpdateResource(ctx, map[string]interface{}{
+		"bucket_name": "test-bucket",
+		"tags":        map[string]string{"team": "data"},
+	})
+	if !result.Success {
+		t.Fatalf("Expected successful update, got %+v", result)
+	}
+
+	bucket, _ := backend.Bucket("test-bucket")
+	if bucket.Tags["owner"] != "alice" || bucket.Tags["team"] != "data" {
+		t.Errorf("Expected both tags, got %v", bucket.Tags)
+	}
+
+	result, _ = service.DescribeResource(ctx, map[string]interface{}{"bucket_name": "test-bucket"})
+	if tags, ok := result.Data["tags"].(map[string]string); !ok || len(tags) != 2 {
+		t.Errorf("Expected tags in describe result, got %+v", result.Data)
+	}
+}
+
+func TestCreateRejectsInvalidTags(t *testing.T) {
+	service, _ := newTestS3Service(t)
+
+	result, _ := service.CreateResource(context.Background(), map[string]interface{}{
+		"bucket_name": "test-bucket",
+		"tags":        "aws:owner=alice",
+	})
+	if result.Success || result.Error != "ValidationError" {
+		t.Errorf("Expected validation error, got %+v", result)
+	}
+}
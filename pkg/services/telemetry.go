@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Tech-Preta/aws-resources/pkg/logging"
+)
+
+// instrumentationName names the tracer and meter of the services
+const instrumentationName = "github.com/Tech-Preta/aws-resources/pkg/services"
+
+// instruments are the metrics of service operations
+type instruments struct {
+	operations metric.Int64Counter
+	errors     metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// operationInstruments returns the instruments of the global meter provider.
+// The tracer and meter are resolved at call time rather than once, so
+// providers installed or replaced later, e.g. by telemetry.Setup, are used.
+// The SDK returns the same instruments for the same names.
+func operationInstruments() instruments {
+	meter := otel.GetMeterProvider().Meter(instrumentationName)
+	operations, _ := meter.Int64Counter("aws_resources.operations",
+		metric.WithDescription("Service operations run, by service, operation and outcome"))
+	failures, _ := meter.Int64Counter("aws_resources.errors",
+		metric.WithDescription("Failed service operations, by service, operation and error code"))
+	duration, _ := meter.Float64Histogram("aws_resources.operation.duration",
+		metric.WithDescription("Duration of service operations"), metric.WithUnit("s"))
+	return instruments{operations: operations, errors: failures, duration: duration}
+}
+
+// operationSpan traces and logs one service operation and records its metrics
+type operationSpan struct {
+	span   trace.Span
+	logger *slog.Logger
+	start  time.Time
+	attrs  []attribute.KeyValue
+}
+
+// startOperation starts the span of a service operation and logs its
+// redacted parameters. The returned context carries the span, so the SDK
+// calls of the operation are its children, and the logger of its AWS calls.
+func startOperation(ctx context.Context, logger *slog.Logger, service, operation, region string, params map[string]interface{}) (context.Context, *operationSpan) {
+	attrs := []attribute.KeyValue{
+		attribute.String("aws_resources.service", service),
+		attribute.String("aws_resources.operation", operation),
+		attribute.String("aws.region", region),
+	}
+	ctx, span := otel.GetTracerProvider().Tracer(instrumentationName).Start(ctx, service+"."+operation, trace.WithAttributes(attrs...))
+
+	logger = logger.With("service", service, "operation", operation, "region", region)
+	logger.DebugContext(ctx, "Operation started", "params", logging.RedactParams(params))
+	return withLogger(ctx, logger), &operationSpan{span: span, logger: logger, start: time.Now(), attrs: attrs}
+}
+
+// end ends the span with the outcome of the operation and records its metrics
+func (o *operationSpan) end(result *ResourceResult, err error) {
+	ctx := context.Background()
+	instruments := operationInstruments()
+	duration := time.Since(o.start)
+	success := err == nil && result != nil && result.Success
+	attrs := append(o.attrs[:len(o.attrs):len(o.attrs)], attribute.Bool("aws_resources.success", success))
+
+	if !success {
+		code, message := "Error", ""
+		if err != nil {
+			message = err.Error()
+			o.span.RecordError(err)
+		}
+		if result != nil {
+			code, message = result.Error, result.Message
+			if result.RequestID != "" {
+				o.span.SetAttributes(attribute.String("aws.request_id", result.RequestID))
+			}
+		}
+		o.span.SetAttributes(attribute.String("aws_resources.error", code))
+		o.span.SetStatus(codes.Error, message)
+		instruments.errors.Add(ctx, 1, metric.WithAttributes(append(o.attrs[:len(o.attrs):len(o.attrs)], attribute.String("aws_resources.error", code))...))
+		o.logFailure(duration, code, message, result, err)
+	} else {
+		o.logger.Info("Operation succeeded", "duration", duration, "dry_run", IsDryRun(result))
+	}
+	if IsDryRun(result) {
+		o.span.SetAttributes(attribute.Bool("aws_resources.dry_run", true))
+	}
+
+	instruments.operations.Add(ctx, 1, metric.WithAttributes(attrs...))
+	instruments.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+	o.span.End()
+}
+
+// logFailure logs a failed operation, as an error when it returned an
+// error instead of a failed result
+func (o *operationSpan) logFailure(duration time.Duration, code, message string, result *ResourceResult, err error) {
+	attrs := []any{"duration", duration, "error", code, "message", message}
+	if result != nil && result.RequestID != "" {
+		attrs = append(attrs, "request_id", result.RequestID)
+	}
+	if err != nil {
+		o.logger.Error("Operation failed", attrs...)
+		return
+	}
+	o.logger.Warn("Operation failed", attrs...)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	metricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Tech-Preta/aws-resources/pkg/awsconfig"
+	"github.com/Tech-Preta/aws-resources/pkg/telemetry"
+)
+
+// collector is a local OTLP/HTTP collector keeping what it receives
+type collector struct {
+	mu      sync.Mutex
+	parents map[string]string // span name -> parent span name
+	metrics map[string]bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/traces":
+		var request tracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		names := map[string]string{}
+		for _, rs := range request.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					names[string(span.SpanId)] = span.Name
+				}
+			}
+		}
+		for _, rs := range request.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					c.parents[span.Name] = names[string(span.ParentSpanId)]
+				}
+			}
+		}
+	case "/v1/metrics":
+		var request metricspb.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rm := range request.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, metric := range sm.Metrics {
+					c.metrics[metric.Name] = true
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+}
+
+func TestOperationTelemetry(t *testing.T) {
+	t.Setenv("AWS_CA_BUNDLE", "")
+	c := &collector{parents: map[string]string{}, metrics: map[string]bool{}}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	shutdown, err := telemetry.Setup(context.Background(), telemetry.Options{Exporter: telemetry.ExporterOTLP, Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	provider := awsconfig.NewProvider(awsconfig.WithLoadOptions(
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		config.WithHTTPClient(&flakyTransport{}),
+	))
+	svc, err := NewS3Service("us-east-1", WithProvider(provider))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result, _ := svc.CreateResource(context.Background(), map[string]interface{}{"bucket_name": "traced-bucket"})
+	if !result.Success {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for span, parent := range map[string]string{
+		"s3.create":        "",
+		"awsconfig.Config": "s3.create",
+		"S3.CreateBucket":  "s3.create",
+		"RetryLoop":        "S3.CreateBucket",
+		"GetIdentity":      "Attempt",
+	} {
+		got, ok := c.parents[span]
+		if !ok {
+			t.Errorf("Expected a %s span, got %v", span, c.parents)
+		} else if got != parent {
+			t.Errorf("Expected %s to be a child of %q, got %q", span, parent, got)
+		}
+	}
+	for _, name := range []string{"aws_resources.operations", "aws_resources.operation.duration"} {
+		if !c.metrics[name] {
+			t.Errorf("Expected the %s metric, got %v", name, c.metrics)
+		}
+	}
+}
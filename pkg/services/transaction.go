@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// Compensation undoes a resource created within a transaction
+type Compensation struct {
+	Service string `json:"service"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Region  string `json:"region"`
+	// Description says what undoing does, e.g. "delete bucket my-bucket"
+	Description string `json:"description"`
+
+	undo func(ctx context.Context) *ResourceResult
+}
+
+// RollbackStep is the outcome of one compensation
+type RollbackStep struct {
+	Compensation
+	Error string `json:"error,omitempty"`
+}
+
+// RollbackReport lists what a rollback undid and what it could not undo
+type RollbackReport struct {
+	Undone []RollbackStep `json:"undone"`
+	Failed []RollbackStep `json:"failed,omitempty"`
+}
+
+// Transaction collects the compensations of the resources created by a
+// batch of operations so the batch can be undone when one of them fails.
+// It is safe for concurrent use.
+type Transaction struct {
+	mu            sync.Mutex
+	compensations []Compensation
+}
+
+// NewTransaction creates an empty transaction
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+type transactionKey struct{}
+
+// ContextWithTransaction returns a context that makes services register
+// the compensation of every resource they create in tx
+func ContextWithTransaction(ctx context.Context, tx *Transaction) context.Context {
+	return context.WithValue(ctx, transactionKey{}, tx)
+}
+
+// TransactionFromContext returns the transaction of ctx, or nil
+func TransactionFromContext(ctx context.Context) *Transaction {
+	tx, _ := ctx.Value(transactionKey{}).(*Transaction)
+	return tx
+}
+
+// register adds the compensation of a created resource to the transaction of ctx, if any
+func register(ctx context.Context, compensation Compensation) {
+	if tx := TransactionFromContext(ctx); tx != nil {
+		tx.Register(compensation)
+	}
+}
+
+// Register adds a compensation, undone before the compensations registered earlier
+func (t *Transaction) Register(compensation Compensation) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.compensations = append(t.compensations, compensation)
+}
+
+// Compensations returns the registered compensations in registration order
+func (t *Transaction) Compensations() []Compensation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Compensation(nil), t.compensations...)
+}
+
+// Rollback runs every compensation, newest first, and empties the transaction.
+// A failed compensation does not stop the others.
+func (t *Transaction) Rollback(ctx context.Context) *RollbackReport {
+	t.mu.Lock()
+	compensations := t.compensations
+	t.compensations = nil
+	t.mu.Unlock()
+
+	report := &RollbackReport{Undone: []RollbackStep{}}
+	for i := len(compensations) - 1; i >= 0; i-- {
+		step := RollbackStep{Compensation: compensations[i]}
+		if result := compensations[i].undo(ctx); !result.Success {
+			step.Error = result.Message
+			report.Failed = append(report.Failed, step)
+			continue
+		}
+		report.Undone = append(report.Undone, step)
+	}
+	return report
+}
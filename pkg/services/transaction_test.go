@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Tech-Preta/aws-resources/pkg/services/fake"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	store := openTestInventory(t)
+	s3Backend := fake.NewS3()
+	ec2Backend := fake.NewEC2("us-east-1")
+	s3Service, _ := NewS3Service("us-east-1", WithS3Client(s3Backend), WithInventory(store))
+	ec2Service, _ := NewEC2Service("us-east-1", WithEC2Client(ec2Backend), WithInventory(store))
+
+	tx := NewTransaction()
+	ctx := ContextWithTransaction(context.Background(), tx)
+
+	s3Service.CreateResource(ctx, map[string]interface{}{"bucket_name": "tx-bucket"})
+	s3Service.CreateResource(ctx, map[string]interface{}{"bucket_name": "tx-bucket"})
+	ec2Service.CreateResource(ctx, map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"count":         2,
+	})
+
+	// The retried create of an owned bucket registers nothing
+	compensations := tx.Compensations()
+	if len(compensations) != 2 {
+		t.Fatalf("Expected 2 compensations, got %+v", compensations)
+	}
+
+	report := tx.Rollback(context.Background())
+	if len(report.Undone) != 2 || len(report.Failed) != 0 {
+		t.Fatalf("Expected everything undone, got %+v", report)
+	}
+	if report.Undone[0].Service != EC2ServiceName {
+		t.Errorf("Expected newest compensation first, got %s", report.Undone[0].Service)
+	}
+
+	if _, ok := s3Backend.Bucket("tx-bucket"); ok {
+		t.Error("Expected tx-bucket to be deleted")
+	}
+	for _, instance := range ec2Backend.Instances() {
+		if instance.State.Name != "shutting-down" && instance.State.Name != "terminated" {
+			t.Errorf("Expected %s to be terminated, got %s", *instance.InstanceId, instance.State.Name)
+		}
+	}
+	if record, _ := store.Get(S3ServiceName, "tx-bucket"); !record.Deleted() {
+		t.Error("Expected the bucket record to be marked deleted")
+	}
+
+	if len(tx.Compensations()) != 0 {
+		t.Error("Expected rollback to empty the transaction")
+	}
+}
+
+func TestTransactionRollbackFailure(t *testing.T) {
+	backend := fake.NewS3()
+	service, _ := NewS3Service("us-east-1", WithS3Client(backend))
+
+	tx := NewTransaction()
+	service.CreateResource(ContextWithTransaction(context.Background(), tx), map[string]interface{}{"bucket_name": "full-bucket"})
+
+	backend.InjectError("DeleteBucket", fake.APIError("S3", "DeleteBucket", "BucketNotEmpty",
+		"The bucket you tried to delete is not empty", http.StatusConflict))
+
+	report := tx.Rollback(context.Background())
+	if len(report.Failed) != 1 || report.Failed[0].ID != "full-bucket" || report.Failed[0].Error == "" {
+		t.Fatalf("Expected the bucket deletion to fail, got %+v", report)
+	}
+	if _, ok := backend.Bucket("full-bucket"); !ok {
+		t.Error("Expected full-bucket to be kept")
+	}
+}
+
+func TestTransactionRollbackKeepsReplayedLaunch(t *testing.T) {
+	store := openTestInventory(t)
+	backend := fake.NewEC2("us-east-1")
+	service, _ := NewEC2Service("us-east-1", WithEC2Client(backend), WithInventory(store))
+	params := map[string]interface{}{
+		"image_id":      "ami-12345678",
+		"instance_type": "t2.micro",
+		"key_name":      "my-key",
+		"client_token":  "launch-web",
+	}
+
+	// Launched by an earlier run with the same token
+	if result, _ := service.CreateResource(context.Background(), params); !result.Success {
+		t.Fatalf("Expected successful launch, got %+v", result)
+	}
+
+	tx := NewTransaction()
+	result, _ := service.CreateResource(ContextWithTransaction(context.Background(), tx), params)
+	if !result.Success || result.Data["replayed"] != true {
+		t.Fatalf("Expected the launch to be replayed, got %+v", result)
+	}
+	if compensations := tx.Compensations(); len(compensations) != 0 {
+		t.Fatalf("Expected no compensation for a replayed launch, got %+v", compensations)
+	}
+
+	tx.Rollback(context.Background())
+	instances := backend.Instances()
+	if len(instances) != 1 || instances[0].State.Name == "shutting-down" || instances[0].State.Name == "terminated" {
+		t.Errorf("Expected the instance of the earlier run to keep running, got %+v", instances)
+	}
+}
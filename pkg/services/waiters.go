@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultWaitTimeout is used when a create asks to wait without a timeout
+const DefaultWaitTimeout = 5 * time.Minute
+
+// ErrNotReady is matched by errors.Is when a resource was created but did not
+// become ready before the wait timeout
+var ErrNotReady = errors.New("resource not ready")
+
+// waitTimeout converts a wait_timeout parameter in seconds to a duration
+func waitTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return DefaultWaitTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// waitForInstances waits until the instances are running and their status
+// checks pass, then returns their refreshed details
+func waitForInstances(ctx context.Context, client EC2API, instanceIDs []string, timeout time.Duration) ([]map[string]interface{}, error) {
+	deadline := time.Now().Add(timeout)
+
+	err := ec2.NewInstanceRunningWaiter(client).Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, timeout)
+	if err == nil {
+		err = ec2.NewInstanceStatusOkWaiter(client).Wait(ctx, &ec2.DescribeInstanceStatusInput{
+			InstanceIds: instanceIDs,
+		}, time.Until(deadline))
+	}
+
+	// Report the latest known state even when waiting failed
+	instances, describeErr := describeInstances(ctx, client, instanceIDs)
+	if err != nil {
+		return instances, fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+	return instances, describeErr
+}
+
+// waitForTermination waits until the instances are terminated
+func waitForTermination(ctx context.Context, client EC2API, instanceIDs []string, timeout time.Duration) error {
+	err := ec2.NewInstanceTerminatedWaiter(client).Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+	return nil
+}
+
+// waitForStopped waits until the instances are stopped
+func waitForStopped(ctx context.Context, client EC2API, instanceIDs []string, timeout time.Duration) error {
+	err := ec2.NewInstanceStoppedWaiter(client).Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+	return nil
+}
+
+// describeInstances returns the details of the given instances
+func describeInstances(ctx context.Context, client EC2API, instanceIDs []string) ([]map[string]interface{}, error) {
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []map[string]interface{}
+	for _, reservation := range output.Reservations {
+		for _, instance := range reservation.Instances {
+			instances = append(instances, instanceData(instance))
+		}
+	}
+	return instances, nil
+}
+
+// waitForBucket waits until a bucket answers HeadBucket
+func waitForBucket(ctx context.Context, client S3API, bucketName string, timeout time.Duration) error {
+	err := s3.NewBucketExistsWaiter(client).Wait(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	}, timeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SDKTracerProvider returns a tracer provider for the TracerProvider option
+// of AWS SDK clients. It records the spans of every call and middleware
+// step, e.g. GetIdentity for credential resolution or RetryLoop, with the
+// global OpenTelemetry tracer provider.
+func SDKTracerProvider() tracing.TracerProvider {
+	return sdkTracerProvider{}
+}
+
+type sdkTracerProvider struct{}
+
+func (sdkTracerProvider) Tracer(scope string, opts ...tracing.TracerOption) tracing.Tracer {
+	return sdkTracer{tracer: otel.Tracer(scope)}
+}
+
+type sdkTracer struct {
+	tracer trace.Tracer
+}
+
+// StartSpan starts a span whose parent is the innermost SDK span of ctx.
+// The SDK ends a step by popping its span from the context lineage, which
+// OpenTelemetry does not see, so the parent is taken from the lineage.
+func (t sdkTracer) StartSpan(ctx context.Context, name string, opts ...tracing.SpanOption) (context.Context, tracing.Span) {
+	var options tracing.SpanOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	parent := ctx
+	if current, ok := tracing.GetSpan(ctx); ok {
+		if span, ok := current.(*sdkSpan); ok {
+			parent = trace.ContextWithSpan(ctx, span.span)
+		}
+	}
+
+	ctx, span := t.tracer.Start(parent, name,
+		trace.WithSpanKind(spanKind(options.Kind)),
+		trace.WithAttributes(attributes(&options.Properties)...))
+	wrapped := &sdkSpan{name: name, span: span}
+	return tracing.WithSpan(ctx, wrapped), wrapped
+}
+
+// sdkSpan is an OpenTelemetry span seen by the SDK
+type sdkSpan struct {
+	name string
+	span trace.Span
+}
+
+func (s *sdkSpan) Name() string {
+	return s.name
+}
+
+func (s *sdkSpan) Context() tracing.SpanContext {
+	sc := s.span.SpanContext()
+	return tracing.SpanContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), IsRemote: sc.IsRemote()}
+}
+
+func (s *sdkSpan) AddEvent(name string, opts ...tracing.EventOption) {
+	var options tracing.EventOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	s.span.AddEvent(name, trace.WithAttributes(attributes(&options.Properties)...))
+}
+
+func (s *sdkSpan) SetStatus(status tracing.SpanStatus) {
+	switch status {
+	case tracing.SpanStatusOK:
+		s.span.SetStatus(codes.Ok, "")
+	case tracing.SpanStatusError:
+		s.span.SetStatus(codes.Error, "")
+	}
+}
+
+func (s *sdkSpan) SetProperty(k, v any) {
+	s.span.SetAttributes(attributeOf(k, v))
+}
+
+func (s *sdkSpan) End() {
+	s.span.End()
+}
+
+func spanKind(kind tracing.SpanKind) trace.SpanKind {
+	switch kind {
+	case tracing.SpanKindClient:
+		return trace.SpanKindClient
+	case tracing.SpanKindServer:
+		return trace.SpanKindServer
+	case tracing.SpanKindProducer:
+		return trace.SpanKindProducer
+	case tracing.SpanKindConsumer:
+		return trace.SpanKindConsumer
+	}
+	return trace.SpanKindInternal
+}
+
+// attributes converts smithy properties to span attributes
+func attributes(properties *smithy.Properties) []attribute.KeyValue {
+	values := properties.Values()
+	attrs := make([]attribute.KeyValue, 0, len(values))
+	for k, v := range values {
+		attrs = append(attrs, attributeOf(k, v))
+	}
+	return attrs
+}
+
+func attributeOf(k, v any) attribute.KeyValue {
+	key := fmt.Sprint(k)
+	switch v := v.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(v))
+}
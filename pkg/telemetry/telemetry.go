@@ -0,0 +1,126 @@
+// Package telemetry sets up OpenTelemetry tracing and metrics for this
+// module and bridges the spans of the AWS SDK clients to them.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultServiceName identifies this tool in exported telemetry
+const DefaultServiceName = "aws-resources"
+
+// Options selects where telemetry is exported
+type Options struct {
+	// Exporter is none, stdout or otlp; nothing is exported when empty
+	Exporter string
+	// Endpoint is the OTLP/HTTP endpoint, e.g. localhost:4318 or
+	// https://collector:4318. When empty the OTEL_EXPORTER_OTLP_ENDPOINT
+	// environment variable or localhost:4318 is used.
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP
+	Insecure bool
+	// ServiceName is the service.name resource attribute, DefaultServiceName when empty
+	ServiceName string
+	// Writer receives the stdout exporter output, os.Stdout when nil
+	Writer io.Writer
+	// MetricInterval is how often metrics are exported, a minute when zero.
+	// Metrics are also exported on shutdown.
+	MetricInterval time.Duration
+}
+
+// Setup installs global tracer and meter providers exporting to the
+// configured exporter. The returned function flushes and stops them and
+// must be called before the program exits.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	var spans sdktrace.SpanExporter
+	var metrics sdkmetric.Exporter
+	var err error
+
+	switch strings.ToLower(options.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		writer := options.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		if spans, err = stdouttrace.New(stdouttrace.WithWriter(writer)); err != nil {
+			return nil, err
+		}
+		if metrics, err = stdoutmetric.New(stdoutmetric.WithWriter(writer)); err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		var traceOpts []otlptracehttp.Option
+		var metricOpts []otlpmetrichttp.Option
+		if options.Endpoint != "" {
+			if strings.Contains(options.Endpoint, "://") {
+				traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(strings.TrimSuffix(options.Endpoint, "/")+"/v1/traces"))
+				metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(strings.TrimSuffix(options.Endpoint, "/")+"/v1/metrics"))
+			} else {
+				traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(options.Endpoint))
+				metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(options.Endpoint))
+			}
+		}
+		if options.Insecure {
+			traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+		}
+		if spans, err = otlptracehttp.New(ctx, traceOpts...); err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		if metrics, err = otlpmetrichttp.New(ctx, metricOpts...); err != nil {
+			return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown telemetry exporter %q, expected %s, %s or %s", options.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+
+	serviceName := options.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	interval := options.MetricInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithResource(res))
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metrics, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/smithy-go/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSDKTracerProviderParents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	// The SDK starts and pops spans like this for every operation
+	tracer := SDKTracerProvider().Tracer("github.com/aws/aws-sdk-go-v2/service/ec2")
+	ctx := tracing.WithOperationTracer(context.Background(), tracer)
+	ctx, operation := tracer.StartSpan(ctx, "EC2.RunInstances", func(o *tracing.SpanOptions) {
+		o.Kind = tracing.SpanKindClient
+		o.Properties.Set("rpc.method", "RunInstances")
+	})
+	ctx, _ = tracing.StartSpan(ctx, "Initialize")
+	ctx, initialize := tracing.PopSpan(ctx)
+	initialize.End()
+	_, identity := tracing.StartSpan(ctx, "GetIdentity")
+	identity.SetProperty("auth.scheme_id", "aws.auth#sigv4")
+	identity.End()
+	operation.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	root := spans["EC2.RunInstances"].SpanContext().SpanID()
+	if spans["GetIdentity"].Parent().SpanID() != root {
+		t.Errorf("Expected GetIdentity to be a child of the operation, not of the popped Initialize span")
+	}
+	if !hasAttribute(spans["EC2.RunInstances"].Attributes(), attribute.String("rpc.method", "RunInstances")) {
+		t.Errorf("Expected the rpc.method attribute, got %v", spans["EC2.RunInstances"].Attributes())
+	}
+	if !hasAttribute(spans["GetIdentity"].Attributes(), attribute.String("auth.scheme_id", "aws.auth#sigv4")) {
+		t.Errorf("Expected the auth.scheme_id attribute, got %v", spans["GetIdentity"].Attributes())
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetupStdout(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, Writer: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "stdout-span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "stdout-span") || !strings.Contains(out.String(), DefaultServiceName) {
+		t.Errorf("Expected the span to be written, got %s", out.String())
+	}
+
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+}